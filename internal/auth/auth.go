@@ -1,19 +1,31 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"gowa-broadcast/internal/config"
 	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/media"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type AuthService struct {
-	db        *gorm.DB
-	jwtSecret []byte
+	db            *gorm.DB
+	mediaStore    media.Store // Stored attachments of deleted users are removed from it
+	signingMethod jwt.SigningMethod
+	signKey       interface{} // nil when only verification is possible
+	verifyKey     interface{}
+	issuer        string
+	audience      string
 }
 
 type Claims struct {
@@ -58,16 +70,80 @@ type UpdateUserRequest struct {
 	Active   *bool  `json:"active"`
 }
 
+// UserLimitsRequest sets per-user broadcast limits, 0 resets to the global limit
+type UserLimitsRequest struct {
+	BroadcastMaxRecipients *int `json:"broadcast_max_recipients" binding:"omitempty,min=0"`
+	BroadcastRateLimit     *int `json:"broadcast_rate_limit" binding:"omitempty,min=0"`
+}
+
+type UserLimitsResponse struct {
+	UserID                 uint `json:"user_id"`
+	BroadcastMaxRecipients int  `json:"broadcast_max_recipients"`
+	BroadcastRateLimit     int  `json:"broadcast_rate_limit"`
+}
+
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
 
-func NewAuthService(db *gorm.DB, jwtSecret string) *AuthService {
-	return &AuthService{
-		db:        db,
-		jwtSecret: []byte(jwtSecret),
+func NewAuthService(db *gorm.DB, jwtCfg config.JWTConfig) (*AuthService, error) {
+	a := &AuthService{
+		db:       db,
+		issuer:   jwtCfg.Issuer,
+		audience: jwtCfg.Audience,
+	}
+
+	switch strings.ToUpper(jwtCfg.Algorithm) {
+	case "", "HS256":
+		a.signingMethod = jwt.SigningMethodHS256
+		a.signKey = []byte(jwtCfg.Secret)
+		a.verifyKey = []byte(jwtCfg.Secret)
+	case "RS256":
+		a.signingMethod = jwt.SigningMethodRS256
+		if err := a.loadRSAKeys(jwtCfg.PrivateKeyFile, jwtCfg.PublicKeyFile); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", jwtCfg.Algorithm)
+	}
+
+	return a, nil
+}
+
+// loadRSAKeys reads the PEM keys for RS256. Without a private key the service
+// can still validate tokens minted elsewhere but cannot log users in.
+func (a *AuthService) loadRSAKeys(privateKeyFile, publicKeyFile string) error {
+	if privateKeyFile == "" && publicKeyFile == "" {
+		return errors.New("RS256 requires JWT_PRIVATE_KEY_FILE or JWT_PUBLIC_KEY_FILE")
+	}
+
+	if privateKeyFile != "" {
+		data, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read JWT private key: %v", err)
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse JWT private key: %v", err)
+		}
+		a.signKey = privateKey
+		a.verifyKey = &privateKey.PublicKey
+	}
+
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read JWT public key: %v", err)
+		}
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse JWT public key: %v", err)
+		}
+		a.verifyKey = publicKey
 	}
+
+	return nil
 }
 
 // Login authenticates user and returns JWT token
@@ -94,12 +170,19 @@ func (a *AuthService) Login(req LoginRequest) (*LoginResponse, error) {
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "gowa-broadcast",
+			Issuer:    a.issuer,
 		},
 	}
+	if a.audience != "" {
+		claims.Audience = jwt.ClaimStrings{a.audience}
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(a.jwtSecret)
+	if a.signKey == nil {
+		return nil, errors.New("token signing is not configured")
+	}
+
+	token := jwt.NewWithClaims(a.signingMethod, claims)
+	tokenString, err := token.SignedString(a.signKey)
 	if err != nil {
 		return nil, err
 	}
@@ -120,9 +203,18 @@ func (a *AuthService) Login(req LoginRequest) (*LoginResponse, error) {
 
 // ValidateToken validates JWT token and returns claims
 func (a *AuthService) ValidateToken(tokenString string) (*Claims, error) {
+	// Only accept tokens minted by us, not by another system sharing the secret
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{a.signingMethod.Alg()}),
+		jwt.WithIssuer(a.issuer),
+	}
+	if a.audience != "" {
+		options = append(options, jwt.WithAudience(a.audience))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return a.jwtSecret, nil
-	})
+		return a.verifyKey, nil
+	}, options...)
 
 	if err != nil {
 		return nil, err
@@ -134,6 +226,12 @@ func (a *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 		if err := a.db.Where("id = ? AND active = ?", claims.UserID, true).First(&user).Error; err != nil {
 			return nil, errors.New("user not found or inactive")
 		}
+
+		// Reject tokens issued before the user's tokens were revoked
+		if user.TokensRevokedAt != nil && claims.IssuedAt != nil &&
+			claims.IssuedAt.Time.Before(user.TokensRevokedAt.Truncate(time.Second)) {
+			return nil, errors.New("token has been revoked")
+		}
 		return claims, nil
 	}
 
@@ -276,8 +374,9 @@ func (a *AuthService) UpdateUser(userID uint, req UpdateUserRequest) (*UserRespo
 	}, nil
 }
 
-// DeleteUser deletes user (admin only)
-func (a *AuthService) DeleteUser(userID uint) error {
+// DeleteUser deletes user and everything it owns (admin only). confirm must
+// repeat the username as a safeguard against deleting the wrong account.
+func (a *AuthService) DeleteUser(userID, actorID uint, confirm, ipAddress string) error {
 	// Don't allow deleting the last admin
 	var user database.User
 	if err := a.db.First(&user, userID).Error; err != nil {
@@ -287,6 +386,13 @@ func (a *AuthService) DeleteUser(userID uint) error {
 		return err
 	}
 
+	if confirm != user.Username {
+		return errors.New("confirmation does not match the username")
+	}
+	if userID == actorID {
+		return errors.New("cannot delete your own account")
+	}
+
 	if user.Role == "admin" {
 		var adminCount int64
 		a.db.Model(&database.User{}).Where("role = ? AND active = ?", "admin", true).Count(&adminCount)
@@ -295,7 +401,154 @@ func (a *AuthService) DeleteUser(userID uint) error {
 		}
 	}
 
-	return a.db.Delete(&user).Error
+	// Collected up front, the rows go with the transaction
+	var mediaKeys []string
+	a.db.Model(&database.Message{}).
+		Where("user_id = ? AND media_url <> ''", userID).
+		Pluck("media_url", &mediaKeys)
+
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		listIDs := tx.Model(&database.BroadcastList{}).Select("id").Where("user_id = ?", userID)
+		broadcastIDs := tx.Model(&database.BroadcastMessage{}).Select("id").Where("user_id = ?", userID)
+
+		// Children first, then the records that reference the user
+		// directly. Each step runs only after the previous one succeeded.
+		owned := func(model interface{}) func() error {
+			return func() error {
+				return tx.Where("user_id = ?", userID).Delete(model).Error
+			}
+		}
+		steps := []func() error{
+			func() error {
+				return tx.Where("broadcast_message_id IN (?)", broadcastIDs).Delete(&database.BroadcastDelivery{}).Error
+			},
+			func() error {
+				return tx.Where("broadcast_list_id IN (?)", listIDs).Delete(&database.BroadcastRecipient{}).Error
+			},
+			owned(&database.BroadcastMessage{}),
+			owned(&database.BroadcastList{}),
+			owned(&database.ScheduledMessage{}),
+			owned(&database.Message{}),
+			owned(&database.StatusUpdate{}),
+			owned(&database.Contact{}),
+			owned(&database.Group{}),
+			owned(&database.Device{}),
+		}
+		for _, step := range steps {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.mediaStore != nil {
+		media.DeleteInbound(context.Background(), a.mediaStore, mediaKeys)
+	}
+
+	a.recordAudit(actorID, "user.delete", user.ID, ipAddress)
+	return nil
+}
+
+// SetMediaStore sets the store DeleteUser removes a user's stored attachments from
+func (a *AuthService) SetMediaStore(store media.Store) {
+	a.mediaStore = store
+}
+
+// DeactivateUser disables a user without deleting it and revokes its tokens (admin only)
+func (a *AuthService) DeactivateUser(userID, actorID uint, ipAddress string) (*UserResponse, error) {
+	var user database.User
+	if err := a.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	if userID == actorID {
+		return nil, errors.New("cannot deactivate your own account")
+	}
+	if !user.Active {
+		return nil, errors.New("user is already inactive")
+	}
+
+	if user.Role == "admin" {
+		var adminCount int64
+		a.db.Model(&database.User{}).Where("role = ? AND active = ?", "admin", true).Count(&adminCount)
+		if adminCount <= 1 {
+			return nil, errors.New("cannot deactivate the last admin user")
+		}
+	}
+
+	now := time.Now()
+	if err := a.db.Model(&user).Updates(map[string]interface{}{
+		"active":            false,
+		"tokens_revoked_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	user.Active = false
+
+	a.recordAudit(actorID, "user.deactivate", user.ID, ipAddress)
+
+	return &UserResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		FullName: user.FullName,
+		Role:     user.Role,
+		Active:   user.Active,
+	}, nil
+}
+
+// ReactivateUser enables a previously deactivated user (admin only). Tokens
+// issued before the deactivation stay revoked.
+func (a *AuthService) ReactivateUser(userID, actorID uint, ipAddress string) (*UserResponse, error) {
+	var user database.User
+	if err := a.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	if user.Active {
+		return nil, errors.New("user is already active")
+	}
+
+	if err := a.db.Model(&user).Update("active", true).Error; err != nil {
+		return nil, err
+	}
+	user.Active = true
+
+	a.recordAudit(actorID, "user.reactivate", user.ID, ipAddress)
+
+	return &UserResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		FullName: user.FullName,
+		Role:     user.Role,
+		Active:   user.Active,
+	}, nil
+}
+
+// recordAudit writes an audit entry for an action taken on a user
+func (a *AuthService) recordAudit(actorID uint, action string, targetID uint, ipAddress string) {
+	entry := &database.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   targetID,
+		IPAddress:  ipAddress,
+	}
+	if err := a.db.Create(entry).Error; err != nil {
+		logrus.Errorf("Failed to record audit entry %s for user %d: %v", action, targetID, err)
+	}
 }
 
 // ChangePassword changes user password
@@ -323,6 +576,52 @@ func (a *AuthService) ChangePassword(userID uint, req ChangePasswordRequest) err
 	return a.db.Save(&user).Error
 }
 
+// GetUserLimits returns the broadcast limits configured for a user
+func (a *AuthService) GetUserLimits(userID uint) (*UserLimitsResponse, error) {
+	var user database.User
+	if err := a.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	return &UserLimitsResponse{
+		UserID:                 user.ID,
+		BroadcastMaxRecipients: user.BroadcastMaxRecipients,
+		BroadcastRateLimit:     user.BroadcastRateLimit,
+	}, nil
+}
+
+// SetUserLimits updates the broadcast limits of a user (admin only)
+func (a *AuthService) SetUserLimits(userID uint, req UserLimitsRequest) (*UserLimitsResponse, error) {
+	var user database.User
+	if err := a.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	if req.BroadcastMaxRecipients != nil {
+		user.BroadcastMaxRecipients = *req.BroadcastMaxRecipients
+	}
+	if req.BroadcastRateLimit != nil {
+		user.BroadcastRateLimit = *req.BroadcastRateLimit
+	}
+
+	// Select so that resetting a limit to 0 is persisted too
+	if err := a.db.Model(&user).Select("broadcast_max_recipients", "broadcast_rate_limit").Updates(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &UserLimitsResponse{
+		UserID:                 user.ID,
+		BroadcastMaxRecipients: user.BroadcastMaxRecipients,
+		BroadcastRateLimit:     user.BroadcastRateLimit,
+	}, nil
+}
+
 // GetUserByID returns user from database
 func (a *AuthService) GetUserByID(userID uint) (*database.User, error) {
 	var user database.User
@@ -330,4 +629,4 @@ func (a *AuthService) GetUserByID(userID uint) (*database.User, error) {
 		return nil, err
 	}
 	return &user, nil
-}
\ No newline at end of file
+}
@@ -2,7 +2,9 @@ package broadcast
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,73 +16,206 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrNotConnected is returned when a broadcast can't start because WhatsApp
+// is not connected
+var ErrNotConnected = errors.New("whatsapp not connected")
+
 type Manager struct {
 	cfg      *config.Config
 	db       *gorm.DB
 	waClient *whatsapp.Client
+	notify   Notifier
 	mu       sync.RWMutex
 	active   map[uint]*BroadcastJob
+
+	// createMu makes the duplicate check and the insert atomic, so two
+	// submits racing each other can't both pass
+	createMu sync.Mutex
+
+	cfgMu   sync.RWMutex
+	runtime RuntimeConfig
 }
 
 type BroadcastJob struct {
 	ID              uint
 	BroadcastListID uint
+	Name            string
 	MessageType     string
 	Content         string
 	MediaURL        string
+	Album           []whatsapp.AlbumItem
 	Recipients      []string
-	Status          string
-	SentCount       int
-	FailedCount     int
 	TotalRecipients int
+	RateLimit       int
 	StartedAt       *time.Time
 	CompletedAt     *time.Time
 	cancel          chan bool
+
+	// Status, counters and abort details change while the job runs, once it
+	// is active they're read through state() and updated under stateMu
+	stateMu     sync.Mutex
+	Status      string
+	SentCount   int
+	FailedCount int
+	AbortReason string
+	ResumeIndex int
+
+	// client is the WhatsApp session the job sends through
+	client *whatsapp.Client
+
+	// progress is the broadcast.progress payload, nil unless the broadcast
+	// asked for progress reports
+	progress *Event
+
+	// media is uploaded once and reused for every recipient
+	mediaMu    sync.Mutex
+	media      *whatsapp.UploadedMedia
+	albumMedia []*whatsapp.UploadedMedia
+
+	// sendTimes holds the successful sends of the last minute, for the
+	// achieved send rate
+	sendTimesMu sync.Mutex
+	sendTimes   []time.Time
 }
 
 type BroadcastRequest struct {
-	BroadcastListID uint   `json:"broadcast_list_id" binding:"required"`
-	MessageType     string `json:"message_type" binding:"required"` // text, image, document, audio, video
-	Content         string `json:"content" binding:"required"`
-	MediaURL        string `json:"media_url,omitempty"`
-	ScheduledAt     string `json:"scheduled_at,omitempty"` // RFC3339 format
+	UserID          uint                 `json:"-" form:"-"` // Set from the authenticated user
+	BroadcastListID uint                 `json:"broadcast_list_id" form:"broadcast_list_id" binding:"required"`
+	MessageType     string               `json:"message_type" form:"message_type" binding:"required"` // text, image, document, audio, video, album
+	Content         string               `json:"content" form:"content" binding:"required"`
+	MediaURL        string               `json:"media_url,omitempty" form:"media_url"`
+	Album           []whatsapp.AlbumItem `json:"album,omitempty" form:"-" binding:"omitempty,dive"` // Items for message_type album, content captions the first one
+	ScheduledAt     string               `json:"scheduled_at,omitempty" form:"scheduled_at"`        // RFC3339 format
+	OnlinePresence  *bool                `json:"online_presence,omitempty" form:"online_presence"`  // Defaults to BROADCAST_ONLINE_PRESENCE
+	Name            string               `json:"name,omitempty" form:"name" binding:"max=100"`      // Campaign label shown in history and status
+	Notes           string               `json:"notes,omitempty" form:"notes" binding:"max=2000"`
+	CallbackURL     string               `json:"callback_url,omitempty" form:"callback_url" binding:"omitempty,url,max=500"` // Gets the final summary POSTed, like a one-off webhook
+	ReportProgress  bool                 `json:"report_progress,omitempty" form:"report_progress"`                           // Also POST broadcast.progress to CallbackURL
+	AckMessage      string               `json:"ack_message,omitempty" form:"ack_message" binding:"max=1000"`                // Auto reply to recipients answering the broadcast
+	AckReaction     string               `json:"ack_reaction,omitempty" form:"ack_reaction" binding:"max=16"`                // Emoji reaction to their answer
+	AckWindowMin    int                  `json:"ack_window_min,omitempty" form:"ack_window_min" binding:"min=0,max=43200"`   // Defaults to 24 hours
+	DeviceID        uint                 `json:"device_id,omitempty" form:"device_id"`                                       // Paired device to send from, defaults to the primary session
 }
 
 type BroadcastResponse struct {
-	Success       bool   `json:"success"`
-	BroadcastID   uint   `json:"broadcast_id,omitempty"`
-	Message       string `json:"message"`
-	TotalRecipients int  `json:"total_recipients,omitempty"`
-	EstimatedTime string `json:"estimated_time,omitempty"`
+	Success         bool   `json:"success"`
+	BroadcastID     uint   `json:"broadcast_id,omitempty"`
+	Message         string `json:"message"`
+	TotalRecipients int    `json:"total_recipients,omitempty"`
+	EstimatedTime   string `json:"estimated_time,omitempty"`
+	ConnectionState string `json:"connection_state,omitempty"`
+
+	// RequiresConfirmation is set when the broadcast waits for POST /broadcasts/:id/confirm
+	RequiresConfirmation bool `json:"requires_confirmation,omitempty"`
+
+	// SkippedRecipients counts list entries left out as inactive, duplicate
+	// or invalid, see PreviewRecipients for the details
+	SkippedRecipients int `json:"skipped_recipients,omitempty"`
+
+	// CallbackSecret verifies the X-Broadcast-Signature of callback deliveries.
+	// It is only returned here, when the broadcast is created.
+	CallbackSecret string `json:"callback_secret,omitempty"`
 }
 
 type BroadcastStatus struct {
 	ID              uint       `json:"id"`
 	BroadcastListID uint       `json:"broadcast_list_id"`
+	Name            string     `json:"name,omitempty"`
 	Status          string     `json:"status"`
 	SentCount       int        `json:"sent_count"`
 	FailedCount     int        `json:"failed_count"`
+	DeliveredCount  int        `json:"delivered_count"`
+	ReadCount       int        `json:"read_count"`
 	TotalRecipients int        `json:"total_recipients"`
 	Progress        float64    `json:"progress"`
+	AbortReason     string     `json:"abort_reason,omitempty"`
+	ResumeIndex     int        `json:"resume_index,omitempty"`
 	StartedAt       *time.Time `json:"started_at,omitempty"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
+
+	// Throughput of a running broadcast, CurrentRate in messages per minute
+	// against the RateLimit cap
+	CurrentRate    float64 `json:"current_rate,omitempty"`
+	RateLimit      int     `json:"rate_limit,omitempty"`
+	EffectiveDelay string  `json:"effective_delay,omitempty"`
 }
 
-func NewManager(cfg *config.Config, db *gorm.DB, waClient *whatsapp.Client) *Manager {
-	return &Manager{
+func NewManager(cfg *config.Config, db *gorm.DB, waClient *whatsapp.Client, notify Notifier) *Manager {
+	if notify == nil {
+		notify = func(string, interface{}) {}
+	}
+
+	m := &Manager{
 		cfg:      cfg,
 		db:       db,
 		waClient: waClient,
+		notify:   notify,
 		active:   make(map[uint]*BroadcastJob),
 	}
+	m.runtime = m.loadRuntimeConfig()
+	return m
 }
 
 // CreateBroadcast creates a new broadcast
 func (m *Manager) CreateBroadcast(req *BroadcastRequest) (*BroadcastResponse, error) {
+	// Sending right away with no connection would fail every recipient
+	if req.ScheduledAt == "" {
+		client, err := m.sessionFor(req.UserID, req.DeviceID)
+		if err != nil {
+			return &BroadcastResponse{
+				Success: false,
+				Message: err.Error(),
+			}, err
+		}
+		if !client.IsReady() {
+			return &BroadcastResponse{
+				Success:         false,
+				Message:         "WhatsApp is not connected",
+				ConnectionState: client.ConnectionState(),
+			}, ErrNotConnected
+		}
+	}
+
+	var callbackSecret string
+	if req.CallbackURL != "" {
+		if err := checkCallbackURL(req.CallbackURL); err != nil {
+			return &BroadcastResponse{
+				Success: false,
+				Message: err.Error(),
+			}, err
+		}
+		secret, err := newCallbackSecret()
+		if err != nil {
+			return &BroadcastResponse{
+				Success: false,
+				Message: "Failed to create callback secret",
+			}, err
+		}
+		callbackSecret = secret
+	}
+
+	var album string
+	if req.MessageType == "album" {
+		if err := whatsapp.ValidateAlbum(req.Album); err != nil {
+			return &BroadcastResponse{
+				Success: false,
+				Message: err.Error(),
+			}, err
+		}
+		encoded, err := json.Marshal(req.Album)
+		if err != nil {
+			return &BroadcastResponse{
+				Success: false,
+				Message: "Invalid album",
+			}, err
+		}
+		album = string(encoded)
+	}
+
 	// Validate broadcast list
 	var broadcastList database.BroadcastList
-	if err := m.db.Preload("Recipients").First(&broadcastList, req.BroadcastListID).Error; err != nil {
+	if err := m.db.Where("user_id = ?", req.UserID).First(&broadcastList, req.BroadcastListID).Error; err != nil {
 		return &BroadcastResponse{
 			Success: false,
 			Message: "Broadcast list not found",
@@ -95,11 +230,12 @@ func (m *Manager) CreateBroadcast(req *BroadcastRequest) (*BroadcastResponse, er
 	}
 
 	// Get active recipients
-	activeRecipients := make([]database.BroadcastRecipient, 0)
-	for _, recipient := range broadcastList.Recipients {
-		if recipient.IsActive {
-			activeRecipients = append(activeRecipients, recipient)
-		}
+	activeRecipients, skipped, err := m.listRecipients(&broadcastList)
+	if err != nil {
+		return &BroadcastResponse{
+			Success: false,
+			Message: err.Error(),
+		}, err
 	}
 
 	if len(activeRecipients) == 0 {
@@ -110,23 +246,73 @@ func (m *Manager) CreateBroadcast(req *BroadcastRequest) (*BroadcastResponse, er
 	}
 
 	// Check recipient limit
-	if len(activeRecipients) > m.cfg.Broadcast.MaxRecipients {
+	maxRecipients, _ := m.userLimits(req.UserID)
+	if len(activeRecipients) > maxRecipients {
 		return &BroadcastResponse{
 			Success: false,
-			Message: fmt.Sprintf("Too many recipients. Maximum allowed: %d", m.cfg.Broadcast.MaxRecipients),
+			Message: fmt.Sprintf("Too many recipients. Maximum allowed: %d", maxRecipients),
 		}, fmt.Errorf("too many recipients")
 	}
 
+	if m.cfg.Broadcast.DuplicateWindowSec > 0 {
+		m.createMu.Lock()
+		defer m.createMu.Unlock()
+
+		existing, err := m.findDuplicate(req, album)
+		if err != nil {
+			return &BroadcastResponse{
+				Success: false,
+				Message: "Failed to check for duplicate broadcasts",
+			}, err
+		}
+		if existing != nil {
+			return &BroadcastResponse{
+				Success:     false,
+				BroadcastID: existing.ID,
+				Message: fmt.Sprintf("An identical broadcast to this list was created %s ago",
+					time.Since(existing.CreatedAt).Round(time.Second)),
+			}, ErrDuplicateBroadcast
+		}
+	}
+
+	// Appear online while sending, unless overridden for this broadcast
+	onlinePresence := m.cfg.Broadcast.OnlinePresence
+	if req.OnlinePresence != nil {
+		onlinePresence = *req.OnlinePresence
+	}
+
+	// Large sends wait for an explicit confirmation so a wrong list isn't
+	// blasted by accident
+	threshold := m.cfg.Broadcast.ConfirmThreshold
+	needsConfirmation := req.ScheduledAt == "" && threshold > 0 && len(activeRecipients) > threshold
+
+	status := "pending"
+	if needsConfirmation {
+		status = "pending_confirmation"
+	}
+
 	// Create broadcast message record
 	broadcastMsg := &database.BroadcastMessage{
+		UserID:          req.UserID,
 		BroadcastListID: req.BroadcastListID,
+		DeviceID:        req.DeviceID,
+		Name:            req.Name,
+		Notes:           req.Notes,
+		CallbackURL:     req.CallbackURL,
+		CallbackSecret:  callbackSecret,
+		ReportProgress:  req.CallbackURL != "" && req.ReportProgress,
+		AckMessage:      req.AckMessage,
+		AckReaction:     req.AckReaction,
+		AckWindowMin:    req.AckWindowMin,
 		MessageType:     req.MessageType,
 		Content:         req.Content,
 		MediaURL:        req.MediaURL,
-		Status:          "pending",
+		Album:           album,
+		Status:          status,
 		SentCount:       0,
 		FailedCount:     0,
 		TotalRecipients: len(activeRecipients),
+		OnlinePresence:  onlinePresence,
 	}
 
 	if err := m.db.Create(broadcastMsg).Error; err != nil {
@@ -137,9 +323,23 @@ func (m *Manager) CreateBroadcast(req *BroadcastRequest) (*BroadcastResponse, er
 	}
 
 	// Calculate estimated time
-	delayMs := time.Duration(m.cfg.Broadcast.DelayMS) * time.Millisecond
+	delayMs := time.Duration(m.RuntimeConfig().DelayMS) * time.Millisecond
 	estimatedTime := time.Duration(len(activeRecipients)) * delayMs
 
+	if needsConfirmation {
+		logrus.Infof("Broadcast %d to %d recipients awaits confirmation", broadcastMsg.ID, len(activeRecipients))
+		return &BroadcastResponse{
+			Success:              true,
+			BroadcastID:          broadcastMsg.ID,
+			Message:              fmt.Sprintf("Broadcast to more than %d recipients created, confirm it to start sending", threshold),
+			TotalRecipients:      len(activeRecipients),
+			SkippedRecipients:    len(skipped),
+			EstimatedTime:        estimatedTime.String(),
+			RequiresConfirmation: true,
+			CallbackSecret:       callbackSecret,
+		}, nil
+	}
+
 	// Start broadcast if not scheduled
 	if req.ScheduledAt == "" {
 		go m.executeBroadcast(broadcastMsg.ID, activeRecipients)
@@ -149,15 +349,18 @@ func (m *Manager) CreateBroadcast(req *BroadcastRequest) (*BroadcastResponse, er
 	}
 
 	return &BroadcastResponse{
-		Success:         true,
-		BroadcastID:     broadcastMsg.ID,
-		Message:         "Broadcast created successfully",
-		TotalRecipients: len(activeRecipients),
-		EstimatedTime:   estimatedTime.String(),
+		Success:           true,
+		BroadcastID:       broadcastMsg.ID,
+		Message:           "Broadcast created successfully",
+		TotalRecipients:   len(activeRecipients),
+		SkippedRecipients: len(skipped),
+		EstimatedTime:     estimatedTime.String(),
+		CallbackSecret:    callbackSecret,
 	}, nil
 }
 
-// executeBroadcast executes the broadcast
+// executeBroadcast executes the broadcast against the recipients captured at
+// creation; later changes to the list don't affect it
 func (m *Manager) executeBroadcast(broadcastID uint, recipients []database.BroadcastRecipient) {
 	logrus.Infof("Starting broadcast %d with %d recipients", broadcastID, len(recipients))
 
@@ -168,16 +371,35 @@ func (m *Manager) executeBroadcast(broadcastID uint, recipients []database.Broad
 		return
 	}
 
-	// Update status to sending
+	// The connection may have dropped since the broadcast was created
 	now := time.Now()
+	client, err := m.sessionFor(broadcastMsg.UserID, broadcastMsg.DeviceID)
+	if err == nil && !client.IsReady() {
+		err = fmt.Errorf("%w (%s)", ErrNotConnected, client.ConnectionState())
+	}
+	if err != nil {
+		broadcastMsg.Status = "failed"
+		broadcastMsg.AbortReason = err.Error()
+		broadcastMsg.CompletedAt = &now
+		m.db.Save(&broadcastMsg)
+		m.notify("broadcast.end", NewEvent(&broadcastMsg))
+		logrus.Warnf("Broadcast %d not started: %s", broadcastID, broadcastMsg.AbortReason)
+		return
+	}
+
+	// Update status to sending
 	broadcastMsg.Status = "sending"
 	broadcastMsg.StartedAt = &now
 	m.db.Save(&broadcastMsg)
+	m.notify("broadcast.start", NewEvent(&broadcastMsg))
+
+	_, rateLimit := m.userLimits(broadcastMsg.UserID)
 
 	// Create job
 	job := &BroadcastJob{
 		ID:              broadcastMsg.ID,
 		BroadcastListID: broadcastMsg.BroadcastListID,
+		Name:            broadcastMsg.Name,
 		MessageType:     broadcastMsg.MessageType,
 		Content:         broadcastMsg.Content,
 		MediaURL:        broadcastMsg.MediaURL,
@@ -186,8 +408,15 @@ func (m *Manager) executeBroadcast(broadcastID uint, recipients []database.Broad
 		SentCount:       0,
 		FailedCount:     0,
 		TotalRecipients: len(recipients),
+		RateLimit:       rateLimit,
 		StartedAt:       &now,
 		cancel:          make(chan bool, 1),
+		client:          client,
+	}
+
+	if broadcastMsg.ReportProgress {
+		event := NewEvent(&broadcastMsg)
+		job.progress = &event
 	}
 
 	// Convert recipients to JIDs
@@ -195,13 +424,32 @@ func (m *Manager) executeBroadcast(broadcastID uint, recipients []database.Broad
 		job.Recipients[i] = recipient.JID
 	}
 
+	if broadcastMsg.Album != "" {
+		if err := json.Unmarshal([]byte(broadcastMsg.Album), &job.Album); err != nil {
+			logrus.Errorf("Failed to decode album of broadcast %d: %v", broadcastID, err)
+		}
+	}
+
 	// Add to active jobs
 	m.mu.Lock()
 	m.active[broadcastID] = job
 	m.mu.Unlock()
 
 	// Execute broadcast
+	if broadcastMsg.OnlinePresence {
+		client.HoldOnlinePresence()
+	}
 	m.sendToRecipients(job)
+	if broadcastMsg.OnlinePresence {
+		client.ReleaseOnlinePresence()
+	}
+
+	// Receipts arrive after the sends, give them time before the final counts
+	state := job.state()
+	finished := state.Status == "sending" && state.SentCount+state.FailedCount == job.TotalRecipients
+	if grace := time.Duration(m.cfg.Broadcast.FinalizeGraceSec) * time.Second; finished && grace > 0 {
+		m.awaitReceipts(job, grace)
+	}
 
 	// Remove from active jobs
 	m.mu.Lock()
@@ -209,23 +457,54 @@ func (m *Manager) executeBroadcast(broadcastID uint, recipients []database.Broad
 	m.mu.Unlock()
 
 	// Update final status
+	final := job.state()
 	completedAt := time.Now()
 	broadcastMsg.Status = "completed"
-	broadcastMsg.SentCount = job.SentCount
-	broadcastMsg.FailedCount = job.FailedCount
+	broadcastMsg.SentCount = final.SentCount
+	broadcastMsg.FailedCount = final.FailedCount
+	broadcastMsg.DeliveredCount, broadcastMsg.ReadCount = m.receiptCounts(broadcastID)
 	broadcastMsg.CompletedAt = &completedAt
-	m.db.Save(&broadcastMsg)
+	if final.Status == "aborted" {
+		broadcastMsg.Status = "aborted"
+		broadcastMsg.AbortReason = final.AbortReason
+		broadcastMsg.ResumeIndex = final.ResumeIndex
+	}
+	err = database.RetryOnLock(lockRetryAttempts, lockRetryBackoff, func() error {
+		return m.db.Save(&broadcastMsg).Error
+	})
+	if err != nil {
+		logrus.Warnf("Failed to save final status of broadcast %d: %v", broadcastID, err)
+	}
+	m.notify("broadcast.end", NewEvent(&broadcastMsg))
 
-	logrus.Infof("Broadcast %d completed. Sent: %d, Failed: %d", broadcastID, job.SentCount, job.FailedCount)
+	if final.Status == "aborted" {
+		logrus.Warnf("Broadcast %d aborted at recipient %d: %s", broadcastID, final.ResumeIndex, final.AbortReason)
+		return
+	}
+
+	logrus.Infof("Broadcast %d completed. Sent: %d, Failed: %d, Delivered: %d, Read: %d",
+		broadcastID, final.SentCount, final.FailedCount, broadcastMsg.DeliveredCount, broadcastMsg.ReadCount)
 }
 
 // sendToRecipients sends messages to all recipients
 func (m *Manager) sendToRecipients(job *BroadcastJob) {
-	delayMs := time.Duration(m.cfg.Broadcast.DelayMS) * time.Millisecond
-	rateLimit := m.cfg.Broadcast.RateLimit
+	if workers := m.cfg.Broadcast.Workers; workers > 1 {
+		m.sendToRecipientsConcurrently(job, workers)
+		return
+	}
+
+	rateLimit := job.RateLimit
 	sentInWindow := 0
 	windowStart := time.Now()
 
+	flushInterval := time.Duration(m.cfg.Broadcast.ProgressFlushSec) * time.Second
+	lastFlush := time.Now()
+
+	consecutiveFailures := 0
+
+	// Always persist the final counts, including when cancelled midway
+	defer m.flushProgress(job)
+
 	for i, recipientJID := range job.Recipients {
 		// Check for cancellation
 		select {
@@ -240,6 +519,8 @@ func (m *Manager) sendToRecipients(job *BroadcastJob) {
 			// Wait for next window
 			elapsed := time.Since(windowStart)
 			if elapsed < time.Minute {
+				m.flushProgress(job)
+				lastFlush = time.Now()
 				time.Sleep(time.Minute - elapsed)
 			}
 			sentInWindow = 0
@@ -247,44 +528,186 @@ func (m *Manager) sendToRecipients(job *BroadcastJob) {
 		}
 
 		// Send message
-		var err error
-		switch job.MessageType {
-		case "text":
-			_, err = m.waClient.SendTextMessage(recipientJID, job.Content)
-		case "image", "document", "audio", "video":
-			req := &whatsapp.MediaMessageRequest{
-				To:       recipientJID,
-				MediaURL: job.MediaURL,
-				Type:     job.MessageType,
-				Caption:  job.Content,
-			}
-			_, err = m.waClient.SendMediaMessage(req)
-		default:
-			err = fmt.Errorf("unsupported message type: %s", job.MessageType)
+		resp, attempts, err := m.sendWithRetry(job, recipientJID)
+		m.recordDelivery(job.ID, recipientJID, resp, attempts, err)
+
+		// Nothing was sent to a recipient off the allowlist, skip it without
+		// waiting and without counting it towards an abort
+		if errors.Is(err, whatsapp.ErrRecipientNotAllowed) {
+			logrus.Warnf("Skipped %s: %v", recipientJID, err)
+			job.countResult(false)
+			continue
 		}
 
 		if err != nil {
-			logrus.Errorf("Failed to send message to %s: %v", recipientJID, err)
-			job.FailedCount++
+			logrus.Errorf("Failed to send message to %s after %d attempt(s): %v", recipientJID, attempts, err)
+			job.countResult(false)
+			consecutiveFailures++
+
+			// A run of failures usually means the connection is gone, stop
+			// instead of failing everyone left on the list
+			if maxFailures := m.RuntimeConfig().MaxConsecutiveFailures; maxFailures > 0 && consecutiveFailures >= maxFailures {
+				job.abort(fmt.Sprintf("stopped after %d consecutive failures, last error: %v", consecutiveFailures, err),
+					i+1-consecutiveFailures)
+				return
+			}
 		} else {
 			logrus.Debugf("Message sent to %s", recipientJID)
-			job.SentCount++
+			job.recordSend(time.Now())
+			job.countResult(true)
 			sentInWindow++
+			consecutiveFailures = 0
 		}
 
-		// Update progress in database every 10 messages
-		if (i+1)%10 == 0 || i == len(job.Recipients)-1 {
-			m.db.Model(&database.BroadcastMessage{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
-				"sent_count":   job.SentCount,
-				"failed_count": job.FailedCount,
-			})
+		// Update progress in database every 10 messages, or sooner when
+		// sending is slow (e.g. large media)
+		if (i+1)%10 == 0 || (flushInterval > 0 && time.Since(lastFlush) >= flushInterval) {
+			m.flushProgress(job)
+			lastFlush = time.Now()
 		}
 
 		// Delay between messages
 		if i < len(job.Recipients)-1 {
-			time.Sleep(delayMs)
+			time.Sleep(time.Duration(m.RuntimeConfig().DelayMS) * time.Millisecond)
+		}
+	}
+}
+
+// Writes racing other activity on SQLite can fail with "database is locked",
+// these are retried briefly before giving up
+const (
+	lockRetryAttempts = 4
+	lockRetryBackoff  = 50 * time.Millisecond
+)
+
+// flushProgress persists the job's counters and touches UpdatedAt so the last
+// activity of a running broadcast is visible
+func (m *Manager) flushProgress(job *BroadcastJob) {
+	state := job.state()
+	err := database.RetryOnLock(lockRetryAttempts, lockRetryBackoff, func() error {
+		return m.db.Model(&database.BroadcastMessage{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"sent_count":   state.SentCount,
+			"failed_count": state.FailedCount,
+			"updated_at":   time.Now(),
+		}).Error
+	})
+	if err != nil {
+		// The next flush writes the full counters again, nothing is lost
+		// unless every one of them fails
+		logrus.Warnf("Failed to update progress of broadcast %d: %v", job.ID, err)
+	}
+
+	if job.progress != nil {
+		event := *job.progress
+		event.SentCount, event.FailedCount = state.SentCount, state.FailedCount
+		m.notify("broadcast.progress", event)
+	}
+}
+
+// userLimits returns the recipient and rate limits for a user. Per-user limits
+// override the global config but can never exceed it.
+func (m *Manager) userLimits(userID uint) (maxRecipients, rateLimit int) {
+	rc := m.RuntimeConfig()
+	maxRecipients = rc.MaxRecipients
+	rateLimit = rc.RateLimit
+
+	var user database.User
+	if err := m.db.Select("id", "broadcast_max_recipients", "broadcast_rate_limit").First(&user, userID).Error; err != nil {
+		return maxRecipients, rateLimit
+	}
+
+	if user.BroadcastMaxRecipients > 0 && user.BroadcastMaxRecipients < maxRecipients {
+		maxRecipients = user.BroadcastMaxRecipients
+	}
+	if user.BroadcastRateLimit > 0 && user.BroadcastRateLimit < rateLimit {
+		rateLimit = user.BroadcastRateLimit
+	}
+
+	return maxRecipients, rateLimit
+}
+
+// sendWithRetry sends to a single recipient with the broadcast's retry
+// settings. The client retries transient failures itself, under one message
+// ID per recipient. It returns the number of attempts made.
+func (m *Manager) sendWithRetry(job *BroadcastJob, recipientJID string) (*whatsapp.MessageResponse, int, error) {
+	rc := m.RuntimeConfig()
+	retry := whatsapp.RetryPolicy{
+		Attempts: rc.RetryAttempts,
+		Backoff:  time.Duration(rc.RetryBackoffMS) * time.Millisecond,
+	}
+
+	resp, err := m.sendMessage(job, recipientJID, retry)
+	attempts := 1
+	if resp != nil && resp.Attempts > 0 {
+		attempts = resp.Attempts
+	}
+	return resp, attempts, err
+}
+
+// sendMessage sends the job's message to a single recipient
+func (m *Manager) sendMessage(job *BroadcastJob, recipientJID string, retry whatsapp.RetryPolicy) (*whatsapp.MessageResponse, error) {
+	switch job.MessageType {
+	case "text":
+		return job.client.SendTextMessageWithRetry(recipientJID, job.Content, retry)
+	case "image", "document", "audio", "video":
+		job.mediaMu.Lock()
+		if job.media == nil {
+			media, err := job.client.UploadMedia(job.MediaURL, job.MessageType)
+			if err != nil {
+				job.mediaMu.Unlock()
+				return nil, err
+			}
+			job.media = media
+		}
+		job.mediaMu.Unlock()
+		return job.client.SendUploadedMediaWithRetry(recipientJID, job.media, job.Content, "", retry)
+	case "album":
+		job.mediaMu.Lock()
+		if job.albumMedia == nil {
+			media, err := job.client.UploadAlbum(job.Album)
+			if err != nil {
+				job.mediaMu.Unlock()
+				return nil, err
+			}
+			job.albumMedia = media
+		}
+		job.mediaMu.Unlock()
+		captions := make([]string, len(job.Album))
+		for i, item := range job.Album {
+			captions[i] = item.Caption
+		}
+		if len(captions) > 0 && captions[0] == "" {
+			captions[0] = job.Content
+		}
+		return job.client.SendUploadedAlbumWithRetry(recipientJID, job.albumMedia, captions, retry)
+	default:
+		return nil, fmt.Errorf("unsupported message type: %s", job.MessageType)
+	}
+}
+
+// recordDelivery stores the outcome of sending a broadcast to one recipient
+func (m *Manager) recordDelivery(broadcastID uint, recipientJID string, resp *whatsapp.MessageResponse, attempts int, sendErr error) {
+	delivery := &database.BroadcastDelivery{
+		BroadcastMessageID: broadcastID,
+		JID:                recipientJID,
+		Attempts:           attempts,
+	}
+
+	if sendErr != nil {
+		delivery.Status = "failed"
+		delivery.Error = sendErr.Error()
+	} else {
+		sentAt := time.Now()
+		delivery.Status = "sent"
+		delivery.SentAt = &sentAt
+		if resp != nil {
+			delivery.MessageID = resp.MessageID
 		}
 	}
+
+	if err := m.db.Create(delivery).Error; err != nil {
+		logrus.Errorf("Failed to record delivery to %s for broadcast %d: %v", recipientJID, broadcastID, err)
+	}
 }
 
 // GetBroadcastStatus returns the status of a broadcast
@@ -299,18 +722,32 @@ func (m *Manager) GetBroadcastStatus(broadcastID uint) (*BroadcastStatus, error)
 		progress = float64(broadcastMsg.SentCount+broadcastMsg.FailedCount) / float64(broadcastMsg.TotalRecipients) * 100
 	}
 
-	return &BroadcastStatus{
+	status := &BroadcastStatus{
 		ID:              broadcastMsg.ID,
 		BroadcastListID: broadcastMsg.BroadcastListID,
+		Name:            broadcastMsg.Name,
 		Status:          broadcastMsg.Status,
 		SentCount:       broadcastMsg.SentCount,
 		FailedCount:     broadcastMsg.FailedCount,
+		DeliveredCount:  broadcastMsg.DeliveredCount,
+		ReadCount:       broadcastMsg.ReadCount,
 		TotalRecipients: broadcastMsg.TotalRecipients,
 		Progress:        progress,
+		AbortReason:     broadcastMsg.AbortReason,
+		ResumeIndex:     broadcastMsg.ResumeIndex,
 		StartedAt:       broadcastMsg.StartedAt,
 		CompletedAt:     broadcastMsg.CompletedAt,
 		CreatedAt:       broadcastMsg.CreatedAt,
-	}, nil
+	}
+
+	m.mu.RLock()
+	job, running := m.active[broadcastID]
+	m.mu.RUnlock()
+	if running {
+		job.applyThroughput(status)
+	}
+
+	return status, nil
 }
 
 // CancelBroadcast cancels an active broadcast
@@ -320,6 +757,13 @@ func (m *Manager) CancelBroadcast(broadcastID uint) error {
 	m.mu.RUnlock()
 
 	if !exists {
+		// Broadcasts awaiting confirmation have no job yet
+		result := m.db.Model(&database.BroadcastMessage{}).
+			Where("id = ? AND status = ?", broadcastID, "pending_confirmation").
+			Update("status", "cancelled")
+		if result.Error == nil && result.RowsAffected > 0 {
+			return nil
+		}
 		return fmt.Errorf("broadcast not found or not active")
 	}
 
@@ -337,31 +781,103 @@ func (m *Manager) CancelBroadcast(broadcastID uint) error {
 	return nil
 }
 
-// ListActiveBroadcasts returns all active broadcasts
+// activeStatusOrder groups active broadcasts so running ones are listed before
+// those that are waiting
+var activeStatusOrder = map[string]int{
+	"sending":    0,
+	"finalizing": 1,
+	"paused":     2,
+	"queued":     3,
+	"pending":    3,
+
+	// Not sending yet, but the list is still in use
+	"pending_confirmation": 4,
+}
+
+// pendingStartGrace is how long a pending broadcast counts as active before
+// its job is running
+const pendingStartGrace = time.Minute
+
+// HasActiveBroadcast reports whether a broadcast using the list hasn't
+// finished yet. Running broadcasts work from the recipients captured when they
+// were created, so recipients added later are not included, but the list
+// itself must stay until they finish.
+//
+// Running broadcasts are taken from the jobs in memory, so rows left as
+// sending or pending by a crash or restart don't lock the list forever. Rows
+// awaiting confirmation, and pending ones that were just created or confirmed
+// and whose job hasn't registered yet, still count.
+func (m *Manager) HasActiveBroadcast(listID uint) (bool, error) {
+	m.mu.RLock()
+	for _, job := range m.active {
+		if job.BroadcastListID == listID {
+			m.mu.RUnlock()
+			return true, nil
+		}
+	}
+	m.mu.RUnlock()
+
+	var count int64
+	err := m.db.Model(&database.BroadcastMessage{}).
+		Where("broadcast_list_id = ?", listID).
+		Where("status = ? OR (status = ? AND updated_at > ?)",
+			"pending_confirmation", "pending", time.Now().Add(-pendingStartGrace)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListActiveBroadcasts returns active broadcasts grouped by status and ordered
+// by start time, oldest first
 func (m *Manager) ListActiveBroadcasts() []*BroadcastStatus {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	result := make([]*BroadcastStatus, 0, len(m.active))
 	for _, job := range m.active {
+		state := job.state()
 		progress := float64(0)
 		if job.TotalRecipients > 0 {
-			progress = float64(job.SentCount+job.FailedCount) / float64(job.TotalRecipients) * 100
+			progress = float64(state.SentCount+state.FailedCount) / float64(job.TotalRecipients) * 100
 		}
 
 		status := &BroadcastStatus{
 			ID:              job.ID,
 			BroadcastListID: job.BroadcastListID,
-			Status:          job.Status,
-			SentCount:       job.SentCount,
-			FailedCount:     job.FailedCount,
+			Name:            job.Name,
+			Status:          state.Status,
+			SentCount:       state.SentCount,
+			FailedCount:     state.FailedCount,
 			TotalRecipients: job.TotalRecipients,
 			Progress:        progress,
 			StartedAt:       job.StartedAt,
 			CompletedAt:     job.CompletedAt,
 		}
+		job.applyThroughput(status)
 		result = append(result, status)
 	}
 
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if ra, rb := statusRank(a.Status), statusRank(b.Status); ra != rb {
+			return ra < rb
+		}
+		switch {
+		case a.StartedAt != nil && b.StartedAt != nil && !a.StartedAt.Equal(*b.StartedAt):
+			return a.StartedAt.Before(*b.StartedAt)
+		case a.StartedAt != nil && b.StartedAt == nil:
+			return true
+		case a.StartedAt == nil && b.StartedAt != nil:
+			return false
+		}
+		return a.ID < b.ID
+	})
+
 	return result
-}
\ No newline at end of file
+}
+
+func statusRank(status string) int {
+	if rank, ok := activeStatusOrder[status]; ok {
+		return rank
+	}
+	return len(activeStatusOrder)
+}
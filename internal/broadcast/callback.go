@@ -0,0 +1,45 @@
+package broadcast
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"gowa-broadcast/internal/netguard"
+)
+
+// ErrCallbackNotAllowed is returned when a broadcast's callback URL points at
+// the server's own or a private network
+var ErrCallbackNotAllowed = errors.New("callback URL is not allowed")
+
+// CallbackSignatureHeader carries the HMAC-SHA256 of the callback body, keyed
+// with the secret returned when the broadcast was created
+const CallbackSignatureHeader = "X-Broadcast-Signature"
+
+// checkCallbackURL makes sure a callback can't be used to reach internal
+// services
+func checkCallbackURL(callbackURL string) error {
+	if err := netguard.CheckURL(callbackURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrCallbackNotAllowed, err)
+	}
+	return nil
+}
+
+// newCallbackSecret returns a random key for signing a broadcast's callbacks
+func newCallbackSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// SignCallback returns the CallbackSignatureHeader value for payload
+func SignCallback(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,23 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSignCallback(t *testing.T) {
+	// echo -n '{"event":"broadcast.end"}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=52a2db0ad5898f6600b34cd47c8cd687761a70a7d76f875c97f197f6538b60c3"
+	if got := SignCallback("secret", `{"event":"broadcast.end"}`); got != want {
+		t.Errorf("SignCallback = %s, want %s", got, want)
+	}
+}
+
+func TestCheckCallbackURL(t *testing.T) {
+	if err := checkCallbackURL("http://127.0.0.1:8080/hook"); !errors.Is(err, ErrCallbackNotAllowed) {
+		t.Errorf("checkCallbackURL(loopback) = %v, want ErrCallbackNotAllowed", err)
+	}
+	if err := checkCallbackURL("https://8.8.8.8/hook"); err != nil {
+		t.Errorf("checkCallbackURL(public) = %v, want nil", err)
+	}
+}
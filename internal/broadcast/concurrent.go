@@ -0,0 +1,168 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gowa-broadcast/internal/whatsapp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sendLimiter paces sends shared by all workers of a broadcast: at most
+// rateLimit sends per minute and DelayMS between the start of two sends
+type sendLimiter struct {
+	m           *Manager
+	mu          sync.Mutex
+	rateLimit   int
+	windowStart time.Time
+	inWindow    int
+	lastStart   time.Time
+}
+
+// wait blocks until the next send may start. It returns false when stop is
+// closed first.
+func (l *sendLimiter) wait(stop <-chan struct{}) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for {
+		now := time.Now()
+		if now.Sub(l.windowStart) >= time.Minute {
+			l.windowStart = now
+			l.inWindow = 0
+		}
+
+		var until time.Time
+		if l.inWindow >= l.rateLimit {
+			until = l.windowStart.Add(time.Minute)
+		} else if next := l.lastStart.Add(time.Duration(l.m.RuntimeConfig().DelayMS) * time.Millisecond); now.Before(next) {
+			until = next
+		} else {
+			break
+		}
+
+		timer := time.NewTimer(time.Until(until))
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return false
+		}
+	}
+
+	l.inWindow++
+	l.lastStart = time.Now()
+	return true
+}
+
+type sendResult struct {
+	index    int
+	jid      string
+	resp     *whatsapp.MessageResponse
+	attempts int
+	err      error
+}
+
+// sendToRecipientsConcurrently sends with several workers at once. Pace is
+// governed by the shared limiter; results are handled here only, in order of
+// arrival, and recorded through the job's state lock.
+func (m *Manager) sendToRecipientsConcurrently(job *BroadcastJob, workers int) {
+	limiter := &sendLimiter{m: m, rateLimit: job.RateLimit, windowStart: time.Now()}
+
+	flushInterval := time.Duration(m.cfg.Broadcast.ProgressFlushSec) * time.Second
+	lastFlush := time.Now()
+
+	// Always persist the final counts, including when cancelled midway
+	defer m.flushProgress(job)
+
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	stopSending := func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+	defer stopSending()
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range job.Recipients {
+			select {
+			case indexes <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		select {
+		case <-job.cancel:
+			logrus.Infof("Broadcast %d cancelled", job.ID)
+			stopSending()
+		case <-stop:
+		}
+	}()
+
+	results := make(chan sendResult)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if !limiter.wait(stop) {
+					return
+				}
+				recipientJID := job.Recipients[i]
+				resp, attempts, err := m.sendWithRetry(job, recipientJID)
+				results <- sendResult{index: i, jid: recipientJID, resp: resp, attempts: attempts, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	consecutiveFailures := 0
+	failureRunStart := 0
+	handled := 0
+
+	for result := range results {
+		handled++
+		m.recordDelivery(job.ID, result.jid, result.resp, result.attempts, result.err)
+
+		if errors.Is(result.err, whatsapp.ErrRecipientNotAllowed) {
+			// Not a sign of a lost connection, leave the failure run alone
+			logrus.Warnf("Skipped %s: %v", result.jid, result.err)
+			job.countResult(false)
+		} else if result.err != nil {
+			logrus.Errorf("Failed to send message to %s after %d attempt(s): %v", result.jid, result.attempts, result.err)
+			job.countResult(false)
+			if consecutiveFailures == 0 || result.index < failureRunStart {
+				failureRunStart = result.index
+			}
+			consecutiveFailures++
+
+			// Resuming from the start of the failure run may resend a few
+			// recipients that other workers completed in the meantime
+			if maxFailures := m.RuntimeConfig().MaxConsecutiveFailures; maxFailures > 0 && consecutiveFailures >= maxFailures &&
+				job.abort(fmt.Sprintf("stopped after %d consecutive failures, last error: %v", consecutiveFailures, result.err), failureRunStart) {
+				stopSending()
+			}
+		} else {
+			logrus.Debugf("Message sent to %s", result.jid)
+			job.recordSend(time.Now())
+			job.countResult(true)
+			consecutiveFailures = 0
+		}
+
+		if handled%10 == 0 || (flushInterval > 0 && time.Since(lastFlush) >= flushInterval) {
+			m.flushProgress(job)
+			lastFlush = time.Now()
+		}
+	}
+}
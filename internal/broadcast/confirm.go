@@ -0,0 +1,103 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotAwaitingConfirmation is returned when confirming a broadcast that
+// isn't waiting for it
+var ErrNotAwaitingConfirmation = errors.New("broadcast is not awaiting confirmation")
+
+// ConfirmBroadcast starts a broadcast created in the pending_confirmation
+// state. Recipients are read from the list again, as it is when confirmed.
+func (m *Manager) ConfirmBroadcast(userID, broadcastID uint) (*BroadcastResponse, error) {
+	var broadcastMsg database.BroadcastMessage
+	if err := m.db.Where("user_id = ?", userID).First(&broadcastMsg, broadcastID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBroadcastNotFound
+		}
+		return nil, err
+	}
+
+	if broadcastMsg.Status != "pending_confirmation" {
+		return nil, ErrNotAwaitingConfirmation
+	}
+
+	client, err := m.sessionFor(userID, broadcastMsg.DeviceID)
+	if err != nil {
+		return &BroadcastResponse{
+			Success:     false,
+			BroadcastID: broadcastMsg.ID,
+			Message:     err.Error(),
+		}, err
+	}
+	if !client.IsReady() {
+		return &BroadcastResponse{
+			Success:         false,
+			BroadcastID:     broadcastMsg.ID,
+			Message:         "WhatsApp is not connected",
+			ConnectionState: client.ConnectionState(),
+		}, ErrNotConnected
+	}
+
+	var list database.BroadcastList
+	if err := m.db.First(&list, broadcastMsg.BroadcastListID).Error; err != nil {
+		return nil, err
+	}
+	recipients, skipped, err := m.listRecipients(&list)
+	if err != nil {
+		return &BroadcastResponse{
+			Success:     false,
+			BroadcastID: broadcastMsg.ID,
+			Message:     err.Error(),
+		}, err
+	}
+	if len(recipients) == 0 {
+		return &BroadcastResponse{
+			Success:     false,
+			BroadcastID: broadcastMsg.ID,
+			Message:     "No active recipients found",
+		}, fmt.Errorf("no active recipients")
+	}
+
+	maxRecipients, _ := m.userLimits(userID)
+	if len(recipients) > maxRecipients {
+		return &BroadcastResponse{
+			Success:     false,
+			BroadcastID: broadcastMsg.ID,
+			Message:     fmt.Sprintf("Too many recipients. Maximum allowed: %d", maxRecipients),
+		}, fmt.Errorf("too many recipients")
+	}
+
+	// Claim the broadcast so a double confirm can't start it twice
+	result := m.db.Model(&database.BroadcastMessage{}).
+		Where("id = ? AND status = ?", broadcastMsg.ID, "pending_confirmation").
+		Updates(map[string]interface{}{
+			"status":           "pending",
+			"total_recipients": len(recipients),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotAwaitingConfirmation
+	}
+
+	go m.executeBroadcast(broadcastMsg.ID, recipients)
+
+	delayMs := time.Duration(m.RuntimeConfig().DelayMS) * time.Millisecond
+	return &BroadcastResponse{
+		Success:           true,
+		BroadcastID:       broadcastMsg.ID,
+		Message:           "Broadcast confirmed and started",
+		TotalRecipients:   len(recipients),
+		SkippedRecipients: len(skipped),
+		EstimatedTime:     (time.Duration(len(recipients)) * delayMs).String(),
+	}, nil
+}
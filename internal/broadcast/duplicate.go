@@ -0,0 +1,35 @@
+package broadcast
+
+import (
+	"errors"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateBroadcast is returned when an identical broadcast to the same
+// list was created within BROADCAST_DUPLICATE_WINDOW_SEC
+var ErrDuplicateBroadcast = errors.New("duplicate broadcast")
+
+// findDuplicate returns the user's latest broadcast to the same list with the
+// same message that was created within the duplicate window, or nil. Cancelled
+// and failed broadcasts don't count, those are meant to be submitted again.
+func (m *Manager) findDuplicate(req *BroadcastRequest, album string) (*database.BroadcastMessage, error) {
+	since := time.Now().Add(-time.Duration(m.cfg.Broadcast.DuplicateWindowSec) * time.Second)
+
+	var existing database.BroadcastMessage
+	err := m.db.Where("user_id = ? AND broadcast_list_id = ? AND created_at >= ?", req.UserID, req.BroadcastListID, since).
+		Where("message_type = ? AND content = ? AND media_url = ? AND album = ?", req.MessageType, req.Content, req.MediaURL, album).
+		Where("status NOT IN ?", []string{"cancelled", "failed"}).
+		Order("created_at DESC").
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
@@ -0,0 +1,54 @@
+package broadcast
+
+import (
+	"time"
+
+	"gowa-broadcast/internal/database"
+)
+
+// Notifier is called for broadcast lifecycle events
+type Notifier func(event string, data interface{})
+
+// Event is the webhook payload for broadcast.start, broadcast.progress and
+// broadcast.end
+type Event struct {
+	BroadcastID     uint       `json:"broadcast_id"`
+	UserID          uint       `json:"user_id"`
+	BroadcastListID uint       `json:"broadcast_list_id"`
+	Name            string     `json:"name,omitempty"`
+	Status          string     `json:"status"`
+	TotalRecipients int        `json:"total_recipients"`
+	SentCount       int        `json:"sent_count"`
+	FailedCount     int        `json:"failed_count"`
+	DeliveredCount  int        `json:"delivered_count"`
+	ReadCount       int        `json:"read_count"`
+	AbortReason     string     `json:"abort_reason,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+
+	// CallbackURL is the broadcast's own endpoint that receives the event
+	// besides the subscribed webhooks
+	CallbackURL    string `json:"-"`
+	CallbackSecret string `json:"-"`
+}
+
+// NewEvent builds the webhook payload for a broadcast
+func NewEvent(msg *database.BroadcastMessage) Event {
+	return Event{
+		BroadcastID:     msg.ID,
+		UserID:          msg.UserID,
+		BroadcastListID: msg.BroadcastListID,
+		Name:            msg.Name,
+		Status:          msg.Status,
+		TotalRecipients: msg.TotalRecipients,
+		SentCount:       msg.SentCount,
+		FailedCount:     msg.FailedCount,
+		DeliveredCount:  msg.DeliveredCount,
+		ReadCount:       msg.ReadCount,
+		AbortReason:     msg.AbortReason,
+		StartedAt:       msg.StartedAt,
+		CompletedAt:     msg.CompletedAt,
+		CallbackURL:     msg.CallbackURL,
+		CallbackSecret:  msg.CallbackSecret,
+	}
+}
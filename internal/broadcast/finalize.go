@@ -0,0 +1,60 @@
+package broadcast
+
+import (
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// receiptPollInterval is how often a finalizing broadcast checks whether
+// every sent message has been delivered
+const receiptPollInterval = 5 * time.Second
+
+// awaitReceipts keeps a broadcast whose sends are done in the finalizing
+// state until every sent message has a delivery receipt or grace runs out,
+// so the delivered and read counts stored on completion mean something
+func (m *Manager) awaitReceipts(job *BroadcastJob, grace time.Duration) {
+	job.setStatus("finalizing")
+	state := job.state()
+	m.db.Model(&database.BroadcastMessage{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":       "finalizing",
+		"sent_count":   state.SentCount,
+		"failed_count": state.FailedCount,
+	})
+	logrus.Infof("Broadcast %d finalizing, waiting up to %s for receipts", job.ID, grace)
+
+	ticker := time.NewTicker(receiptPollInterval)
+	defer ticker.Stop()
+	deadline := time.After(grace)
+
+	for {
+		select {
+		case <-deadline:
+			return
+		case <-job.cancel:
+			return
+		case <-ticker.C:
+			if delivered, _ := m.receiptCounts(job.ID); delivered >= state.SentCount {
+				return
+			}
+		}
+	}
+}
+
+// receiptCounts counts the broadcast's messages that were delivered and read
+func (m *Manager) receiptCounts(broadcastID uint) (delivered, read int) {
+	var counts struct {
+		DeliveredCount int
+		ReadCount      int
+	}
+	err := m.db.Model(&database.BroadcastDelivery{}).
+		Select("COUNT(delivered_at) AS delivered_count, COUNT(read_at) AS read_count").
+		Where("broadcast_message_id = ? AND status = ?", broadcastID, "sent").
+		Scan(&counts).Error
+	if err != nil {
+		logrus.Errorf("Failed to count receipts of broadcast %d: %v", broadcastID, err)
+	}
+	return counts.DeliveredCount, counts.ReadCount
+}
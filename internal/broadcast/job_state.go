@@ -0,0 +1,59 @@
+package broadcast
+
+// jobState is a consistent copy of a running job's status and counters
+type jobState struct {
+	Status      string
+	SentCount   int
+	FailedCount int
+	AbortReason string
+	ResumeIndex int
+}
+
+// state returns the job's status and counters. The sender updates them while
+// HTTP handlers read them, so they're only accessed under stateMu.
+func (job *BroadcastJob) state() jobState {
+	job.stateMu.Lock()
+	defer job.stateMu.Unlock()
+
+	return jobState{
+		Status:      job.Status,
+		SentCount:   job.SentCount,
+		FailedCount: job.FailedCount,
+		AbortReason: job.AbortReason,
+		ResumeIndex: job.ResumeIndex,
+	}
+}
+
+// countResult adds a send to the sent or failed counter
+func (job *BroadcastJob) countResult(sent bool) {
+	job.stateMu.Lock()
+	defer job.stateMu.Unlock()
+
+	if sent {
+		job.SentCount++
+	} else {
+		job.FailedCount++
+	}
+}
+
+func (job *BroadcastJob) setStatus(status string) {
+	job.stateMu.Lock()
+	defer job.stateMu.Unlock()
+
+	job.Status = status
+}
+
+// abort marks the job aborted with the index sending can resume from. It
+// returns false when the job was already aborted.
+func (job *BroadcastJob) abort(reason string, resumeIndex int) bool {
+	job.stateMu.Lock()
+	defer job.stateMu.Unlock()
+
+	if job.Status == "aborted" {
+		return false
+	}
+	job.Status = "aborted"
+	job.AbortReason = reason
+	job.ResumeIndex = resumeIndex
+	return true
+}
@@ -0,0 +1,202 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/whatsapp"
+
+	"gorm.io/gorm"
+)
+
+// List source types
+const (
+	SourceStatic = "static"
+	SourceGroup  = "group"
+)
+
+// Reasons a list entry is left out of a broadcast
+const (
+	SkipInactive   = "inactive"
+	SkipDuplicate  = "duplicate"
+	SkipInvalid    = "invalid"
+	SkipNotAllowed = "not_allowed"
+)
+
+// ErrListNotFound is returned when the broadcast list doesn't exist or
+// belongs to another user
+var ErrListNotFound = errors.New("broadcast list not found")
+
+// SkippedRecipient is a list entry a broadcast wouldn't send to
+type SkippedRecipient struct {
+	JID    string `json:"jid"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// RecipientPreview shows who a broadcast to a list would reach right now
+type RecipientPreview struct {
+	BroadcastListID uint                          `json:"broadcast_list_id"`
+	SourceType      string                        `json:"source_type"`
+	Recipients      []database.BroadcastRecipient `json:"recipients"`
+	Skipped         []SkippedRecipient            `json:"skipped"`
+	SkippedByReason map[string]int                `json:"skipped_by_reason"`
+}
+
+// PreviewRecipients resolves a list exactly like CreateBroadcast would,
+// without creating anything
+func (m *Manager) PreviewRecipients(userID, listID uint) (*RecipientPreview, error) {
+	var list database.BroadcastList
+	if err := m.db.Where("user_id = ?", userID).First(&list, listID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrListNotFound
+		}
+		return nil, err
+	}
+
+	recipients, skipped, err := m.listRecipients(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &RecipientPreview{
+		BroadcastListID: list.ID,
+		SourceType:      list.SourceType,
+		Recipients:      recipients,
+		Skipped:         skipped,
+		SkippedByReason: make(map[string]int),
+	}
+	for _, s := range skipped {
+		preview.SkippedByReason[s.Reason]++
+	}
+	return preview, nil
+}
+
+// listRecipients returns who a broadcast to the list goes to and which
+// entries are left out. Static lists use their recipient rows; group lists
+// are resolved against WhatsApp so the broadcast follows the group's current
+// membership. Inactive entries, addresses that can't be parsed, repeats of
+// an address already included and recipients off
+// WHATSAPP_RECIPIENT_ALLOWLIST are skipped.
+func (m *Manager) listRecipients(list *database.BroadcastList) ([]database.BroadcastRecipient, []SkippedRecipient, error) {
+	var candidates []database.BroadcastRecipient
+	if list.SourceType == SourceGroup {
+		jids, err := m.waClient.GroupMemberJIDs(list.SourceGroupJID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve members of group %s: %w", list.SourceGroupJID, err)
+		}
+
+		candidates = make([]database.BroadcastRecipient, len(jids))
+		for i, jid := range jids {
+			candidates[i] = database.BroadcastRecipient{
+				BroadcastListID: list.ID,
+				JID:             jid,
+				IsActive:        true,
+			}
+		}
+	} else if err := m.db.Where("broadcast_list_id = ?", list.ID).Order("id ASC").
+		Find(&candidates).Error; err != nil {
+		return nil, nil, err
+	}
+
+	recipients := make([]database.BroadcastRecipient, 0, len(candidates))
+	skipped := make([]SkippedRecipient, 0)
+	seen := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		skip := SkippedRecipient{JID: candidate.JID, Name: candidate.Name}
+
+		if !candidate.IsActive {
+			skip.Reason = SkipInactive
+			skipped = append(skipped, skip)
+			continue
+		}
+
+		jid, err := m.waClient.NormalizeJID(candidate.JID)
+		if err != nil {
+			skip.Reason, skip.Detail = SkipInvalid, err.Error()
+			skipped = append(skipped, skip)
+			continue
+		}
+		if seen[jid] {
+			skip.Reason, skip.Detail = SkipDuplicate, jid
+			skipped = append(skipped, skip)
+			continue
+		}
+		seen[jid] = true
+
+		if !m.waClient.RecipientJIDAllowed(jid) {
+			skip.Reason, skip.Detail = SkipNotAllowed, whatsapp.ErrRecipientNotAllowed.Error()
+			skipped = append(skipped, skip)
+			continue
+		}
+
+		recipients = append(recipients, candidate)
+	}
+
+	return recipients, skipped, nil
+}
+
+// NotListed is the eligibility reason for a JID that isn't on the list
+const NotListed = "not_listed"
+
+// RecipientEligibility explains whether a broadcast to a list reaches a JID
+type RecipientEligibility struct {
+	BroadcastListID uint   `json:"broadcast_list_id"`
+	JID             string `json:"jid"`
+	Listed          bool   `json:"listed"` // On the list, or a member of a group list
+	Active          bool   `json:"active"`
+	Eligible        bool   `json:"eligible"`         // A broadcast sent now would include it
+	Reason          string `json:"reason,omitempty"` // inactive, duplicate, invalid, not_allowed or not_listed
+	Detail          string `json:"detail,omitempty"`
+	Registered      *bool  `json:"registered,omitempty"` // Whether the number has a WhatsApp account, nil when unknown
+}
+
+// CheckRecipient runs the same filtering as CreateBroadcast and reports what
+// it decides for a single JID, so "why didn't X get the broadcast" is one
+// lookup. The JID may be written any way sending accepts it.
+func (m *Manager) CheckRecipient(userID, listID uint, to string) (*RecipientEligibility, error) {
+	var list database.BroadcastList
+	if err := m.db.Where("user_id = ?", userID).First(&list, listID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrListNotFound
+		}
+		return nil, err
+	}
+
+	jid, err := m.waClient.NormalizeJID(to)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, skipped, err := m.listRecipients(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &RecipientEligibility{BroadcastListID: list.ID, JID: jid, Reason: NotListed}
+	if resolved, err := m.waClient.ResolveJID(jid, ""); err == nil {
+		check.Registered = resolved.Registered
+	}
+
+	same := func(candidate string) bool {
+		normalized, err := m.waClient.NormalizeJID(candidate)
+		return candidate == to || (err == nil && normalized == jid)
+	}
+	for _, recipient := range recipients {
+		if same(recipient.JID) {
+			check.Listed, check.Active, check.Eligible, check.Reason = true, true, true, ""
+			return check, nil
+		}
+	}
+	for _, skip := range skipped {
+		if same(skip.JID) {
+			check.Listed, check.Active = true, skip.Reason != SkipInactive
+			check.Reason, check.Detail = skip.Reason, skip.Detail
+			return check, nil
+		}
+	}
+
+	return check, nil
+}
@@ -0,0 +1,129 @@
+package broadcast
+
+import (
+	"math"
+	"time"
+
+	"gowa-broadcast/internal/database"
+)
+
+// throttleLookback is how far back failed sends and aborted broadcasts count
+// as signs that the account is being throttled
+const throttleLookback = 30 * 24 * time.Hour
+
+// pacingTiers caps the send rate by list size, bigger lists are sent slower
+// since long bursts are what gets numbers flagged
+var pacingTiers = []struct {
+	upTo      int
+	rateLimit int
+}{
+	{100, 30},
+	{1000, 20},
+	{5000, 12},
+	{math.MaxInt32, 8},
+}
+
+// PacingPlan is a delay and rate limit with the throughput they result in
+type PacingPlan struct {
+	DelayMS           int     `json:"delay_ms"`
+	RateLimit         int     `json:"rate_limit"` // Messages per minute
+	MessagesPerMinute float64 `json:"messages_per_minute"`
+	ETASeconds        int64   `json:"eta_seconds"`
+	ETA               string  `json:"eta"`
+}
+
+// Pacing compares the configured pacing with the recommended one for a list
+type Pacing struct {
+	Recipients    int        `json:"recipients"`
+	MaxRecipients int        `json:"max_recipients"`
+	ExceedsMax    bool       `json:"exceeds_max"`
+	Configured    PacingPlan `json:"configured"`
+	Recommended   PacingPlan `json:"recommended"`
+
+	// Signs of throttling in the last 30 days, each halves the recommended
+	// rate once (down to 1 message per minute)
+	AbortedBroadcasts int64 `json:"aborted_broadcasts"`
+	ThrottledSends    int64 `json:"throttled_sends"`
+}
+
+// SuggestPacing recommends a delay and rate limit for sending to recipients,
+// staying within the user's limits and slowing down further when recent
+// broadcasts were aborted or hit WhatsApp's rate limits
+func (m *Manager) SuggestPacing(userID uint, recipients int) (*Pacing, error) {
+	maxRecipients, rateLimit := m.userLimits(userID)
+	delayMS := m.RuntimeConfig().DelayMS
+
+	pacing := &Pacing{
+		Recipients:    recipients,
+		MaxRecipients: maxRecipients,
+		ExceedsMax:    recipients > maxRecipients,
+		Configured:    newPacingPlan(recipients, delayMS, rateLimit),
+	}
+
+	since := time.Now().Add(-throttleLookback)
+	err := m.db.Model(&database.BroadcastMessage{}).
+		Where("user_id = ? AND status = ? AND created_at >= ?", userID, "aborted", since).
+		Count(&pacing.AbortedBroadcasts).Error
+	if err != nil {
+		return nil, err
+	}
+	err = m.db.Model(&database.BroadcastDelivery{}).
+		Joins("JOIN broadcast_messages ON broadcast_messages.id = broadcast_deliveries.broadcast_message_id").
+		Where("broadcast_messages.user_id = ? AND broadcast_deliveries.status = ? AND broadcast_deliveries.created_at >= ?", userID, "failed", since).
+		Where("broadcast_deliveries.error LIKE ? OR broadcast_deliveries.error LIKE ?", "%rate-overlimit%", "%429%").
+		Count(&pacing.ThrottledSends).Error
+	if err != nil {
+		return nil, err
+	}
+
+	recommended := rateLimit
+	for _, tier := range pacingTiers {
+		if recipients <= tier.upTo {
+			if tier.rateLimit < recommended {
+				recommended = tier.rateLimit
+			}
+			break
+		}
+	}
+	signals := pacing.AbortedBroadcasts
+	if pacing.ThrottledSends > 0 {
+		signals++
+	}
+	for ; signals > 0 && recommended > 1; signals-- {
+		recommended /= 2
+	}
+	if recommended < 1 {
+		recommended = 1
+	}
+
+	// Spread the sends over the minute instead of bursting them, but never
+	// go faster than the configured delay
+	recommendedDelay := 60000 / recommended
+	if recommendedDelay < delayMS {
+		recommendedDelay = delayMS
+	}
+	pacing.Recommended = newPacingPlan(recipients, recommendedDelay, recommended)
+
+	return pacing, nil
+}
+
+// newPacingPlan works out the throughput of a delay and rate limit, whichever
+// of the two is slower decides
+func newPacingPlan(recipients, delayMS, rateLimit int) PacingPlan {
+	perMinute := float64(rateLimit)
+	if delayMS > 0 {
+		perMinute = math.Min(perMinute, 60000/float64(delayMS))
+	}
+
+	plan := PacingPlan{
+		DelayMS:           delayMS,
+		RateLimit:         rateLimit,
+		MessagesPerMinute: math.Round(perMinute*100) / 100,
+	}
+	if perMinute > 0 {
+		eta := time.Duration(float64(recipients) / perMinute * float64(time.Minute)).Round(time.Second)
+		plan.ETASeconds = int64(eta.Seconds())
+		plan.ETA = eta.String()
+	}
+	return plan
+}
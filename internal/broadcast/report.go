@@ -0,0 +1,110 @@
+package broadcast
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// maxFailureReasons is how many distinct errors a report lists
+const maxFailureReasons = 5
+
+// FailureReason is an error and how many deliveries failed with it
+type FailureReason struct {
+	Error string `json:"error"`
+	Count int64  `json:"count"`
+}
+
+// TimelinePoint counts the deliveries sent within one hour
+type TimelinePoint struct {
+	Hour  time.Time `json:"hour"`
+	Sent  int64     `json:"sent"`
+	Read  int64     `json:"read"`
+	Total int64     `json:"total"` // Sent and failed
+}
+
+// Report summarizes how a broadcast went, for campaign reporting
+type Report struct {
+	Broadcast      database.BroadcastMessage `json:"broadcast"`
+	DeliveryRate   float64                   `json:"delivery_rate"` // Delivered out of sent, 0 to 1
+	ReadRate       float64                   `json:"read_rate"`     // Read out of sent, 0 to 1
+	FailureRate    float64                   `json:"failure_rate"`  // Failed out of attempted, 0 to 1
+	Timeline       []TimelinePoint           `json:"timeline"`
+	FailureReasons []FailureReason           `json:"failure_reasons"`
+	GeneratedAt    time.Time                 `json:"generated_at"`
+}
+
+// BuildReport aggregates a broadcast's deliveries into rates, an hourly
+// timeline and its most common failure reasons
+func (m *Manager) BuildReport(userID, broadcastID uint) (*Report, error) {
+	report := &Report{GeneratedAt: time.Now()}
+	if err := m.db.Where("user_id = ?", userID).First(&report.Broadcast, broadcastID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBroadcastNotFound
+		}
+		return nil, err
+	}
+
+	// Receipts keep arriving after the broadcast finishes, count them as of now
+	msg := &report.Broadcast
+	msg.DeliveredCount, msg.ReadCount = m.receiptCounts(msg.ID)
+	if msg.SentCount > 0 {
+		report.DeliveryRate = float64(msg.DeliveredCount) / float64(msg.SentCount)
+		report.ReadRate = float64(msg.ReadCount) / float64(msg.SentCount)
+	}
+	if attempted := msg.SentCount + msg.FailedCount; attempted > 0 {
+		report.FailureRate = float64(msg.FailedCount) / float64(attempted)
+	}
+
+	err := m.db.Model(&database.BroadcastDelivery{}).
+		Select("error, COUNT(*) AS count").
+		Where("broadcast_message_id = ? AND status = ?", msg.ID, "failed").
+		Group("error").
+		Order("count DESC").
+		Limit(maxFailureReasons).
+		Scan(&report.FailureReasons).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Bucket in Go, hour truncation differs between SQLite and PostgreSQL
+	var deliveries []database.BroadcastDelivery
+	if err := m.db.Select("status", "sent_at", "read_at", "created_at").
+		Where("broadcast_message_id = ?", msg.ID).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	buckets := make(map[time.Time]*TimelinePoint)
+	for _, delivery := range deliveries {
+		at := delivery.CreatedAt
+		if delivery.SentAt != nil {
+			at = *delivery.SentAt
+		}
+		hour := at.Truncate(time.Hour)
+		point, ok := buckets[hour]
+		if !ok {
+			point = &TimelinePoint{Hour: hour}
+			buckets[hour] = point
+		}
+		point.Total++
+		if delivery.Status == "sent" {
+			point.Sent++
+		}
+		if delivery.ReadAt != nil {
+			point.Read++
+		}
+	}
+	report.Timeline = make([]TimelinePoint, 0, len(buckets))
+	for _, point := range buckets {
+		report.Timeline = append(report.Timeline, *point)
+	}
+	sort.Slice(report.Timeline, func(i, j int) bool {
+		return report.Timeline[i].Hour.Before(report.Timeline[j].Hour)
+	})
+
+	return report, nil
+}
@@ -0,0 +1,145 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBroadcastNotFound is returned when the broadcast doesn't exist or
+	// belongs to another user
+	ErrBroadcastNotFound = errors.New("broadcast not found")
+
+	// ErrDeliveryNotFound is returned when the broadcast was never sent to the JID
+	ErrDeliveryNotFound = errors.New("delivery not found")
+
+	// ErrAlreadyDelivered is returned when resending a delivery that succeeded
+	ErrAlreadyDelivered = errors.New("delivery already succeeded")
+
+	// ErrBroadcastRunning is returned when a broadcast is still sending
+	ErrBroadcastRunning = errors.New("broadcast is still running")
+
+	// ErrResendInProgress is returned when another request is already
+	// resending the delivery
+	ErrResendInProgress = errors.New("delivery is already being resent")
+)
+
+// ResendDelivery sends a broadcast again to one recipient whose delivery
+// failed, e.g. after their number was corrected, and updates the delivery
+// and the broadcast counters with the outcome
+func (m *Manager) ResendDelivery(userID, broadcastID uint, jid string) (*database.BroadcastDelivery, error) {
+	var broadcastMsg database.BroadcastMessage
+	if err := m.db.Where("user_id = ?", userID).First(&broadcastMsg, broadcastID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBroadcastNotFound
+		}
+		return nil, err
+	}
+
+	m.mu.RLock()
+	_, running := m.active[broadcastID]
+	m.mu.RUnlock()
+	if running {
+		return nil, ErrBroadcastRunning
+	}
+
+	var delivery database.BroadcastDelivery
+	if err := m.db.Where("broadcast_message_id = ? AND jid = ?", broadcastID, jid).
+		Order("id DESC").First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	if delivery.Status == "sent" {
+		return &delivery, ErrAlreadyDelivered
+	}
+
+	client, err := m.sessionFor(userID, broadcastMsg.DeviceID)
+	if err != nil {
+		return &delivery, err
+	}
+	if !client.IsReady() {
+		return &delivery, fmt.Errorf("%w (%s)", ErrNotConnected, client.ConnectionState())
+	}
+
+	job := &BroadcastJob{
+		ID:          broadcastMsg.ID,
+		MessageType: broadcastMsg.MessageType,
+		Content:     broadcastMsg.Content,
+		MediaURL:    broadcastMsg.MediaURL,
+		client:      client,
+	}
+	if broadcastMsg.Album != "" {
+		if err := json.Unmarshal([]byte(broadcastMsg.Album), &job.Album); err != nil {
+			return &delivery, fmt.Errorf("failed to decode album: %v", err)
+		}
+	}
+
+	// Claim the delivery so concurrent resends don't send it twice or count
+	// it twice in the broadcast totals. Everything that can fail before
+	// sending is done above, so a claimed row always gets a final status.
+	result := m.db.Model(&database.BroadcastDelivery{}).
+		Where("id = ? AND status = ?", delivery.ID, "failed").
+		Update("status", "resending")
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := m.db.First(&delivery, delivery.ID).Error; err == nil && delivery.Status == "sent" {
+			return &delivery, ErrAlreadyDelivered
+		}
+		return &delivery, ErrResendInProgress
+	}
+
+	resp, attempts, sendErr := m.sendWithRetry(job, jid)
+
+	delivery.Attempts += attempts
+	if sendErr != nil {
+		delivery.Status = "failed"
+		delivery.Error = sendErr.Error()
+	} else {
+		sentAt := time.Now()
+		delivery.Status = "sent"
+		delivery.Error = ""
+		delivery.SentAt = &sentAt
+		if resp != nil {
+			delivery.MessageID = resp.MessageID
+		}
+	}
+
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&delivery).Error; err != nil {
+			return err
+		}
+		if sendErr != nil {
+			return nil
+		}
+		return tx.Model(&database.BroadcastMessage{}).Where("id = ?", broadcastID).Updates(map[string]interface{}{
+			"sent_count":   gorm.Expr("sent_count + 1"),
+			"failed_count": gorm.Expr("CASE WHEN failed_count > 0 THEN failed_count - 1 ELSE 0 END"),
+		}).Error
+	})
+	if err != nil {
+		logrus.Errorf("Failed to record resend to %s for broadcast %d: %v", jid, broadcastID, err)
+		// Release the claim, otherwise every later resend is refused as
+		// already in progress
+		if err := m.db.Model(&database.BroadcastDelivery{}).
+			Where("id = ? AND status = ?", delivery.ID, "resending").
+			Update("status", "failed").Error; err != nil {
+			logrus.Errorf("Failed to release resend claim on delivery %d: %v", delivery.ID, err)
+		}
+		if sendErr == nil {
+			sendErr = err
+		}
+	}
+
+	return &delivery, sendErr
+}
@@ -0,0 +1,108 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const runtimeConfigKey = "broadcast_runtime_config"
+
+// RuntimeConfig is the part of the broadcast config that can be tuned without
+// a restart. It starts from the BROADCAST_* environment variables and is
+// overridden by whatever was last saved through UpdateRuntimeConfig.
+type RuntimeConfig struct {
+	RateLimit              int `json:"rate_limit"`
+	DelayMS                int `json:"delay_ms"`
+	MaxRecipients          int `json:"max_recipients"`
+	RetryAttempts          int `json:"retry_attempts"`
+	RetryBackoffMS         int `json:"retry_backoff_ms"`
+	MaxConsecutiveFailures int `json:"max_consecutive_failures"`
+}
+
+// RuntimeConfigUpdate changes only the fields that are set
+type RuntimeConfigUpdate struct {
+	RateLimit              *int `json:"rate_limit" binding:"omitempty,min=1,max=1000"`
+	DelayMS                *int `json:"delay_ms" binding:"omitempty,min=0,max=600000"`
+	MaxRecipients          *int `json:"max_recipients" binding:"omitempty,min=1,max=100000"`
+	RetryAttempts          *int `json:"retry_attempts" binding:"omitempty,min=0,max=10"`
+	RetryBackoffMS         *int `json:"retry_backoff_ms" binding:"omitempty,min=0,max=600000"`
+	MaxConsecutiveFailures *int `json:"max_consecutive_failures" binding:"omitempty,min=0,max=10000"`
+}
+
+// loadRuntimeConfig returns the environment defaults overlaid with the saved
+// runtime config, if any
+func (m *Manager) loadRuntimeConfig() RuntimeConfig {
+	rc := RuntimeConfig{
+		RateLimit:              m.cfg.Broadcast.RateLimit,
+		DelayMS:                m.cfg.Broadcast.DelayMS,
+		MaxRecipients:          m.cfg.Broadcast.MaxRecipients,
+		RetryAttempts:          m.cfg.Broadcast.RetryAttempts,
+		RetryBackoffMS:         m.cfg.Broadcast.RetryBackoffMS,
+		MaxConsecutiveFailures: m.cfg.Broadcast.MaxConsecutiveFailures,
+	}
+
+	var setting database.Setting
+	if err := m.db.First(&setting, "key = ?", runtimeConfigKey).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Errorf("Failed to load broadcast runtime config: %v", err)
+		}
+		return rc
+	}
+
+	if err := json.Unmarshal([]byte(setting.Value), &rc); err != nil {
+		logrus.Errorf("Ignoring invalid broadcast runtime config: %v", err)
+	}
+	return rc
+}
+
+// RuntimeConfig returns the broadcast config currently in effect
+func (m *Manager) RuntimeConfig() RuntimeConfig {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.runtime
+}
+
+// UpdateRuntimeConfig applies and persists a config change. Running
+// broadcasts keep their rate limit, everything else applies to the next
+// message sent.
+func (m *Manager) UpdateRuntimeConfig(update RuntimeConfigUpdate) (RuntimeConfig, error) {
+	m.cfgMu.Lock()
+	defer m.cfgMu.Unlock()
+
+	rc := m.runtime
+	if update.RateLimit != nil {
+		rc.RateLimit = *update.RateLimit
+	}
+	if update.DelayMS != nil {
+		rc.DelayMS = *update.DelayMS
+	}
+	if update.MaxRecipients != nil {
+		rc.MaxRecipients = *update.MaxRecipients
+	}
+	if update.RetryAttempts != nil {
+		rc.RetryAttempts = *update.RetryAttempts
+	}
+	if update.RetryBackoffMS != nil {
+		rc.RetryBackoffMS = *update.RetryBackoffMS
+	}
+	if update.MaxConsecutiveFailures != nil {
+		rc.MaxConsecutiveFailures = *update.MaxConsecutiveFailures
+	}
+
+	value, err := json.Marshal(rc)
+	if err != nil {
+		return m.runtime, err
+	}
+	setting := database.Setting{Key: runtimeConfigKey, Value: string(value)}
+	if err := m.db.Save(&setting).Error; err != nil {
+		return m.runtime, err
+	}
+
+	m.runtime = rc
+	return rc, nil
+}
@@ -0,0 +1,44 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/whatsapp"
+
+	"gorm.io/gorm"
+)
+
+// ErrDeviceUnavailable is returned when a broadcast asks for a device that
+// isn't known, logged in or connected
+var ErrDeviceUnavailable = errors.New("device is not available")
+
+// sessionFor resolves the WhatsApp session a broadcast of userID sends
+// through. Device 0 is the primary session, any other ID must be a device the
+// user paired, connected at the moment. Only the primary session runs for
+// now, so a device is usable when it is the account the primary session is
+// logged in as; this is where further sessions get looked up once there are
+// more.
+func (m *Manager) sessionFor(userID, deviceID uint) (*whatsapp.Client, error) {
+	if deviceID == 0 {
+		return m.waClient, nil
+	}
+
+	var device database.Device
+	if err := m.db.Where("user_id = ?", userID).First(&device, deviceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: device %d not found", ErrDeviceUnavailable, deviceID)
+		}
+		return nil, err
+	}
+
+	wa := m.waClient.GetClient()
+	if wa == nil || wa.Store == nil || wa.Store.ID == nil || wa.Store.ID.String() != device.JID {
+		return nil, fmt.Errorf("%w: device %d is not logged in", ErrDeviceUnavailable, deviceID)
+	}
+	if !device.Connected || !m.waClient.IsReady() {
+		return nil, fmt.Errorf("%w: device %d is not connected (%s)", ErrDeviceUnavailable, deviceID, m.waClient.ConnectionState())
+	}
+	return m.waClient, nil
+}
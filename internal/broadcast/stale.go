@@ -0,0 +1,97 @@
+package broadcast
+
+import (
+	"fmt"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StaleContact is a recipient whose deliveries kept failing
+type StaleContact struct {
+	JID         string `json:"jid"`
+	Failures    int    `json:"failures"`
+	Registered  *bool  `json:"registered,omitempty"` // Nil when the check itself failed
+	Deactivated int64  `json:"deactivated"`          // Broadcast list entries switched off
+}
+
+// StaleContactReport summarizes a DeactivateStaleContacts run
+type StaleContactReport struct {
+	Threshold   int            `json:"threshold"`
+	DryRun      bool           `json:"dry_run"`
+	Checked     int            `json:"checked"`
+	Deactivated int64          `json:"deactivated"`
+	Contacts    []StaleContact `json:"contacts"`
+}
+
+// DeactivateStaleContacts finds recipients with at least threshold failed
+// broadcast deliveries, confirms with WhatsApp that the number no longer has
+// an account and marks them inactive on all of the user's broadcast lists.
+// Numbers that are still registered are reported but left alone, their
+// failures had another cause. With dryRun nothing is changed.
+func (m *Manager) DeactivateStaleContacts(userID uint, threshold int, dryRun bool) (*StaleContactReport, error) {
+	if threshold <= 0 {
+		threshold = m.cfg.Broadcast.StaleFailureThreshold
+	}
+	if !m.waClient.IsReady() {
+		return nil, fmt.Errorf("%w (%s)", ErrNotConnected, m.waClient.ConnectionState())
+	}
+
+	var candidates []struct {
+		JID      string
+		Failures int
+	}
+	err := m.db.Model(&database.BroadcastDelivery{}).
+		Select("broadcast_deliveries.jid AS jid, COUNT(*) AS failures").
+		Joins("JOIN broadcast_messages ON broadcast_messages.id = broadcast_deliveries.broadcast_message_id").
+		Where("broadcast_messages.user_id = ? AND broadcast_deliveries.status = ?", userID, "failed").
+		Group("broadcast_deliveries.jid").
+		Having("COUNT(*) >= ?", threshold).
+		Order("failures DESC").
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StaleContactReport{
+		Threshold: threshold,
+		DryRun:    dryRun,
+		Checked:   len(candidates),
+		Contacts:  make([]StaleContact, 0),
+	}
+
+	listIDs := m.db.Model(&database.BroadcastList{}).Select("id").Where("user_id = ?", userID)
+	for _, candidate := range candidates {
+		contact := StaleContact{JID: candidate.JID, Failures: candidate.Failures}
+
+		resolved, err := m.waClient.ResolveJID(candidate.JID, "")
+		if err != nil || resolved.Registered == nil {
+			logrus.Warnf("Failed to check registration of %s: %v", candidate.JID, err)
+			report.Contacts = append(report.Contacts, contact)
+			continue
+		}
+		contact.Registered = resolved.Registered
+		if *resolved.Registered {
+			report.Contacts = append(report.Contacts, contact)
+			continue
+		}
+
+		query := m.db.Model(&database.BroadcastRecipient{}).
+			Where("jid = ? AND is_active = ? AND broadcast_list_id IN (?)", candidate.JID, true, listIDs)
+		if dryRun {
+			err = query.Count(&contact.Deactivated).Error
+		} else {
+			result := query.Update("is_active", false)
+			err, contact.Deactivated = result.Error, result.RowsAffected
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		report.Deactivated += contact.Deactivated
+		report.Contacts = append(report.Contacts, contact)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,61 @@
+package broadcast
+
+import (
+	"time"
+)
+
+// throughputWindow is the rolling window the current send rate is measured over
+const throughputWindow = time.Minute
+
+// recordSend remembers when a message was sent, dropping times that fell out
+// of the throughput window
+func (job *BroadcastJob) recordSend(at time.Time) {
+	job.sendTimesMu.Lock()
+	defer job.sendTimesMu.Unlock()
+
+	job.sendTimes = append(job.sendTimes, at)
+
+	cutoff := at.Add(-throughputWindow)
+	drop := 0
+	for drop < len(job.sendTimes) && job.sendTimes[drop].Before(cutoff) {
+		drop++
+	}
+	job.sendTimes = job.sendTimes[drop:]
+}
+
+// applyThroughput fills the achieved send rate, in messages per minute, and
+// the average time between sends over the last minute
+func (job *BroadcastJob) applyThroughput(status *BroadcastStatus) {
+	job.sendTimesMu.Lock()
+	defer job.sendTimesMu.Unlock()
+
+	status.RateLimit = job.RateLimit
+
+	now := time.Now()
+	cutoff := now.Add(-throughputWindow)
+	var recent []time.Time
+	for _, t := range job.sendTimes {
+		if !t.Before(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) == 0 {
+		return
+	}
+
+	// Right after the start the window isn't full yet, scale to a minute
+	window := throughputWindow
+	if job.StartedAt != nil {
+		if elapsed := now.Sub(*job.StartedAt); elapsed < window {
+			window = elapsed
+		}
+	}
+	if window > 0 {
+		status.CurrentRate = float64(len(recent)) / window.Minutes()
+	}
+
+	if len(recent) > 1 {
+		gap := recent[len(recent)-1].Sub(recent[0]) / time.Duration(len(recent)-1)
+		status.EffectiveDelay = gap.Round(time.Millisecond).String()
+	}
+}
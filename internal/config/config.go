@@ -1,105 +1,377 @@
 package config
 
 import (
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// Value sources reported by Config.Sources
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// redactedValue replaces secrets in Config.Redacted
+const redactedValue = "[REDACTED]"
+
+// loadSources collects where each value came from while Load runs
+var loadSources map[string]string
+
 type Config struct {
 	App       AppConfig
 	Database  DatabaseConfig
+	JWT       JWTConfig
 	WhatsApp  WhatsAppConfig
 	Broadcast BroadcastConfig
 	Scheduler SchedulerConfig
+	Webhook   WebhookConfig
+	Log       LogConfig
+	Media     MediaConfig
+
+	// Sources maps each environment variable name to where its value came from
+	Sources map[string]string `json:"-"`
 }
 
 type AppConfig struct {
-	Port      string
-	Debug     bool
-	OS        string
-	BasicAuth string
-	BasePath  string
+	Port            string
+	Debug           bool
+	OS              string
+	BasicAuth       string
+	BasePath        string
+	MaxBodyBytes    int64
+	MaxUploadBytes  int64
+	TLSCertFile     string // HTTPS is served when both cert and key are set
+	TLSKeyFile      string
+	TLSRedirectPort string // Plain HTTP port redirecting to HTTPS, empty disables
 }
 
 type DatabaseConfig struct {
 	URI string
 }
 
+type JWTConfig struct {
+	Algorithm      string // HS256 or RS256
+	Secret         string
+	PrivateKeyFile string // RS256 signing key, PEM
+	PublicKeyFile  string // RS256 verification key, PEM, derived from the private key when empty
+	Issuer         string
+	Audience       string // Empty disables the audience check
+}
+
 type WhatsAppConfig struct {
 	AutoReply           string
+	AfterHoursReply     string
+	BusinessHours       string
+	BusinessTimezone    string
+	BusinessHolidays    string
 	AutoMarkRead        bool
+	AutoMarkReadDelayMS int
+	AutoMarkReadAllow   string
+	AutoMarkReadDeny    string
 	Webhook             string
 	WebhookSecret       string
 	AccountValidation   bool
 	ChatStorage         bool
+	StorageDMOnly       bool
+	StorageSkipGroups   bool
+	StorageJIDs         string
+	StorageKeywords     string
+	StorageTypes        string // Message types that are stored, empty means all
+	StorageMaxContent   int    // Stored content longer than this many characters is truncated, 0 disables
+	MaxStoredMessages   int    // Per user, oldest unstarred messages are pruned above it, 0 disables
+	StoreMedia          bool
+	ConnectRetries      int
+	ConnectBackoffMS    int
+	ConnectTimeoutSec   int
+	DefaultCountryCode  string
+	RecipientAllowlist  string
+	MediaRetryAttempts  int
+	MediaRetryBackoffMS int
+	SendRetryAttempts   int
+	SendRetryBackoffMS  int
+	MediaUserAgent      string
+	MediaHeaders        string // "Name: value" pairs separated by ";"
+	AllowPrivateURLs    bool   // Media and link previews may be fetched from private addresses
+	KeepAliveSec        int
+	IdleReconnectSec    int
+	QRWaitSec           int
+	BusinessCacheSec    int // How long a fetched business profile is reused
+	SyncBatchSize       int // Rows per upsert when syncing contacts and groups
+	SyncWorkers         int // Batches written concurrently, keep 1 on SQLite
 }
 
 type BroadcastConfig struct {
-	RateLimit     int
-	DelayMS       int
-	MaxRecipients int
+	RateLimit              int
+	DelayMS                int
+	MaxRecipients          int
+	RetryAttempts          int
+	RetryBackoffMS         int
+	OnlinePresence         bool
+	ProgressFlushSec       int
+	MaxConsecutiveFailures int
+	ConfirmThreshold       int // Broadcasts to more recipients must be confirmed, 0 disables
+	Workers                int // Recipients sent to concurrently, pace is still set by the rate limit and delay
+	StaleFailureThreshold  int // Failed deliveries before a recipient is checked for deactivation
+	FinalizeGraceSec       int // How long a sent broadcast waits for receipts before completing, 0 disables
+	DuplicateWindowSec     int // An identical broadcast to the same list is rejected within this window, 0 disables
 }
 
 type SchedulerConfig struct {
-	Enabled  bool
-	Timezone string
+	Enabled        bool
+	Timezone       string
+	MinLeadSec     int    // How far in the future a message must be scheduled
+	MaxHorizonDays int    // How far ahead a message may be scheduled, 0 for no limit
+	MissedGraceSec int    // Messages overdue by more than this at startup fall under MissedPolicy
+	MissedPolicy   string // fire or missed
+	RetentionDays  int    // Finished one-off messages are deleted after this many days, 0 keeps them
+}
+
+type WebhookConfig struct {
+	MaxConcurrent int
+	QueueSize     int
+	RawEvents     bool // Forward every whatsmeow event to "raw" subscribers, high volume
+}
+
+type MediaConfig struct {
+	Storage     string // local or s3
+	LocalDir    string
+	S3Endpoint  string // Empty means AWS for S3Region
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+type LogConfig struct {
+	Level      string // Empty means debug when APP_DEBUG is set, info otherwise
+	Format     string // json or text, empty means text when APP_DEBUG is set, json otherwise
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
 }
 
 func Load() *Config {
-	return &Config{
+	loadSources = make(map[string]string)
+
+	cfg := &Config{
 		App: AppConfig{
-			Port:      getEnv("APP_PORT", "3000"),
-			Debug:     getEnvBool("APP_DEBUG", false),
-			OS:        getEnv("APP_OS", "GOWA-Broadcast"),
-			BasicAuth: getEnv("APP_BASIC_AUTH", ""),
-			BasePath:  getEnv("APP_BASE_PATH", ""),
+			Port:            getEnv("APP_PORT", "3000"),
+			Debug:           getEnvBool("APP_DEBUG", false),
+			OS:              getEnv("APP_OS", "GOWA-Broadcast"),
+			BasicAuth:       getEnv("APP_BASIC_AUTH", ""),
+			BasePath:        getEnv("APP_BASE_PATH", ""),
+			MaxBodyBytes:    int64(getEnvInt("APP_MAX_BODY_BYTES", 1<<20)),
+			MaxUploadBytes:  int64(getEnvInt("APP_MAX_UPLOAD_BYTES", 32<<20)),
+			TLSCertFile:     getEnv("TLS_CERT_FILE", ""),
+			TLSKeyFile:      getEnv("TLS_KEY_FILE", ""),
+			TLSRedirectPort: getEnv("TLS_REDIRECT_PORT", ""),
 		},
 		Database: DatabaseConfig{
 			URI: getEnv("DB_URI", "file:storages/whatsapp.db?_foreign_keys=on"),
 		},
+		JWT: JWTConfig{
+			Algorithm:      getEnv("JWT_ALGORITHM", "HS256"),
+			Secret:         getEnv("JWT_SECRET", "your-secret-key"),
+			PrivateKeyFile: getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			PublicKeyFile:  getEnv("JWT_PUBLIC_KEY_FILE", ""),
+			Issuer:         getEnv("JWT_ISSUER", "gowa-broadcast"),
+			Audience:       getEnv("JWT_AUDIENCE", ""),
+		},
 		WhatsApp: WhatsAppConfig{
-			AutoReply:         getEnv("WHATSAPP_AUTO_REPLY", ""),
-			AutoMarkRead:      getEnvBool("WHATSAPP_AUTO_MARK_READ", false),
-			Webhook:           getEnv("WHATSAPP_WEBHOOK", ""),
-			WebhookSecret:     getEnv("WHATSAPP_WEBHOOK_SECRET", "secret"),
-			AccountValidation: getEnvBool("WHATSAPP_ACCOUNT_VALIDATION", true),
-			ChatStorage:       getEnvBool("WHATSAPP_CHAT_STORAGE", true),
+			AutoReply:           getEnv("WHATSAPP_AUTO_REPLY", ""),
+			AfterHoursReply:     getEnv("WHATSAPP_AFTER_HOURS_REPLY", ""),
+			BusinessHours:       getEnv("WHATSAPP_BUSINESS_HOURS", ""),
+			BusinessTimezone:    getEnv("WHATSAPP_BUSINESS_TIMEZONE", "Asia/Jakarta"),
+			BusinessHolidays:    getEnv("WHATSAPP_BUSINESS_HOLIDAYS", ""),
+			AutoMarkRead:        getEnvBool("WHATSAPP_AUTO_MARK_READ", false),
+			AutoMarkReadDelayMS: getEnvInt("WHATSAPP_AUTO_MARK_READ_DELAY_MS", 0),
+			AutoMarkReadAllow:   getEnv("WHATSAPP_AUTO_MARK_READ_ALLOW", ""),
+			AutoMarkReadDeny:    getEnv("WHATSAPP_AUTO_MARK_READ_DENY", ""),
+			Webhook:             getEnv("WHATSAPP_WEBHOOK", ""),
+			WebhookSecret:       getEnv("WHATSAPP_WEBHOOK_SECRET", "secret"),
+			AccountValidation:   getEnvBool("WHATSAPP_ACCOUNT_VALIDATION", true),
+			ChatStorage:         getEnvBool("WHATSAPP_CHAT_STORAGE", true),
+			StorageDMOnly:       getEnvBool("WHATSAPP_STORAGE_DM_ONLY", false),
+			StorageSkipGroups:   getEnvBool("WHATSAPP_STORAGE_SKIP_GROUPS", false),
+			StorageJIDs:         getEnv("WHATSAPP_STORAGE_JIDS", ""),
+			StorageKeywords:     getEnv("WHATSAPP_STORAGE_KEYWORDS", ""),
+			StorageTypes:        getEnv("WHATSAPP_STORAGE_TYPES", ""),
+			StorageMaxContent:   getEnvInt("WHATSAPP_STORAGE_MAX_CONTENT", 0),
+			MaxStoredMessages:   getEnvInt("MAX_STORED_MESSAGES_PER_USER", 0),
+			StoreMedia:          getEnvBool("WHATSAPP_STORE_MEDIA", false),
+			ConnectRetries:      getEnvInt("WHATSAPP_CONNECT_RETRIES", 5),
+			ConnectBackoffMS:    getEnvInt("WHATSAPP_CONNECT_BACKOFF_MS", 2000),
+			ConnectTimeoutSec:   getEnvInt("WHATSAPP_CONNECT_TIMEOUT_SEC", 30),
+			DefaultCountryCode:  getEnv("DEFAULT_COUNTRY_CODE", ""),
+			RecipientAllowlist:  getEnv("WHATSAPP_RECIPIENT_ALLOWLIST", ""),
+			MediaRetryAttempts:  getEnvInt("WHATSAPP_MEDIA_RETRY_ATTEMPTS", 2),
+			MediaRetryBackoffMS: getEnvInt("WHATSAPP_MEDIA_RETRY_BACKOFF_MS", 1000),
+			SendRetryAttempts:   getEnvInt("WHATSAPP_SEND_RETRY_ATTEMPTS", 2),
+			SendRetryBackoffMS:  getEnvInt("WHATSAPP_SEND_RETRY_BACKOFF_MS", 1000),
+			MediaUserAgent:      getEnv("WHATSAPP_MEDIA_USER_AGENT", "GOWA-Broadcast"),
+			MediaHeaders:        getEnv("WHATSAPP_MEDIA_HEADERS", ""),
+			AllowPrivateURLs:    getEnvBool("WHATSAPP_ALLOW_PRIVATE_URLS", false),
+			KeepAliveSec:        getEnvInt("WHATSAPP_KEEPALIVE_SEC", 0),
+			IdleReconnectSec:    getEnvInt("WHATSAPP_IDLE_RECONNECT_SEC", 0),
+			QRWaitSec:           getEnvInt("WHATSAPP_QR_WAIT_SEC", 30),
+			BusinessCacheSec:    getEnvInt("WHATSAPP_BUSINESS_CACHE_SEC", 600),
+			SyncBatchSize:       getEnvInt("WHATSAPP_SYNC_BATCH_SIZE", 200),
+			SyncWorkers:         getEnvInt("WHATSAPP_SYNC_WORKERS", 1),
 		},
 		Broadcast: BroadcastConfig{
-			RateLimit:     getEnvInt("BROADCAST_RATE_LIMIT", 10),
-			DelayMS:       getEnvInt("BROADCAST_DELAY_MS", 1000),
-			MaxRecipients: getEnvInt("BROADCAST_MAX_RECIPIENTS", 100),
+			RateLimit:              getEnvInt("BROADCAST_RATE_LIMIT", 10),
+			DelayMS:                getEnvInt("BROADCAST_DELAY_MS", 1000),
+			MaxRecipients:          getEnvInt("BROADCAST_MAX_RECIPIENTS", 100),
+			RetryAttempts:          getEnvInt("BROADCAST_RETRY_ATTEMPTS", 2),
+			RetryBackoffMS:         getEnvInt("BROADCAST_RETRY_BACKOFF_MS", 2000),
+			OnlinePresence:         getEnvBool("BROADCAST_ONLINE_PRESENCE", false),
+			ProgressFlushSec:       getEnvInt("BROADCAST_PROGRESS_FLUSH_SEC", 5),
+			MaxConsecutiveFailures: getEnvInt("BROADCAST_MAX_CONSECUTIVE_FAILURES", 10),
+			ConfirmThreshold:       getEnvInt("BROADCAST_CONFIRM_THRESHOLD", 0),
+			Workers:                getEnvInt("BROADCAST_WORKERS", 1),
+			StaleFailureThreshold:  getEnvInt("BROADCAST_STALE_FAILURE_THRESHOLD", 3),
+			FinalizeGraceSec:       getEnvInt("BROADCAST_FINALIZE_GRACE_SEC", 0),
+			DuplicateWindowSec:     getEnvInt("BROADCAST_DUPLICATE_WINDOW_SEC", 0),
 		},
 		Scheduler: SchedulerConfig{
-			Enabled:  getEnvBool("SCHEDULER_ENABLED", true),
-			Timezone: getEnv("SCHEDULER_TIMEZONE", "Asia/Jakarta"),
+			Enabled:        getEnvBool("SCHEDULER_ENABLED", true),
+			Timezone:       getEnv("SCHEDULER_TIMEZONE", "Asia/Jakarta"),
+			MinLeadSec:     getEnvInt("SCHEDULER_MIN_LEAD_SEC", 60),
+			MaxHorizonDays: getEnvInt("SCHEDULER_MAX_HORIZON_DAYS", 365),
+			MissedGraceSec: getEnvInt("SCHEDULER_MISSED_GRACE_SEC", 300),
+			MissedPolicy:   getEnv("SCHEDULER_MISSED_POLICY", "missed"),
+			RetentionDays:  getEnvInt("SCHEDULER_RETENTION_DAYS", 0),
+		},
+		Webhook: WebhookConfig{
+			MaxConcurrent: getEnvInt("WEBHOOK_MAX_CONCURRENT", 20),
+			QueueSize:     getEnvInt("WEBHOOK_QUEUE_SIZE", 1000),
+			RawEvents:     getEnvBool("WEBHOOK_RAW_EVENTS", false),
+		},
+		Log: LogConfig{
+			Level:      getEnv("LOG_LEVEL", ""),
+			Format:     getEnv("LOG_FORMAT", ""),
+			File:       getEnv("LOG_FILE", ""),
+			MaxSizeMB:  getEnvInt("LOG_MAX_SIZE_MB", 100),
+			MaxBackups: getEnvInt("LOG_MAX_BACKUPS", 5),
+			MaxAgeDays: getEnvInt("LOG_MAX_AGE_DAYS", 30),
 		},
+		Media: MediaConfig{
+			Storage:     getEnv("MEDIA_STORAGE", "local"),
+			LocalDir:    getEnv("MEDIA_LOCAL_DIR", "storages/media"),
+			S3Endpoint:  getEnv("MEDIA_S3_ENDPOINT", ""),
+			S3Region:    getEnv("MEDIA_S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("MEDIA_S3_BUCKET", ""),
+			S3AccessKey: getEnv("MEDIA_S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("MEDIA_S3_SECRET_KEY", ""),
+		},
+	}
+	cfg.Sources = loadSources
+	loadSources = nil
+
+	return cfg
+}
+
+// MarkFlag records that a command line flag overrode the value of key
+func (c *Config) MarkFlag(key string) {
+	if c.Sources == nil {
+		c.Sources = make(map[string]string)
+	}
+	c.Sources[key] = SourceFlag
+}
+
+// Redacted returns a copy of the config with secrets masked, safe to show to
+// operators
+func (c *Config) Redacted() Config {
+	redacted := *c
+	redacted.Sources = nil
+
+	redacted.JWT.Secret = redactSecret(c.JWT.Secret)
+	redacted.WhatsApp.WebhookSecret = redactSecret(c.WhatsApp.WebhookSecret)
+	redacted.Media.S3SecretKey = redactSecret(c.Media.S3SecretKey)
+	redacted.Database.URI = redactURI(c.Database.URI)
+
+	// Keep usernames and header names, they help debugging
+	var users []string
+	for username := range c.App.ParseBasicAuth() {
+		users = append(users, username+":"+redactedValue)
+	}
+	sort.Strings(users)
+	redacted.App.BasicAuth = strings.Join(users, ",")
+
+	var headers []string
+	for name := range c.WhatsApp.ParseMediaHeaders() {
+		headers = append(headers, name+": "+redactedValue)
+	}
+	sort.Strings(headers)
+	redacted.WhatsApp.MediaHeaders = strings.Join(headers, ";")
+
+	return redacted
+}
+
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURI masks the password of a connection URI
+func redactURI(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return redactedValue
+	}
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		parsed.User = url.UserPassword(parsed.User.Username(), redactedValue)
+		return parsed.String()
+	}
+	return uri
+}
+
+func recordSource(key string, fromEnv bool) {
+	if loadSources == nil {
+		return
+	}
+	if fromEnv {
+		loadSources[key] = SourceEnv
+	} else {
+		loadSources[key] = SourceDefault
 	}
 }
 
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
+		recordSource(key, true)
 		return value
 	}
+	recordSource(key, false)
 	return defaultValue
 }
 
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
+			recordSource(key, true)
 			return parsed
 		}
 	}
+	recordSource(key, false)
 	return defaultValue
 }
 
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := strconv.Atoi(value); err == nil {
+			recordSource(key, true)
 			return parsed
 		}
 	}
+	recordSource(key, false)
 	return defaultValue
 }
 
@@ -126,4 +398,63 @@ func (c *WhatsAppConfig) ParseWebhooks() []string {
 		return []string{}
 	}
 	return strings.Split(c.Webhook, ",")
-}
\ No newline at end of file
+}
+
+// ParseAutoMarkReadAllow parses the chats that may be auto marked as read
+func (c *WhatsAppConfig) ParseAutoMarkReadAllow() []string {
+	return splitList(c.AutoMarkReadAllow)
+}
+
+// ParseAutoMarkReadDeny parses the chats that must never be auto marked as read
+func (c *WhatsAppConfig) ParseAutoMarkReadDeny() []string {
+	return splitList(c.AutoMarkReadDeny)
+}
+
+// ParseBusinessHolidays parses the dates (YYYY-MM-DD) treated as closed
+func (c *WhatsAppConfig) ParseBusinessHolidays() []string {
+	return splitList(c.BusinessHolidays)
+}
+
+// ParseStorageJIDs parses the chats whose messages are stored, empty means all
+func (c *WhatsAppConfig) ParseStorageJIDs() []string {
+	return splitList(c.StorageJIDs)
+}
+
+// ParseStorageKeywords parses the keywords a message must contain to be stored
+func (c *WhatsAppConfig) ParseStorageKeywords() []string {
+	return splitList(c.StorageKeywords)
+}
+
+// ParseStorageTypes parses the message types that are stored, empty means all
+func (c *WhatsAppConfig) ParseStorageTypes() []string {
+	return splitList(c.StorageTypes)
+}
+
+// ParseRecipientAllowlist parses the patterns of recipients messages may be
+// sent to, empty means all
+func (c *WhatsAppConfig) ParseRecipientAllowlist() []string {
+	return splitList(c.RecipientAllowlist)
+}
+
+// ParseMediaHeaders parses the default headers sent when downloading media
+func (c *WhatsAppConfig) ParseMediaHeaders() map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(c.MediaHeaders, ";") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) != "" {
+			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return headers
+}
+
+// splitList splits a comma separated value into trimmed, non-empty items
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -59,6 +59,10 @@ func Initialize(dbURI string) (*gorm.DB, error) {
 
 // Auto migrate all models
 func autoMigrate(db *gorm.DB) error {
+	if err := dedupeForUniqueIndexes(db); err != nil {
+		return err
+	}
+
 	err := db.AutoMigrate(
 		&User{},
 		&Device{},
@@ -68,9 +72,13 @@ func autoMigrate(db *gorm.DB) error {
 		&BroadcastList{},
 		&BroadcastRecipient{},
 		&BroadcastMessage{},
+		&BroadcastDelivery{},
 		&ScheduledMessage{},
 		&Webhook{},
 		&WebhookLog{},
+		&AuditLog{},
+		&StatusUpdate{},
+		&Setting{},
 	)
 	if err != nil {
 		return err
@@ -108,6 +116,13 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
+	// Broadcast limits, 0 means use the global BROADCAST_* setting
+	BroadcastMaxRecipients int `gorm:"default:0" json:"broadcast_max_recipients"`
+	BroadcastRateLimit     int `gorm:"default:0" json:"broadcast_rate_limit"`
+
+	// Tokens issued before this time are rejected
+	TokensRevokedAt *time.Time `json:"-"`
+
 	// Relations
 	Devices         []Device         `gorm:"foreignKey:UserID" json:"devices,omitempty"`
 	Contacts        []Contact        `gorm:"foreignKey:UserID" json:"contacts,omitempty"`
@@ -137,26 +152,53 @@ type Device struct {
 
 // Contact represents WhatsApp contact
 type Contact struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	UserID      uint      `gorm:"not null;index" json:"user_id"`
-	JID         string    `gorm:"index" json:"jid"`
-	Name        string    `json:"name"`
-	PushName    string    `json:"push_name"`
-	PhoneNumber string    `json:"phone_number"`
-	IsGroup     bool      `json:"is_group"`
-	IsBlocked   bool      `json:"is_blocked"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	UserID            uint       `gorm:"not null;index;uniqueIndex:idx_contacts_user_jid" json:"user_id"`
+	JID               string     `gorm:"index;uniqueIndex:idx_contacts_user_jid" json:"jid"`
+	Name              string     `json:"name"`
+	PushName          string     `json:"push_name"`
+	PhoneNumber       string     `json:"phone_number"`
+	IsGroup           bool       `json:"is_group"`
+	IsBlocked         bool       `json:"is_blocked"`
+	IsOnline          bool       `json:"is_online"`
+	LastSeen          *time.Time `json:"last_seen,omitempty"`
+	PresenceUpdatedAt *time.Time `json:"presence_updated_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// DisplayName is computed by ResolveDisplayName, it isn't stored
+	DisplayName string `gorm:"-" json:"display_name"`
+
+	// Business profile as last fetched from WhatsApp, BusinessProfile is JSON
+	IsBusiness        bool       `json:"is_business"`
+	BusinessProfile   string     `gorm:"type:text" json:"business_profile,omitempty"`
+	BusinessCheckedAt *time.Time `json:"business_checked_at,omitempty"`
 
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
+// ResolveDisplayName fills DisplayName with the saved name, falling back to
+// the WhatsApp push name and then the phone number
+func (c *Contact) ResolveDisplayName() string {
+	switch {
+	case c.Name != "":
+		c.DisplayName = c.Name
+	case c.PushName != "":
+		c.DisplayName = c.PushName
+	case c.PhoneNumber != "":
+		c.DisplayName = c.PhoneNumber
+	default:
+		c.DisplayName = strings.SplitN(c.JID, "@", 2)[0]
+	}
+	return c.DisplayName
+}
+
 // Group represents WhatsApp group
 type Group struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	UserID      uint      `gorm:"not null;index" json:"user_id"`
-	JID         string    `gorm:"index" json:"jid"`
+	UserID      uint      `gorm:"not null;index;uniqueIndex:idx_groups_user_jid" json:"user_id"`
+	JID         string    `gorm:"index;uniqueIndex:idx_groups_user_jid" json:"jid"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	OwnerJID    string    `json:"owner_jid"`
@@ -169,18 +211,22 @@ type Group struct {
 
 // Message represents WhatsApp message
 type Message struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	UserID      uint      `gorm:"not null;index" json:"user_id"`
-	MessageID   string    `gorm:"index" json:"message_id"`
-	FromJID     string    `json:"from_jid"`
-	ToJID       string    `json:"to_jid"`
-	Type        string    `json:"type"`
-	Content     string    `json:"content"`
-	MediaURL    string    `json:"media_url,omitempty"`
-	Timestamp   time.Time `json:"timestamp"`
-	IsFromMe    bool      `json:"is_from_me"`
-	IsRead      bool      `json:"is_read"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index;uniqueIndex:idx_messages_user_message" json:"user_id"`
+	MessageID string     `gorm:"uniqueIndex:idx_messages_user_message" json:"message_id"`
+	FromJID   string     `json:"from_jid"`
+	ToJID     string     `json:"to_jid"`
+	Type      string     `json:"type"`
+	Content   string     `json:"content"`
+	MediaURL  string     `json:"media_url,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+	IsFromMe  bool       `json:"is_from_me"`
+	IsRead    bool       `json:"is_read"`
+	IsEdited  bool       `json:"is_edited"`
+	IsStarred bool       `gorm:"default:false;index" json:"is_starred"`
+	Truncated bool       `gorm:"default:false" json:"truncated,omitempty"` // Content was cut to WHATSAPP_STORAGE_MAX_CONTENT
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -198,6 +244,12 @@ type BroadcastList struct {
 	CreatedAt   time.Time            `json:"created_at"`
 	UpdatedAt   time.Time            `json:"updated_at"`
 
+	// SourceType static sends to the stored recipients, group sends to the
+	// members of SourceGroupJID as they are when the broadcast is created.
+	// There is no tag source, contacts and recipients carry no tags yet.
+	SourceType     string `gorm:"default:static" json:"source_type"`
+	SourceGroupJID string `json:"source_group_jid,omitempty"`
+
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
@@ -215,26 +267,58 @@ type BroadcastRecipient struct {
 
 // BroadcastMessage represents a broadcast message
 type BroadcastMessage struct {
-	ID              uint      `gorm:"primaryKey" json:"id"`
-	UserID          uint      `gorm:"not null;index" json:"user_id"`
-	BroadcastListID uint      `json:"broadcast_list_id"`
-	MessageType     string    `json:"message_type"`
-	Content         string    `json:"content"`
-	MediaURL        string    `json:"media_url,omitempty"`
-	Status          string    `json:"status"` // pending, sending, completed, failed
-	SentCount       int       `json:"sent_count"`
-	FailedCount     int       `json:"failed_count"`
-	TotalRecipients int       `json:"total_recipients"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	BroadcastListID uint       `json:"broadcast_list_id"`
+	DeviceID        uint       `json:"device_id,omitempty"`         // Device the broadcast sends from, 0 for the primary session
+	Name            string     `gorm:"index" json:"name,omitempty"` // Campaign label, e.g. "July Promo"
+	Notes           string     `gorm:"type:text" json:"notes,omitempty"`
+	MessageType     string     `json:"message_type"`
+	Content         string     `json:"content"`
+	MediaURL        string     `json:"media_url,omitempty"`
+	Album           string     `gorm:"type:text" json:"album,omitempty"` // JSON array of album items
+	Status          string     `json:"status"`                           // pending, sending, finalizing, completed, failed, cancelled, aborted
+	SentCount       int        `json:"sent_count"`
+	FailedCount     int        `json:"failed_count"`
+	DeliveredCount  int        `json:"delivered_count"`
+	ReadCount       int        `json:"read_count"`
+	TotalRecipients int        `json:"total_recipients"`
+	OnlinePresence  bool       `json:"online_presence"`
+	AbortReason     string     `json:"abort_reason,omitempty"`
+	ResumeIndex     int        `json:"resume_index,omitempty"` // Position in the recipient list to resume an aborted broadcast from
+	CallbackURL     string     `json:"callback_url,omitempty"` // Receives this broadcast's events besides the global webhooks
+	CallbackSecret  string     `json:"-"`                      // Signs the callback deliveries
+	ReportProgress  bool       `json:"report_progress,omitempty"`
+	AckMessage      string     `gorm:"type:text" json:"ack_message,omitempty"` // Sent once to each recipient that replies
+	AckReaction     string     `json:"ack_reaction,omitempty"`                 // Emoji reacted to the reply
+	AckWindowMin    int        `json:"ack_window_min,omitempty"`               // Minutes after StartedAt replies are acknowledged
 	StartedAt       *time.Time `json:"started_at,omitempty"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 
 	// Relations
 	User          User          `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	BroadcastList BroadcastList `gorm:"foreignKey:BroadcastListID" json:"broadcast_list,omitempty"`
 }
 
+// BroadcastDelivery represents the delivery of a broadcast to a single recipient
+type BroadcastDelivery struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	BroadcastMessageID uint       `gorm:"not null;index" json:"broadcast_message_id"`
+	JID                string     `gorm:"index" json:"jid"`
+	MessageID          string     `gorm:"index" json:"message_id,omitempty"`
+	Status             string     `json:"status"` // sent, failed
+	Attempts           int        `json:"attempts"`
+	Error              string     `json:"error,omitempty"`
+	SentAt             *time.Time `json:"sent_at,omitempty"`
+	DeliveredAt        *time.Time `json:"delivered_at,omitempty"` // From the recipient's delivery receipt
+	ReadAt             *time.Time `json:"read_at,omitempty"`
+	AckedAt            *time.Time `json:"acked_at,omitempty"` // When the recipient's reply was acknowledged
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
 // ScheduledMessage represents a scheduled message
 type ScheduledMessage struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
@@ -245,7 +329,7 @@ type ScheduledMessage struct {
 	Content     string    `json:"content"`
 	MediaURL    string    `json:"media_url,omitempty"`
 	ScheduledAt time.Time `json:"scheduled_at"`
-	Status      string    `json:"status"` // pending, sent, failed, cancelled
+	Status      string    `json:"status"`              // pending, sending, sent, failed, cancelled, missed
 	CronExpr    string    `json:"cron_expr,omitempty"` // For recurring messages
 	IsRecurring bool      `json:"is_recurring"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -265,6 +349,14 @@ type Webhook struct {
 	Active    bool      `gorm:"default:true" json:"active"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Batching, events are delivered one per request when both are zero
+	BatchWindowMS int `json:"batch_window_ms"`
+	BatchMaxSize  int `json:"batch_max_size"`
+
+	// PayloadTemplate is a text/template reshaping the body, empty sends the
+	// default envelope
+	PayloadTemplate string `gorm:"type:text" json:"payload_template"`
 }
 
 // WebhookLog represents webhook delivery log
@@ -276,5 +368,39 @@ type WebhookLog struct {
 	StatusCode   int       `json:"status_code"`
 	ResponseBody string    `gorm:"type:text" json:"response_body"`
 	Error        string    `json:"error"`
+	IsReplay     bool      `gorm:"default:false" json:"is_replay"`
 	CreatedAt    time.Time `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// AuditLog records administrative actions
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorID    uint      `gorm:"index" json:"actor_id"`
+	Action     string    `gorm:"index" json:"action"` // user.deactivate, user.reactivate, ...
+	TargetType string    `json:"target_type"`
+	TargetID   uint      `gorm:"index" json:"target_id"`
+	Details    string    `gorm:"type:text" json:"details,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// StatusUpdate represents a posted WhatsApp status (story)
+type StatusUpdate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	MessageID string    `gorm:"index" json:"message_id"`
+	Type      string    `json:"type"` // text, image, video
+	Content   string    `json:"content"`
+	MediaURL  string    `json:"media_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// Setting stores a runtime setting that overrides the environment config
+type Setting struct {
+	Key       string    `gorm:"primaryKey" json:"key"`
+	Value     string    `gorm:"type:text" json:"value"` // JSON encoded
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// uniqueIndex is a unique index added to a table that may already hold rows
+// violating it
+type uniqueIndex struct {
+	model   interface{}
+	table   string
+	name    string
+	columns string
+	keep    string // MIN or MAX, which id of a duplicate group survives
+}
+
+// uniqueIndexes lists the unique indexes added after the first release
+var uniqueIndexes = []uniqueIndex{
+	// The latest sync has the freshest names
+	{&Contact{}, "contacts", "idx_contacts_user_jid", "user_id, jid", "MAX"},
+	{&Group{}, "groups", "idx_groups_user_jid", "user_id, jid", "MAX"},
+
+	// Redelivered messages, the first copy is the one webhooks saw
+	{&Message{}, "messages", "idx_messages_user_message", "user_id, message_id", "MIN"},
+}
+
+// dedupeForUniqueIndexes removes the duplicate rows older databases may
+// have, which would otherwise make AutoMigrate fail to create the unique
+// indexes. It only runs while an index is missing.
+func dedupeForUniqueIndexes(db *gorm.DB) error {
+	for _, idx := range uniqueIndexes {
+		if !db.Migrator().HasTable(idx.model) || db.Migrator().HasIndex(idx.model, idx.name) {
+			continue
+		}
+
+		// Quoted, groups is a keyword in newer SQLite versions
+		result := db.Exec(fmt.Sprintf(
+			`DELETE FROM "%s" WHERE id NOT IN (SELECT %s(id) FROM "%s" GROUP BY %s)`,
+			idx.table, idx.keep, idx.table, idx.columns))
+		if result.Error != nil {
+			return fmt.Errorf("failed to remove duplicate %s: %v", idx.table, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Removed %d duplicate %s before creating %s", result.RowsAffected, idx.table, idx.name)
+		}
+	}
+	return nil
+}
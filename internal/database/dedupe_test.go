@@ -0,0 +1,71 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	return db
+}
+
+func TestAutoMigrateRemovesDuplicatesBeforeUniqueIndexes(t *testing.T) {
+	db := openTestDB(t)
+
+	// A table from before the unique index, holding duplicates
+	if err := db.Exec(`CREATE TABLE contacts (id integer PRIMARY KEY AUTOINCREMENT, user_id integer NOT NULL, jid text, name text)`).Error; err != nil {
+		t.Fatalf("create contacts: %v", err)
+	}
+	for _, name := range []string{"Old", "New"} {
+		db.Exec(`INSERT INTO contacts (user_id, jid, name) VALUES (1, '6281@s.whatsapp.net', ?)`, name)
+	}
+	db.Exec(`INSERT INTO contacts (user_id, jid, name) VALUES (2, '6281@s.whatsapp.net', 'Other user')`)
+
+	if err := autoMigrate(db); err != nil {
+		t.Fatalf("autoMigrate: %v", err)
+	}
+
+	var contacts []Contact
+	db.Order("user_id").Find(&contacts)
+	if len(contacts) != 2 {
+		t.Fatalf("got %d contacts, want 2", len(contacts))
+	}
+	if contacts[0].Name != "New" {
+		t.Errorf("kept %q, want the latest row", contacts[0].Name)
+	}
+	if !db.Migrator().HasIndex(&Contact{}, "idx_contacts_user_jid") {
+		t.Error("unique index was not created")
+	}
+}
+
+func TestAutoMigrateKeepsFirstRedeliveredMessage(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := db.Exec(`CREATE TABLE messages (id integer PRIMARY KEY AUTOINCREMENT, user_id integer NOT NULL, message_id text, content text)`).Error; err != nil {
+		t.Fatalf("create messages: %v", err)
+	}
+	for _, content := range []string{"first", "redelivered"} {
+		db.Exec(`INSERT INTO messages (user_id, message_id, content) VALUES (1, '3EB0DEADBEEF', ?)`, content)
+	}
+
+	if err := autoMigrate(db); err != nil {
+		t.Fatalf("autoMigrate: %v", err)
+	}
+
+	var messages []Message
+	db.Find(&messages)
+	if len(messages) != 1 || messages[0].Content != "first" {
+		t.Errorf("got %+v, want only the first copy", messages)
+	}
+}
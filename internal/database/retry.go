@@ -0,0 +1,32 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// IsLocked reports whether err is SQLite refusing a write because another
+// connection holds the lock. Such errors clear up once the other write ends.
+func IsLocked(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "SQLITE_BUSY")
+}
+
+// RetryOnLock runs fn up to attempts times while it fails with a lock error,
+// doubling the wait after every try. Other errors are returned right away.
+func RetryOnLock(attempts int, backoff time.Duration, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if !IsLocked(err) || attempt >= attempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openLockedDB returns a connection that fails fast on locks and a
+// transaction on a second connection holding the write lock on the same file
+func openLockedDB(t *testing.T) (*gorm.DB, *gorm.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "locked.db")
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	holder, err := gorm.Open(sqlite.Open(path), config)
+	if err != nil {
+		t.Fatalf("open holder: %v", err)
+	}
+	if err := holder.Exec(`CREATE TABLE items (id integer PRIMARY KEY)`).Error; err != nil {
+		t.Fatalf("create items: %v", err)
+	}
+
+	writer, err := gorm.Open(sqlite.Open(path+"?_busy_timeout=0"), config)
+	if err != nil {
+		t.Fatalf("open writer: %v", err)
+	}
+
+	tx := holder.Begin()
+	if err := tx.Exec(`INSERT INTO items (id) VALUES (1)`).Error; err != nil {
+		t.Fatalf("take write lock: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return writer, tx
+}
+
+func TestRetryOnLockGivesUpWhileLocked(t *testing.T) {
+	writer, _ := openLockedDB(t)
+
+	calls := 0
+	err := RetryOnLock(3, time.Millisecond, func() error {
+		calls++
+		return writer.Exec(`INSERT INTO items (id) VALUES (2)`).Error
+	})
+	if !IsLocked(err) {
+		t.Fatalf("RetryOnLock() error = %v, want a lock error", err)
+	}
+	if calls != 3 {
+		t.Errorf("fn ran %d times, want 3", calls)
+	}
+}
+
+func TestRetryOnLockSucceedsOnceReleased(t *testing.T) {
+	writer, holder := openLockedDB(t)
+
+	calls := 0
+	err := RetryOnLock(3, time.Millisecond, func() error {
+		calls++
+		err := writer.Exec(`INSERT INTO items (id) VALUES (2)`).Error
+		if calls == 1 {
+			if !IsLocked(err) {
+				t.Errorf("first write error = %v, want a lock error", err)
+			}
+			holder.Commit()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RetryOnLock() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("fn ran %d times, want 2", calls)
+	}
+
+	var count int64
+	writer.Table("items").Count(&count)
+	if count != 2 {
+		t.Errorf("got %d rows, want 2", count)
+	}
+}
+
+func TestRetryOnLockReturnsOtherErrors(t *testing.T) {
+	boom := errors.New("boom")
+	calls := 0
+	err := RetryOnLock(3, time.Millisecond, func() error {
+		calls++
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("RetryOnLock() error = %v, want %v", err, boom)
+	}
+	if calls != 1 {
+		t.Errorf("fn ran %d times, want 1", calls)
+	}
+	if IsLocked(nil) {
+		t.Error("IsLocked(nil) = true")
+	}
+}
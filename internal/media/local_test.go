@@ -0,0 +1,120 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestLocalStorePutGetDelete(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+	key := InboundKey("6281234567890", "3EB0DEADBEEF", "image/jpeg")
+
+	if err := store.Put(ctx, key, []byte("jpeg bytes"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	body, contentType, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "jpeg bytes" {
+		t.Errorf("Get returned %q", data)
+	}
+	if contentType != "image/jpeg" {
+		t.Errorf("content type = %q, want image/jpeg", contentType)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object = %v, want nil", err)
+	}
+}
+
+func TestLocalStoreSniffsUnknownTypes(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+	key := InboundKey("6281234567890", "3EB0CAFE", "application/x-unknown")
+
+	if err := store.Put(ctx, key, []byte("%PDF-1.4 body"), "application/x-unknown"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	body, contentType, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	body.Close()
+	if contentType != "application/pdf" {
+		t.Errorf("content type = %q, want the sniffed application/pdf", contentType)
+	}
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"../outside", "inbound/../../outside", "/absolute", ""} {
+		if err := store.Put(ctx, key, []byte("x"), ""); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded, want an invalid key error", key)
+		}
+	}
+}
+
+func TestInboundKey(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{"image/jpeg", "inbound/628/ID.jpg"},
+		{"audio/ogg; codecs=opus", "inbound/628/ID.ogg"},
+		{"Application/PDF", "inbound/628/ID.pdf"},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "inbound/628/ID.docx"},
+		{"application/x-unknown", "inbound/628/ID"},
+		{"", "inbound/628/ID"},
+	}
+
+	for _, tt := range tests {
+		if got := InboundKey("628", "ID", tt.mimeType); got != tt.want {
+			t.Errorf("InboundKey(%q) = %q, want %q", tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteInboundSkipsURLs(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+	key := InboundKey("628", "ID", "image/png")
+	store.Put(ctx, key, []byte("png"), "image/png")
+
+	deleted := DeleteInbound(ctx, store, []string{key, "https://example.com/sent.png", ""})
+	if deleted != 1 {
+		t.Errorf("deleted %d, want 1", deleted)
+	}
+	if _, _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stored media still there: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"gowa-broadcast/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("media not found")
+
+// Store keeps media bytes outside the database, messages only hold the key
+type Store interface {
+	// Put stores data under key, replacing any existing object
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+
+	// Get opens the object stored under key along with its content type
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+
+	// Delete removes the object stored under key, a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// inboundPrefix is where received attachments are stored
+const inboundPrefix = "inbound/"
+
+// extensions maps the mime types WhatsApp sends to a fixed file extension.
+// mime.ExtensionsByType depends on the system tables and can return several.
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"image/gif":       ".gif",
+	"video/mp4":       ".mp4",
+	"video/3gpp":      ".3gp",
+	"audio/ogg":       ".ogg",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/aac":       ".aac",
+	"audio/amr":       ".amr",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+	"text/csv":        ".csv",
+
+	"application/msword":            ".doc",
+	"application/vnd.ms-excel":      ".xls",
+	"application/vnd.ms-powerpoint": ".ppt",
+
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+}
+
+// InboundKey returns the key a received attachment is stored under. Types
+// without a known extension are stored without one, Get sniffs their type.
+func InboundKey(chat, messageID, mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	return inboundPrefix + chat + "/" + messageID + extensions[mimeType]
+}
+
+// DeleteInbound removes the stored attachments among keys. Media URLs of
+// sent messages are skipped, they aren't in the store.
+func DeleteInbound(ctx context.Context, store Store, keys []string) int {
+	deleted := 0
+	for _, key := range keys {
+		if !strings.HasPrefix(key, inboundPrefix) {
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			logrus.Warnf("Failed to delete stored media %s: %v", key, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted
+}
+
+// NewStore returns the store selected by MEDIA_STORAGE
+func NewStore(cfg config.MediaConfig) (Store, error) {
+	switch strings.ToLower(cfg.Storage) {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unknown media storage %q, use local or s3", cfg.Storage)
+	}
+}
+
+// cleanKey rejects keys that could escape the storage root
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != key {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return cleaned, nil
+}
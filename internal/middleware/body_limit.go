@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const originalBodyKey = "original_body"
+
+// MaxRequestBody rejects request bodies larger than limit bytes with 413. It
+// can be applied again on a nested group or route to raise or lower the limit
+// for those endpoints. A limit of zero or less disables the check.
+func MaxRequestBody(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			c.Abort()
+			return
+		}
+
+		// Wrap the original body so an inner limit replaces an outer one
+		// instead of being capped by it
+		body := c.Request.Body
+		if original, exists := c.Get(originalBodyKey); exists {
+			body = original.(io.ReadCloser)
+		} else {
+			c.Set(originalBodyKey, body)
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, body, limit)
+
+		c.Next()
+	}
+}
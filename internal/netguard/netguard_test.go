@@ -0,0 +1,64 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"100.128.0.1", true},
+		{"0.0.0.0", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublic(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("IsPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://8.8.8.8/callback", false},
+		{"http://127.0.0.1:8080/callback", true},
+		{"http://[::1]/callback", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://localhost/callback", true},
+		{"ftp://8.8.8.8/callback", true},
+	}
+
+	for _, tt := range tests {
+		if err := CheckURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("CheckURL(%s) = %v, want error %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestControlRejectsPrivateAddresses(t *testing.T) {
+	if err := control("tcp4", "127.0.0.1:80", nil); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("control(127.0.0.1) = %v, want ErrPrivateAddress", err)
+	}
+	if err := control("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("control(93.184.216.34) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package pdf writes simple text reports as PDF: flowing lines of Helvetica
+// text, horizontal rules and bar charts on A4 pages, which is all the
+// reports need and avoids pulling in a full PDF library.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size and margins in points
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 50.0
+)
+
+// Document is a PDF being written top to bottom
+type Document struct {
+	pages []*bytes.Buffer
+	y     float64 // Baseline of the next line on the current page
+}
+
+// New starts a document with one empty page
+func New() *Document {
+	d := &Document{}
+	d.newPage()
+	return d
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// reserve moves down by height, starting a new page when it doesn't fit
+func (d *Document) reserve(height float64) {
+	if d.y-height < margin {
+		d.newPage()
+	}
+	d.y -= height
+}
+
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Heading writes a bold line
+func (d *Document) Heading(size float64, text string) {
+	d.reserve(size * 1.6)
+	fmt.Fprintf(d.page(), "BT /F2 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, margin, d.y, escape(text))
+}
+
+// Text writes a line of regular text, long lines are wrapped
+func (d *Document) Text(size float64, text string) {
+	for _, line := range wrap(text, int((pageWidth-2*margin)/(size*0.5))) {
+		d.reserve(size * 1.4)
+		fmt.Fprintf(d.page(), "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, margin, d.y, escape(line))
+	}
+}
+
+// Row writes label and value in two columns
+func (d *Document) Row(size float64, label, value string) {
+	d.reserve(size * 1.4)
+	fmt.Fprintf(d.page(), "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, margin, d.y, escape(label))
+	fmt.Fprintf(d.page(), "BT /F2 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, margin+200, d.y, escape(value))
+}
+
+// Bar writes a label with a horizontal bar filled to fraction (0 to 1)
+func (d *Document) Bar(size float64, label string, fraction float64) {
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	const width = 250.0
+	d.reserve(size * 1.6)
+	x := margin + 200
+	fmt.Fprintf(d.page(), "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, margin, d.y, escape(label))
+	fmt.Fprintf(d.page(), "0.9 g %.2f %.2f %.2f %.2f re f\n", x, d.y-1, width, size)
+	if fraction > 0 {
+		fmt.Fprintf(d.page(), "0.2 0.5 0.8 rg %.2f %.2f %.2f %.2f re f\n", x, d.y-1, width*fraction, size)
+	}
+	fmt.Fprintf(d.page(), "0 g BT /F1 %.1f Tf %.2f %.2f Td (%.1f%%) Tj ET\n", size, x+width+8, d.y, fraction*100)
+}
+
+// Rule draws a horizontal line across the page with some space around it
+func (d *Document) Rule() {
+	d.reserve(12)
+	fmt.Fprintf(d.page(), "0.7 G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n", margin, d.y+6, pageWidth-margin, d.y+6)
+}
+
+// Space leaves height points empty
+func (d *Document) Space(height float64) {
+	d.reserve(height)
+}
+
+// WriteTo writes the finished PDF
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-4 are the catalog, page tree and fonts, then a page and its
+	// content stream per page
+	out.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// escape makes text safe inside a PDF string. The standard fonts only cover
+// Latin-1, anything else is replaced.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		case r >= 0x80:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// wrap splits text into lines of at most width characters at spaces
+func wrap(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,115 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "Broadcast report", want: "Broadcast report"},
+		{name: "parentheses and backslash", text: `a (b) \c`, want: `a \(b\) \\c`},
+		{name: "whitespace controls", text: "a\nb\rc\td", want: "a b c d"},
+		{name: "other controls", text: "a\x00b", want: "a?b"},
+		{name: "latin-1", text: "café", want: `caf\351`},
+		{name: "outside latin-1", text: "ok ✓ 日本", want: "ok ? ??"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escape(tt.text); got != tt.want {
+				t.Errorf("escape(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{name: "empty", text: "", width: 10, want: []string{""}},
+		{name: "fits", text: "one two", width: 10, want: []string{"one two"}},
+		{name: "exact width", text: "one two", width: 7, want: []string{"one two"}},
+		{name: "wraps at spaces", text: "one two three four", width: 9, want: []string{"one two", "three", "four"}},
+		{name: "collapses whitespace", text: "  one \n two  ", width: 20, want: []string{"one two"}},
+		{name: "long word stays whole", text: "a abcdefghijkl b", width: 5, want: []string{"a", "abcdefghijkl", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrap(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteToXrefOffsets(t *testing.T) {
+	d := New()
+	d.Heading(16, "Report (test)")
+	// Enough lines for a second page
+	for i := 0; i < 80; i++ {
+		d.Row(10, fmt.Sprintf("Row %d", i), "café")
+	}
+	d.Bar(10, "Delivered", 0.75)
+	d.Rule()
+
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := buf.Bytes()
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if match == nil {
+		t.Fatal("missing startxref trailer")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d doesn't point at the xref table", xref)
+	}
+
+	lines := strings.Split(string(out[xref:]), "\n")
+	var first, count int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &count); err != nil {
+		t.Fatalf("xref subsection header %q: %v", lines[1], err)
+	}
+	// Catalog, page tree, two fonts and a page plus content stream per page
+	if want := 4 + 2*len(d.pages) + 1; count != want {
+		t.Errorf("xref has %d entries, want %d", count, want)
+	}
+	if len(d.pages) < 2 {
+		t.Errorf("got %d pages, want the rows to overflow to a second page", len(d.pages))
+	}
+	if lines[2] != "0000000000 65535 f " {
+		t.Errorf("first xref entry = %q, want the free entry", lines[2])
+	}
+
+	for n := 1; n < count; n++ {
+		entry := lines[2+n]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry %d = %q, want 20 bytes with the newline", n, entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if header := fmt.Sprintf("%d 0 obj\n", n); !bytes.HasPrefix(out[offset:], []byte(header)) {
+			t.Errorf("object %d offset %d points at %q", n, offset, out[offset:offset+len(header)])
+		}
+	}
+
+	if !bytes.Contains(out, []byte(fmt.Sprintf("/Count %d", len(d.pages)))) {
+		t.Errorf("page tree doesn't count %d pages", len(d.pages))
+	}
+}
@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const pausedSettingKey = "scheduler_paused"
+
+// pauseState is persisted so a paused scheduler stays paused across restarts
+type pauseState struct {
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+}
+
+// Status describes the scheduler for operators
+type Status struct {
+	Enabled   bool                       `json:"enabled"`
+	Paused    bool                       `json:"paused"`
+	PausedAt  *time.Time                 `json:"paused_at,omitempty"`
+	LastRunAt *time.Time                 `json:"last_run_at,omitempty"` // Last poll for due messages
+	NextDue   *database.ScheduledMessage `json:"next_due,omitempty"`
+}
+
+// loadPauseState restores the paused flag saved by SetPaused
+func (s *Scheduler) loadPauseState() {
+	var setting database.Setting
+	if err := s.db.First(&setting, "key = ?", pausedSettingKey).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Errorf("Failed to load scheduler pause state: %v", err)
+		}
+		return
+	}
+
+	if err := json.Unmarshal([]byte(setting.Value), &s.pause); err != nil {
+		logrus.Errorf("Ignoring invalid scheduler pause state: %v", err)
+	}
+	if s.pause.Paused {
+		logrus.Warn("Scheduler is paused, scheduled messages won't be sent until it is resumed")
+	}
+}
+
+// Paused reports whether dispatching is currently halted
+func (s *Scheduler) Paused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pause.Paused
+}
+
+// SetPaused halts or resumes dispatching of all scheduled messages and
+// persists the choice. Pending messages keep their schedule, the ones that
+// became due while paused are sent on the first poll after resuming.
+func (s *Scheduler) SetPaused(paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := pauseState{Paused: paused}
+	if paused {
+		now := time.Now()
+		state.PausedAt = &now
+		if s.pause.Paused {
+			state.PausedAt = s.pause.PausedAt
+		}
+	}
+
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	setting := database.Setting{Key: pausedSettingKey, Value: string(value)}
+	if err := s.db.Save(&setting).Error; err != nil {
+		return err
+	}
+
+	s.pause = state
+	return nil
+}
+
+// Status returns the paused state, the last dispatching run and the next
+// pending message across all users
+func (s *Scheduler) Status() (*Status, error) {
+	s.mu.RLock()
+	status := &Status{
+		Enabled:  s.cfg.Scheduler.Enabled,
+		Paused:   s.pause.Paused,
+		PausedAt: s.pause.PausedAt,
+	}
+	if !s.lastRunAt.IsZero() {
+		lastRunAt := s.lastRunAt
+		status.LastRunAt = &lastRunAt
+	}
+	s.mu.RUnlock()
+
+	var next database.ScheduledMessage
+	err := s.db.Where("status = ?", "pending").Order("scheduled_at ASC").First(&next).Error
+	switch {
+	case err == nil:
+		status.NextDue = &next
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	return status, nil
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Policies for messages that became due while the scheduler wasn't running
+const (
+	MissedPolicyFire   = "fire"   // Send them right away, as if on time
+	MissedPolicyMissed = "missed" // Mark them missed, recurring ones skip to their next run
+)
+
+// pruneInterval is how often finished scheduled messages past the retention
+// are deleted
+const pruneInterval = 24 * time.Hour
+
+// recoverOverdue applies SCHEDULER_MISSED_POLICY to pending messages overdue
+// by more than the grace period, so downtime doesn't end in a flood of stale
+// sends. It also finalizes messages left "sending" by a crash, since whether
+// they reached anyone is unknown, resending could double send.
+func (s *Scheduler) recoverOverdue() {
+	var stuck []database.ScheduledMessage
+	if err := s.db.Where("status = ?", "sending").Find(&stuck).Error; err != nil {
+		logrus.Errorf("Failed to load interrupted scheduled messages: %v", err)
+	}
+	for i := range stuck {
+		logrus.Warnf("Scheduled message %d was interrupted while sending", stuck[i].ID)
+		s.markMissed(&stuck[i], "failed", "interrupted while sending")
+	}
+
+	// A paused scheduler holds messages on purpose, they are sent on resume
+	if s.cfg.Scheduler.MissedPolicy == MissedPolicyFire || s.Paused() {
+		return
+	}
+
+	grace := time.Duration(s.cfg.Scheduler.MissedGraceSec) * time.Second
+	var overdue []database.ScheduledMessage
+	if err := s.db.Where("status = ? AND scheduled_at < ?", "pending", time.Now().Add(-grace)).
+		Find(&overdue).Error; err != nil {
+		logrus.Errorf("Failed to load overdue scheduled messages: %v", err)
+		return
+	}
+	for i := range overdue {
+		logrus.Warnf("Scheduled message %d missed its time %s", overdue[i].ID, overdue[i].ScheduledAt.Format(time.RFC3339))
+		s.markMissed(&overdue[i], "missed", "scheduler was not running at the scheduled time")
+	}
+}
+
+// markMissed finalizes a message that won't be sent with status, or moves a
+// recurring one on to its next future run, and reports it as scheduled.missed
+func (s *Scheduler) markMissed(msg *database.ScheduledMessage, status, reason string) {
+	event := NewEvent(msg)
+	event.Error = reason
+
+	updates := map[string]interface{}{"status": status}
+	if msg.IsRecurring && msg.CronExpr != "" {
+		if next, err := s.nextRun(msg.CronExpr, time.Now()); err == nil {
+			updates["status"] = "pending"
+			updates["scheduled_at"] = next
+			event.NextRunAt = &next
+		}
+	}
+
+	// Only touch the row if nobody else changed it meanwhile
+	result := s.db.Model(&database.ScheduledMessage{}).
+		Where("id = ? AND status = ?", msg.ID, msg.Status).
+		Updates(updates)
+	if result.Error != nil {
+		logrus.Errorf("Failed to update scheduled message %d: %v", msg.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	s.notify("scheduled.missed", event)
+}
+
+// pruneFinished deletes one-off messages that finished longer ago than
+// SCHEDULER_RETENTION_DAYS. Pending and recurring messages are kept.
+func (s *Scheduler) pruneFinished() {
+	if s.cfg.Scheduler.RetentionDays <= 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -s.cfg.Scheduler.RetentionDays)
+	result := s.db.Where("is_recurring = ? AND status IN ? AND updated_at < ?",
+		false, []string{"sent", "failed", "cancelled", "missed"}, cutoff).
+		Delete(&database.ScheduledMessage{})
+	if result.Error != nil {
+		logrus.Errorf("Failed to prune scheduled messages: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		logrus.Infof("Pruned %d scheduled messages finished before %s", result.RowsAffected, cutoff.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,314 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gowa-broadcast/internal/config"
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/whatsapp"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// pollInterval is how often due scheduled messages are looked up
+const pollInterval = 15 * time.Second
+
+// Notifier is called for scheduled message lifecycle events
+type Notifier func(event string, data interface{})
+
+// Event is the webhook payload for scheduled.* events
+type Event struct {
+	ScheduledMessageID uint       `json:"scheduled_message_id"`
+	UserID             uint       `json:"user_id"`
+	Name               string     `json:"name"`
+	MessageType        string     `json:"message_type"`
+	RecipientCount     int        `json:"recipient_count"`
+	SentCount          int        `json:"sent_count"`
+	FailedCount        int        `json:"failed_count"`
+	ScheduledAt        time.Time  `json:"scheduled_at"`
+	NextRunAt          *time.Time `json:"next_run_at,omitempty"`
+	Error              string     `json:"error,omitempty"`
+}
+
+type Scheduler struct {
+	cfg      *config.Config
+	db       *gorm.DB
+	waClient *whatsapp.Client
+	notify   Notifier
+	location *time.Location
+	stop     chan struct{}
+
+	mu        sync.RWMutex
+	pause     pauseState
+	lastRunAt time.Time
+}
+
+func New(cfg *config.Config, db *gorm.DB, waClient *whatsapp.Client, notify Notifier) *Scheduler {
+	location, err := time.LoadLocation(cfg.Scheduler.Timezone)
+	if err != nil {
+		logrus.Warnf("Invalid SCHEDULER_TIMEZONE %q, using UTC: %v", cfg.Scheduler.Timezone, err)
+		location = time.UTC
+	}
+
+	if notify == nil {
+		notify = func(string, interface{}) {}
+	}
+
+	s := &Scheduler{
+		cfg:      cfg,
+		db:       db,
+		waClient: waClient,
+		notify:   notify,
+		location: location,
+		stop:     make(chan struct{}),
+	}
+	s.loadPauseState()
+	return s
+}
+
+// Start runs the scheduler loop in the background
+func (s *Scheduler) Start() {
+	go func() {
+		s.recoverOverdue()
+		s.pruneFinished()
+		lastPrune := time.Now()
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.runDue()
+			if time.Since(lastPrune) >= pruneInterval {
+				s.pruneFinished()
+				lastPrune = time.Now()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	logrus.Info("Scheduler started")
+}
+
+// Stop ends the scheduler loop
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+// NewEvent builds the webhook payload for a scheduled message
+func NewEvent(msg *database.ScheduledMessage) Event {
+	return Event{
+		ScheduledMessageID: msg.ID,
+		UserID:             msg.UserID,
+		Name:               msg.Name,
+		MessageType:        msg.MessageType,
+		RecipientCount:     len(recipientsOf(msg)),
+		ScheduledAt:        msg.ScheduledAt,
+	}
+}
+
+// runDue sends every pending message whose time has come
+func (s *Scheduler) runDue() {
+	if s.Paused() {
+		return
+	}
+	// Sending now would fail every recipient, leave them pending until we reconnect
+	if !s.waClient.IsReady() {
+		return
+	}
+
+	var due []database.ScheduledMessage
+	if err := s.db.Where("status = ? AND scheduled_at <= ?", "pending", time.Now()).
+		Order("scheduled_at ASC").Find(&due).Error; err != nil {
+		logrus.Errorf("Failed to load due scheduled messages: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.lastRunAt = time.Now()
+	s.mu.Unlock()
+
+	for i := range due {
+		// Claim the message so it is never sent twice
+		result := s.db.Model(&database.ScheduledMessage{}).
+			Where("id = ? AND status = ?", due[i].ID, "pending").
+			Update("status", "sending")
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+
+		s.execute(&due[i])
+	}
+}
+
+// execute sends a scheduled message to all of its recipients and records the outcome
+func (s *Scheduler) execute(msg *database.ScheduledMessage) {
+	recipients := recipientsOf(msg)
+	event := NewEvent(msg)
+
+	var media *whatsapp.UploadedMedia
+	var lastErr error
+	for _, recipient := range recipients {
+		var err error
+		switch msg.MessageType {
+		case "text":
+			_, err = s.waClient.SendTextMessage(recipient, msg.Content)
+		case "image", "document", "audio", "video":
+			if media == nil {
+				media, err = s.waClient.UploadMedia(msg.MediaURL, msg.MessageType)
+				if err != nil {
+					break
+				}
+			}
+			_, err = s.waClient.SendUploadedMedia(recipient, media, msg.Content, "")
+		default:
+			err = fmt.Errorf("unsupported message type: %s", msg.MessageType)
+		}
+
+		if err != nil {
+			logrus.Errorf("Failed to send scheduled message %d to %s: %v", msg.ID, recipient, err)
+			event.FailedCount++
+			lastErr = err
+			continue
+		}
+		event.SentCount++
+	}
+
+	status := "sent"
+	eventName := "scheduled.sent"
+	if event.SentCount == 0 {
+		status = "failed"
+		eventName = "scheduled.failed"
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no recipients")
+		}
+	}
+	if lastErr != nil {
+		event.Error = lastErr.Error()
+	}
+
+	updates := map[string]interface{}{"status": status}
+
+	// Recurring messages go back to pending for their next run
+	if msg.IsRecurring && msg.CronExpr != "" {
+		next, err := s.nextRun(msg.CronExpr, time.Now())
+		if err != nil {
+			logrus.Errorf("Invalid cron expression for scheduled message %d: %v", msg.ID, err)
+		} else {
+			updates["status"] = "pending"
+			updates["scheduled_at"] = next
+			event.NextRunAt = &next
+		}
+	}
+
+	if err := s.db.Model(&database.ScheduledMessage{}).Where("id = ?", msg.ID).Updates(updates).Error; err != nil {
+		logrus.Errorf("Failed to update scheduled message %d: %v", msg.ID, err)
+	}
+
+	logrus.Infof("Scheduled message %d %s. Sent: %d, Failed: %d", msg.ID, status, event.SentCount, event.FailedCount)
+	s.notify(eventName, event)
+}
+
+// ValidateCron checks that expr is a standard five field cron expression
+func ValidateCron(expr string) error {
+	_, err := cron.ParseStandard(expr)
+	return err
+}
+
+// nextRun returns the next time a cron expression fires after from, evaluated
+// in the scheduler timezone
+func (s *Scheduler) nextRun(expr string, from time.Time) (time.Time, error) {
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(from.In(s.location)), nil
+}
+
+func recipientsOf(msg *database.ScheduledMessage) []string {
+	var recipients []string
+	if err := json.Unmarshal([]byte(msg.Recipients), &recipients); err != nil {
+		logrus.Errorf("Failed to decode recipients of scheduled message %d: %v", msg.ID, err)
+	}
+	return recipients
+}
+
+// maxOccurrences caps how many runs of one recurring message Upcoming
+// expands, so a per-minute cron over a long window stays bounded
+const maxOccurrences = 500
+
+// Occurrence is one projected run of a scheduled message
+type Occurrence struct {
+	ScheduledMessageID uint      `json:"scheduled_message_id"`
+	Name               string    `json:"name"`
+	MessageType        string    `json:"message_type"`
+	RecipientCount     int       `json:"recipient_count"`
+	IsRecurring        bool      `json:"is_recurring"`
+	CronExpr           string    `json:"cron_expr,omitempty"`
+	RunAt              time.Time `json:"run_at"`
+}
+
+// Upcoming projects the user's pending scheduled messages into [from, to),
+// expanding recurring ones into each cron occurrence in the scheduler
+// timezone. Occurrences are sorted by run time.
+func (s *Scheduler) Upcoming(userID uint, from, to time.Time) ([]Occurrence, error) {
+	var messages []database.ScheduledMessage
+	if err := s.db.Where("user_id = ? AND status = ? AND scheduled_at < ?", userID, "pending", to).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]Occurrence, 0)
+	for i := range messages {
+		msg := &messages[i]
+		occurrence := Occurrence{
+			ScheduledMessageID: msg.ID,
+			Name:               msg.Name,
+			MessageType:        msg.MessageType,
+			RecipientCount:     len(recipientsOf(msg)),
+			IsRecurring:        msg.IsRecurring && msg.CronExpr != "",
+			CronExpr:           msg.CronExpr,
+		}
+
+		if !msg.ScheduledAt.Before(from) {
+			occurrence.RunAt = msg.ScheduledAt.In(s.location)
+			occurrences = append(occurrences, occurrence)
+		}
+		if !occurrence.IsRecurring {
+			continue
+		}
+
+		// Later runs follow the cron expression from the stored next run
+		schedule, err := cron.ParseStandard(msg.CronExpr)
+		if err != nil {
+			logrus.Warnf("Invalid cron expression for scheduled message %d: %v", msg.ID, err)
+			continue
+		}
+		next := msg.ScheduledAt.In(s.location)
+		if next.Before(from) {
+			next = from.In(s.location).Add(-time.Second)
+		}
+		for n := 0; n < maxOccurrences; n++ {
+			next = schedule.Next(next)
+			if next.IsZero() || !next.Before(to) {
+				break
+			}
+			occurrence.RunAt = next
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].RunAt.Before(occurrences[j].RunAt)
+	})
+	return occurrences, nil
+}
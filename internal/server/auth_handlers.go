@@ -1,15 +1,33 @@
 package server
 
 import (
+	"encoding/csv"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"gowa-broadcast/internal/auth"
 	"gowa-broadcast/internal/middleware"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
+// maxUserImportRows caps how many users a single CSV import may create
+const maxUserImportRows = 1000
+
+// UserImportResult reports the outcome of importing one CSV row
+type UserImportResult struct {
+	Row      int          `json:"row"`
+	Username string       `json:"username"`
+	Success  bool         `json:"success"`
+	UserID   uint         `json:"user_id,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Fields   []FieldError `json:"fields,omitempty"`
+}
+
 // AuthHandlers contains all authentication related handlers
 type AuthHandlers struct {
 	authService *auth.AuthService
@@ -26,7 +44,7 @@ func NewAuthHandlers(authService *auth.AuthService) *AuthHandlers {
 func (h *AuthHandlers) Login(c *gin.Context) {
 	var req auth.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -43,7 +61,7 @@ func (h *AuthHandlers) Login(c *gin.Context) {
 func (h *AuthHandlers) CreateUser(c *gin.Context) {
 	var req auth.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -68,6 +86,95 @@ func (h *AuthHandlers) CreateUser(c *gin.Context) {
 	})
 }
 
+// ImportUsers handles bulk user creation from a CSV file with the columns
+// username,email,full_name,role,password (admin only)
+func (h *AuthHandlers) ImportUsers(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	results := make([]UserImportResult, 0)
+	created := 0
+	row := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid CSV at row %d: %v", row, err)})
+			return
+		}
+
+		// Skip the header row if present
+		if row == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "username") {
+			continue
+		}
+
+		if len(results) >= maxUserImportRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many rows. Maximum allowed: %d", maxUserImportRows)})
+			return
+		}
+
+		result := UserImportResult{Row: row}
+		if len(record) != 5 {
+			result.Error = "expected 5 columns: username,email,full_name,role,password"
+			results = append(results, result)
+			continue
+		}
+
+		req := auth.CreateUserRequest{
+			Username: strings.TrimSpace(record[0]),
+			Email:    strings.TrimSpace(record[1]),
+			FullName: strings.TrimSpace(record[2]),
+			Role:     strings.TrimSpace(record[3]),
+			Password: record[4],
+		}
+		result.Username = req.Username
+
+		// Apply the same validation rules as the JSON endpoint
+		if err := binding.Validator.ValidateStruct(&req); err != nil {
+			result.Error = "Validation failed"
+			result.Fields = validationErrors(err)
+			results = append(results, result)
+			continue
+		}
+
+		user, err := h.authService.CreateUser(req)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Success = true
+		result.UserID = user.ID
+		created++
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%d of %d users imported", created, len(results)),
+		"created": created,
+		"failed":  len(results) - created,
+		"results": results,
+	})
+}
+
 // GetUsers handles getting list of users (admin only)
 func (h *AuthHandlers) GetUsers(c *gin.Context) {
 	users, err := h.authService.GetUsers()
@@ -108,7 +215,7 @@ func (h *AuthHandlers) UpdateUser(c *gin.Context) {
 
 	var req auth.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -142,8 +249,9 @@ func (h *AuthHandlers) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	// The username must be repeated in ?confirm= to delete the account
 	currentUserID, _ := middleware.GetCurrentUserID(c)
-	err = h.authService.DeleteUser(uint(userID), currentUserID)
+	err = h.authService.DeleteUser(uint(userID), currentUserID, c.Query("confirm"), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -152,6 +260,50 @@ func (h *AuthHandlers) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
+// DeactivateUser handles deactivating a user without deleting it (admin only)
+func (h *AuthHandlers) DeactivateUser(c *gin.Context) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	currentUserID, _ := middleware.GetCurrentUserID(c)
+	user, err := h.authService.DeactivateUser(uint(userID), currentUserID, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deactivated successfully",
+		"user":    user,
+	})
+}
+
+// ReactivateUser handles reactivating a deactivated user (admin only)
+func (h *AuthHandlers) ReactivateUser(c *gin.Context) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	currentUserID, _ := middleware.GetCurrentUserID(c)
+	user, err := h.authService.ReactivateUser(uint(userID), currentUserID, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User reactivated successfully",
+		"user":    user,
+	})
+}
+
 // ChangePassword handles password change
 func (h *AuthHandlers) ChangePassword(c *gin.Context) {
 	userIDStr := c.Param("id")
@@ -163,7 +315,7 @@ func (h *AuthHandlers) ChangePassword(c *gin.Context) {
 
 	var req auth.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -177,6 +329,51 @@ func (h *AuthHandlers) ChangePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
 }
 
+// GetUserLimits handles getting a user's broadcast limits (admin only)
+func (h *AuthHandlers) GetUserLimits(c *gin.Context) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limits, err := h.authService.GetUserLimits(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"limits": limits})
+}
+
+// SetUserLimits handles updating a user's broadcast limits (admin only)
+func (h *AuthHandlers) SetUserLimits(c *gin.Context) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req auth.UserLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	limits, err := h.authService.SetUserLimits(uint(userID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User limits updated successfully",
+		"limits":  limits,
+	})
+}
+
 // GetProfile handles getting current user profile
 func (h *AuthHandlers) GetProfile(c *gin.Context) {
 	currentUserID, _ := middleware.GetCurrentUserID(c)
@@ -193,7 +390,7 @@ func (h *AuthHandlers) GetProfile(c *gin.Context) {
 func (h *AuthHandlers) UpdateProfile(c *gin.Context) {
 	var req auth.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		respondBindError(c, err)
 		return
 	}
 
@@ -222,7 +419,7 @@ func (h *AuthHandlers) UpdateProfile(c *gin.Context) {
 func (h *AuthHandlers) ChangeMyPassword(c *gin.Context) {
 	var req auth.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		respondBindError(c, err)
 		return
 	}
 
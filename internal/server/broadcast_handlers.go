@@ -1,21 +1,31 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"gowa-broadcast/internal/broadcast"
 	"gowa-broadcast/internal/database"
 	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/pdf"
+	"gowa-broadcast/internal/scheduler"
+	"gowa-broadcast/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type CreateBroadcastListRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	CreatedBy   string `json:"created_by" binding:"required"`
+	SourceType  string `json:"source_type" binding:"omitempty,oneof=static group"`
+	GroupJID    string `json:"group_jid" binding:"required_if=SourceType group"`
 }
 
 type UpdateBroadcastListRequest struct {
@@ -34,6 +44,16 @@ type RecipientRequest struct {
 	PhoneNumber string `json:"phone_number"`
 }
 
+// RecipientStatus is a list recipient together with its delivery outcome for
+// one broadcast
+type RecipientStatus struct {
+	database.BroadcastRecipient
+	DeliveryStatus string     `json:"delivery_status,omitempty"` // pending, sent, failed
+	MessageID      string     `json:"message_id,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+}
+
 type CreateScheduledMessageRequest struct {
 	Name        string   `json:"name" binding:"required"`
 	Recipients  []string `json:"recipients" binding:"required"`
@@ -98,7 +118,7 @@ func (s *Server) handleCreateBroadcastList(c *gin.Context) {
 
 	var req CreateBroadcastListRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
@@ -108,6 +128,32 @@ func (s *Server) handleCreateBroadcastList(c *gin.Context) {
 		Description: req.Description,
 		CreatedBy:   req.CreatedBy,
 		IsActive:    true,
+		SourceType:  broadcast.SourceStatic,
+	}
+
+	// Group lists are checked against WhatsApp up front so a typo or a group
+	// this account isn't in fails now rather than at broadcast time
+	if req.SourceType == broadcast.SourceGroup {
+		metadata, err := s.waClient.GetGroupMetadata(req.GroupJID)
+		switch {
+		case errors.Is(err, whatsapp.ErrClientNotReady):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, whatsapp.ErrInvalidJID):
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, whatsapp.ErrNotInGroup):
+			c.JSON(403, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, whatsapp.ErrGroupNotFound):
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		broadcastList.SourceType = broadcast.SourceGroup
+		broadcastList.SourceGroupJID = metadata.JID
 	}
 
 	if err := s.db.Create(broadcastList).Error; err != nil {
@@ -160,7 +206,7 @@ func (s *Server) handleUpdateBroadcastList(c *gin.Context) {
 
 	var req UpdateBroadcastListRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
@@ -214,6 +260,17 @@ func (s *Server) handleDeleteBroadcastList(c *gin.Context) {
 		return
 	}
 
+	// In-flight broadcasts still reference the list
+	active, err := s.broadcastMgr.HasActiveBroadcast(uint(id))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to check broadcasts using the list"})
+		return
+	}
+	if active {
+		c.JSON(409, gin.H{"error": "Broadcast list has an active broadcast, wait for it to finish or cancel it first"})
+		return
+	}
+
 	// Delete recipients first
 	s.db.Where("broadcast_list_id = ?", uint(id)).Delete(&database.BroadcastRecipient{})
 
@@ -226,6 +283,174 @@ func (s *Server) handleDeleteBroadcastList(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Broadcast list deleted successfully"})
 }
 
+func (s *Server) handleGetBroadcastListRecipients(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid broadcast list ID"})
+		return
+	}
+
+	var broadcastList database.BroadcastList
+	if err := s.db.Where("user_id = ?", userID).First(&broadcastList, uint(id)).Error; err != nil {
+		c.JSON(404, gin.H{"error": "Broadcast list not found"})
+		return
+	}
+
+	// Optionally join the delivery outcome of one of the list's broadcasts
+	var broadcastMsg *database.BroadcastMessage
+	if broadcastID := c.Query("broadcast_id"); broadcastID != "" {
+		bid, err := strconv.ParseUint(broadcastID, 10, 32)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid broadcast ID"})
+			return
+		}
+		broadcastMsg = &database.BroadcastMessage{}
+		if err := s.db.Where("user_id = ? AND broadcast_list_id = ?", userID, broadcastList.ID).First(broadcastMsg, uint(bid)).Error; err != nil {
+			c.JSON(404, gin.H{"error": "Broadcast not found"})
+			return
+		}
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset := (page - 1) * limit
+
+	query := s.db.Model(&database.BroadcastRecipient{}).Where("broadcast_list_id = ?", broadcastList.ID)
+
+	// Search
+	if search := c.Query("search"); search != "" {
+		query = query.Where("jid LIKE ? OR name LIKE ? OR phone_number LIKE ?", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var recipients []database.BroadcastRecipient
+	query.Order("id ASC").Offset(offset).Limit(limit).Find(&recipients)
+
+	result := make([]RecipientStatus, 0, len(recipients))
+	for _, recipient := range recipients {
+		result = append(result, RecipientStatus{BroadcastRecipient: recipient})
+	}
+
+	if broadcastMsg != nil && len(recipients) > 0 {
+		jids := make([]string, 0, len(recipients))
+		for _, recipient := range recipients {
+			jids = append(jids, recipient.JID)
+		}
+
+		var deliveries []database.BroadcastDelivery
+		s.db.Where("broadcast_message_id = ? AND jid IN ?", broadcastMsg.ID, jids).Order("id ASC").Find(&deliveries)
+
+		// Later rows win so a resend replaces the earlier outcome
+		byJID := make(map[string]database.BroadcastDelivery, len(deliveries))
+		for _, delivery := range deliveries {
+			byJID[delivery.JID] = delivery
+		}
+
+		for i := range result {
+			delivery, ok := byJID[result[i].JID]
+			if !ok {
+				result[i].DeliveryStatus = "pending"
+				continue
+			}
+			result[i].DeliveryStatus = delivery.Status
+			result[i].MessageID = delivery.MessageID
+			result[i].Error = delivery.Error
+			result[i].SentAt = delivery.SentAt
+		}
+	}
+
+	response := gin.H{
+		"broadcast_list_id": broadcastList.ID,
+		"recipients":        result,
+		"total":             total,
+		"page":              page,
+		"limit":             limit,
+	}
+	if broadcastMsg != nil {
+		response["broadcast_id"] = broadcastMsg.ID
+	}
+
+	c.JSON(200, response)
+}
+
+// handlePreviewRecipients shows who a broadcast to the list would reach and
+// which entries would be skipped, without sending anything
+func (s *Server) handlePreviewRecipients(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid broadcast list ID"})
+		return
+	}
+
+	preview, err := s.broadcastMgr.PreviewRecipients(userID, uint(id))
+	switch {
+	case errors.Is(err, broadcast.ErrListNotFound):
+		c.JSON(404, gin.H{"error": "Broadcast list not found"})
+	case errors.Is(err, whatsapp.ErrClientNotReady):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrNotInGroup), errors.Is(err, whatsapp.ErrGroupNotFound):
+		c.JSON(422, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, preview)
+	}
+}
+
+// handleCheckRecipient explains whether a broadcast to the list would reach
+// the JID given as ?jid=, and if not, why
+func (s *Server) handleCheckRecipient(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid broadcast list ID"})
+		return
+	}
+
+	jid := c.Query("jid")
+	if jid == "" {
+		c.JSON(400, gin.H{"error": "jid is required"})
+		return
+	}
+
+	check, err := s.broadcastMgr.CheckRecipient(userID, uint(id), jid)
+	switch {
+	case errors.Is(err, broadcast.ErrListNotFound):
+		c.JSON(404, gin.H{"error": "Broadcast list not found"})
+	case errors.Is(err, whatsapp.ErrInvalidJID):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrClientNotReady):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrNotInGroup), errors.Is(err, whatsapp.ErrGroupNotFound):
+		c.JSON(422, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, check)
+	}
+}
+
 func (s *Server) handleAddRecipients(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -235,7 +460,7 @@ func (s *Server) handleAddRecipients(c *gin.Context) {
 
 	var req AddRecipientsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
@@ -245,6 +470,10 @@ func (s *Server) handleAddRecipients(c *gin.Context) {
 		c.JSON(404, gin.H{"error": "Broadcast list not found"})
 		return
 	}
+	if broadcastList.SourceType == broadcast.SourceGroup {
+		c.JSON(409, gin.H{"error": "Recipients of a group list come from the group's members"})
+		return
+	}
 
 	// Add recipients
 	var recipients []database.BroadcastRecipient
@@ -293,13 +522,34 @@ func (s *Server) handleRemoveRecipient(c *gin.Context) {
 
 // Broadcast Handlers
 func (s *Server) handleCreateBroadcast(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
 	var req broadcast.BroadcastRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
 		return
 	}
+	req.UserID = userID
 
 	resp, err := s.broadcastMgr.CreateBroadcast(&req)
+	if errors.Is(err, broadcast.ErrNotConnected) || errors.Is(err, whatsapp.ErrClientNotReady) {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	if errors.Is(err, whatsapp.ErrNotInGroup) || errors.Is(err, whatsapp.ErrGroupNotFound) || errors.Is(err, broadcast.ErrDeviceUnavailable) ||
+		errors.Is(err, broadcast.ErrCallbackNotAllowed) {
+		c.JSON(422, resp)
+		return
+	}
+	if errors.Is(err, broadcast.ErrDuplicateBroadcast) {
+		c.JSON(409, resp)
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -343,9 +593,151 @@ func (s *Server) handleCancelBroadcast(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Broadcast cancelled successfully"})
 }
 
+// handleConfirmBroadcast starts a broadcast that was held for confirmation
+func (s *Server) handleConfirmBroadcast(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid broadcast ID"})
+		return
+	}
+
+	resp, err := s.broadcastMgr.ConfirmBroadcast(userID, uint(id))
+	switch {
+	case errors.Is(err, broadcast.ErrBroadcastNotFound):
+		c.JSON(404, gin.H{"error": "Broadcast not found"})
+	case errors.Is(err, broadcast.ErrNotAwaitingConfirmation):
+		c.JSON(409, gin.H{"error": err.Error()})
+	case errors.Is(err, broadcast.ErrNotConnected):
+		c.JSON(http.StatusServiceUnavailable, resp)
+	case errors.Is(err, broadcast.ErrDeviceUnavailable):
+		c.JSON(422, resp)
+	case err != nil && resp != nil:
+		c.JSON(400, resp)
+	case err != nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, resp)
+	}
+}
+
+// handleResendDelivery retries one failed recipient of a finished broadcast
+func (s *Server) handleResendDelivery(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid broadcast ID"})
+		return
+	}
+
+	delivery, err := s.broadcastMgr.ResendDelivery(userID, uint(id), c.Param("jid"))
+	switch {
+	case errors.Is(err, broadcast.ErrBroadcastNotFound):
+		c.JSON(404, gin.H{"error": "Broadcast not found"})
+	case errors.Is(err, broadcast.ErrDeliveryNotFound):
+		c.JSON(404, gin.H{"error": "Delivery not found"})
+	case errors.Is(err, broadcast.ErrAlreadyDelivered):
+		c.JSON(409, gin.H{"error": "Delivery already succeeded", "delivery": delivery})
+	case errors.Is(err, broadcast.ErrBroadcastRunning):
+		c.JSON(409, gin.H{"error": "Broadcast is still running"})
+	case errors.Is(err, broadcast.ErrResendInProgress):
+		c.JSON(409, gin.H{"error": "Delivery is already being resent"})
+	case errors.Is(err, broadcast.ErrNotConnected):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrRecipientNotAllowed):
+		c.JSON(403, gin.H{"error": err.Error(), "delivery": delivery})
+	case errors.Is(err, broadcast.ErrDeviceUnavailable):
+		c.JSON(422, gin.H{"error": err.Error(), "delivery": delivery})
+	case err != nil && delivery == nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(502, gin.H{"error": err.Error(), "delivery": delivery})
+	default:
+		c.JSON(200, gin.H{
+			"message":  "Delivery resent successfully",
+			"delivery": delivery,
+		})
+	}
+}
+
+// handleDeactivateStaleContacts switches off list recipients whose repeated
+// failures turn out to be numbers without a WhatsApp account
+func (s *Server) handleDeactivateStaleContacts(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	threshold := 0
+	if t := c.Query("threshold"); t != "" {
+		parsed, err := strconv.Atoi(t)
+		if err != nil || parsed <= 0 {
+			c.JSON(400, gin.H{"error": "Invalid threshold"})
+			return
+		}
+		threshold = parsed
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	report, err := s.broadcastMgr.DeactivateStaleContacts(userID, threshold, dryRun)
+	switch {
+	case errors.Is(err, broadcast.ErrNotConnected):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to deactivate stale contacts"})
+		return
+	}
+
+	if !dryRun && report.Deactivated > 0 {
+		details, _ := json.Marshal(report)
+		audit := &database.AuditLog{
+			ActorID:    userID,
+			Action:     "broadcast_list.deactivate_stale",
+			TargetType: "broadcast_list",
+			Details:    string(details),
+			IPAddress:  c.ClientIP(),
+		}
+		if err := s.db.Create(audit).Error; err != nil {
+			logrus.Errorf("Failed to record audit entry broadcast_list.deactivate_stale: %v", err)
+		}
+	}
+
+	c.JSON(200, report)
+}
+
 func (s *Server) handleGetActiveBroadcasts(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
+
 	activeBroadcasts := s.broadcastMgr.ListActiveBroadcasts()
-	c.JSON(200, gin.H{"active_broadcasts": activeBroadcasts})
+	total := len(activeBroadcasts)
+
+	// Count per status so waiting broadcasts are visible even when cut off by the limit
+	byStatus := make(map[string]int)
+	for _, status := range activeBroadcasts {
+		byStatus[status.Status]++
+	}
+
+	if limit > 0 && len(activeBroadcasts) > limit {
+		activeBroadcasts = activeBroadcasts[:limit]
+	}
+
+	c.JSON(200, gin.H{
+		"active_broadcasts": activeBroadcasts,
+		"total":             total,
+		"by_status":         byStatus,
+	})
 }
 
 func (s *Server) handleGetBroadcastHistory(c *gin.Context) {
@@ -367,6 +759,12 @@ func (s *Server) handleGetBroadcastHistory(c *gin.Context) {
 		query = query.Where("broadcast_list_id = ?", listID)
 	}
 
+	// Search campaign name and notes
+	if search := c.Query("search"); search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("name LIKE ? OR notes LIKE ?", pattern, pattern)
+	}
+
 	var total int64
 	query.Count(&total)
 	query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&broadcasts)
@@ -379,6 +777,277 @@ func (s *Server) handleGetBroadcastHistory(c *gin.Context) {
 	})
 }
 
+// handleGetBroadcastPacing suggests a delay and rate limit for a broadcast to
+// ?recipients=N and the time it would take
+func (s *Server) handleGetBroadcastPacing(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	recipients, err := strconv.Atoi(c.Query("recipients"))
+	if err != nil || recipients < 1 {
+		c.JSON(400, gin.H{"error": "recipients must be a positive number"})
+		return
+	}
+
+	pacing, err := s.broadcastMgr.SuggestPacing(userID, recipients)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to compute pacing"})
+		return
+	}
+
+	c.JSON(200, pacing)
+}
+
+// handleGetBroadcastReport downloads a broadcast's delivery report as a PDF
+// for sharing with campaign stakeholders
+func (s *Server) handleGetBroadcastReport(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid broadcast ID"})
+		return
+	}
+
+	report, err := s.broadcastMgr.BuildReport(userID, uint(id))
+	switch {
+	case errors.Is(err, broadcast.ErrBroadcastNotFound):
+		c.JSON(404, gin.H{"error": "Broadcast not found"})
+		return
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to build report"})
+		return
+	}
+
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="broadcast-%d-report.pdf"`, id))
+	c.Status(200)
+	if _, err := renderBroadcastReport(report).WriteTo(c.Writer); err != nil {
+		logrus.Errorf("Failed to write report of broadcast %d: %v", id, err)
+	}
+}
+
+// renderBroadcastReport lays out a broadcast report as a PDF document
+func renderBroadcastReport(report *broadcast.Report) *pdf.Document {
+	msg := report.Broadcast
+	doc := pdf.New()
+
+	doc.Heading(18, "Broadcast Report")
+	doc.Text(10, fmt.Sprintf("Broadcast #%d, generated %s", msg.ID, report.GeneratedAt.Format("2006-01-02 15:04 MST")))
+	doc.Rule()
+
+	doc.Heading(13, "Summary")
+	doc.Row(10, "Message type", msg.MessageType)
+	doc.Row(10, "Status", msg.Status)
+	doc.Row(10, "Created", msg.CreatedAt.Format("2006-01-02 15:04"))
+	if msg.CompletedAt != nil {
+		doc.Row(10, "Completed", msg.CompletedAt.Format("2006-01-02 15:04"))
+	}
+	if msg.Content != "" {
+		doc.Text(10, "Message: "+msg.Content)
+	}
+	doc.Space(6)
+
+	doc.Heading(13, "Totals")
+	doc.Row(10, "Recipients", strconv.Itoa(msg.TotalRecipients))
+	doc.Row(10, "Sent", strconv.Itoa(msg.SentCount))
+	doc.Row(10, "Failed", strconv.Itoa(msg.FailedCount))
+	doc.Row(10, "Delivered", strconv.Itoa(msg.DeliveredCount))
+	doc.Row(10, "Read", strconv.Itoa(msg.ReadCount))
+	doc.Space(6)
+
+	doc.Heading(13, "Rates")
+	doc.Bar(10, "Delivery rate", report.DeliveryRate)
+	doc.Bar(10, "Read rate", report.ReadRate)
+	doc.Bar(10, "Failure rate", report.FailureRate)
+	doc.Space(6)
+
+	doc.Heading(13, "Timeline (per hour)")
+	if len(report.Timeline) == 0 {
+		doc.Text(10, "No messages were sent.")
+	}
+	var busiest int64
+	for _, point := range report.Timeline {
+		if point.Total > busiest {
+			busiest = point.Total
+		}
+	}
+	for _, point := range report.Timeline {
+		label := fmt.Sprintf("%s  %d sent, %d read", point.Hour.Format("01-02 15:00"), point.Sent, point.Read)
+		doc.Bar(9, label, float64(point.Total)/float64(busiest))
+	}
+	doc.Space(6)
+
+	doc.Heading(13, "Top failure reasons")
+	if len(report.FailureReasons) == 0 {
+		doc.Text(10, "No failed deliveries.")
+	}
+	for _, reason := range report.FailureReasons {
+		text := reason.Error
+		if text == "" {
+			text = "Unknown error"
+		}
+		doc.Text(10, fmt.Sprintf("%d x %s", reason.Count, text))
+	}
+
+	return doc
+}
+
+func (s *Server) handleGetBroadcastReplies(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid broadcast ID"})
+		return
+	}
+
+	var broadcastMsg database.BroadcastMessage
+	if err := s.db.Where("user_id = ?", userID).First(&broadcastMsg, uint(id)).Error; err != nil {
+		c.JSON(404, gin.H{"error": "Broadcast not found"})
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset := (page - 1) * limit
+
+	// A broadcast that never started can't have replies yet
+	if broadcastMsg.StartedAt == nil {
+		c.JSON(200, gin.H{
+			"replies": []database.Message{},
+			"total":   0,
+			"page":    page,
+			"limit":   limit,
+		})
+		return
+	}
+
+	var recipients []database.BroadcastRecipient
+	s.db.Where("broadcast_list_id = ?", broadcastMsg.BroadcastListID).Find(&recipients)
+
+	jids := make([]string, 0, len(recipients))
+	for _, recipient := range recipients {
+		jids = append(jids, normalizeRecipientJID(recipient.JID))
+	}
+
+	var replies []database.Message
+	query := s.db.Model(&database.Message{}).
+		Where("from_jid IN ? AND is_from_me = ? AND timestamp >= ?", jids, false, *broadcastMsg.StartedAt)
+
+	// Search
+	if search := c.Query("search"); search != "" {
+		query = query.Where("content LIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+	query.Order("timestamp ASC").Offset(offset).Limit(limit).Find(&replies)
+
+	c.JSON(200, gin.H{
+		"broadcast_id": broadcastMsg.ID,
+		"replies":      replies,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// normalizeRecipientJID turns a bare phone number into a user JID so it can
+// be matched against stored messages
+func normalizeRecipientJID(recipient string) string {
+	if strings.Contains(recipient, "@") {
+		return recipient
+	}
+
+	phoneNumber := strings.ReplaceAll(recipient, "+", "")
+	phoneNumber = strings.ReplaceAll(phoneNumber, " ", "")
+	phoneNumber = strings.ReplaceAll(phoneNumber, "-", "")
+	return phoneNumber + "@s.whatsapp.net"
+}
+
+// validateScheduleTime keeps scheduled times between the minimum lead time and
+// the maximum horizon, so nothing fires effectively immediately or years out
+func (s *Server) validateScheduleTime(scheduledAt time.Time) error {
+	now := time.Now()
+
+	minLead := time.Duration(s.cfg.Scheduler.MinLeadSec) * time.Second
+	if scheduledAt.Before(now.Add(minLead)) {
+		if minLead <= 0 {
+			return fmt.Errorf("scheduled time must be in the future")
+		}
+		return fmt.Errorf("scheduled time must be at least %s in the future", minLead)
+	}
+
+	if days := s.cfg.Scheduler.MaxHorizonDays; days > 0 && scheduledAt.After(now.AddDate(0, 0, days)) {
+		return fmt.Errorf("scheduled time must be within %d days", days)
+	}
+
+	return nil
+}
+
+// newScheduledMessage validates a scheduled message request and builds the
+// pending message for it: the time must parse and lie within the allowed
+// window, a cron expression must be valid and recipients are normalized to
+// JIDs. It's shared by the JSON endpoint and the CSV import.
+func (s *Server) newScheduledMessage(userID uint, req *CreateScheduledMessageRequest) (*database.ScheduledMessage, error) {
+	scheduledAt, err := time.Parse(time.RFC3339, req.ScheduledAt)
+	if err != nil {
+		return nil, errors.New("Invalid scheduled_at format. Use RFC3339 format")
+	}
+	if err := s.validateScheduleTime(scheduledAt); err != nil {
+		return nil, err
+	}
+
+	if req.CronExpr != "" {
+		if err := scheduler.ValidateCron(req.CronExpr); err != nil {
+			return nil, fmt.Errorf("invalid cron_expr: %v", err)
+		}
+	}
+
+	recipients := make([]string, 0, len(req.Recipients))
+	for _, recipient := range req.Recipients {
+		jid, err := s.waClient.NormalizeJID(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %v", recipient, err)
+		}
+		recipients = append(recipients, jid)
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+	recipientsJSON, err := json.Marshal(recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	return &database.ScheduledMessage{
+		UserID:      userID,
+		Name:        req.Name,
+		Recipients:  string(recipientsJSON),
+		MessageType: req.MessageType,
+		Content:     req.Content,
+		MediaURL:    req.MediaURL,
+		ScheduledAt: scheduledAt,
+		Status:      "pending",
+		CronExpr:    req.CronExpr,
+		IsRecurring: req.IsRecurring,
+	}, nil
+}
+
 // Scheduled Message Handlers
 func (s *Server) handleGetScheduledMessages(c *gin.Context) {
 	// Get current user ID
@@ -413,50 +1082,77 @@ func (s *Server) handleGetScheduledMessages(c *gin.Context) {
 	})
 }
 
-func (s *Server) handleCreateScheduledMessage(c *gin.Context) {
-	// Get current user ID
+// maxUpcomingWindow bounds the window handleGetUpcomingScheduled expands
+const maxUpcomingWindow = 92 * 24 * time.Hour
+
+// handleGetUpcomingScheduled lists the runs of pending and recurring scheduled
+// messages between from and to (RFC3339, defaulting to the next 7 days)
+func (s *Server) handleGetUpcomingScheduled(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
-	var req CreateScheduledMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	from := time.Now()
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid from format. Use RFC3339 format"})
+			return
+		}
+		from = parsed
+	}
+	to := from.Add(7 * 24 * time.Hour)
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid to format. Use RFC3339 format"})
+			return
+		}
+		to = parsed
+	}
+	if !from.Before(to) {
+		c.JSON(400, gin.H{"error": "from must be before to"})
+		return
+	}
+	if to.Sub(from) > maxUpcomingWindow {
+		c.JSON(400, gin.H{"error": "Window can't be longer than 92 days"})
 		return
 	}
 
-	// Parse scheduled time
-	scheduledAt, err := time.Parse(time.RFC3339, req.ScheduledAt)
+	occurrences, err := s.scheduler.Upcoming(userID, from, to)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid scheduled_at format. Use RFC3339 format"})
+		c.JSON(500, gin.H{"error": "Failed to get upcoming scheduled messages"})
 		return
 	}
 
-	// Check if scheduled time is in the future
-	if scheduledAt.Before(time.Now()) {
-		c.JSON(400, gin.H{"error": "Scheduled time must be in the future"})
+	c.JSON(200, gin.H{
+		"occurrences": occurrences,
+		"total":       len(occurrences),
+		"from":        from,
+		"to":          to,
+	})
+}
+
+func (s *Server) handleCreateScheduledMessage(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
-	// Convert recipients to JSON
-	recipientsJSON, err := json.Marshal(req.Recipients)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to process recipients"})
+	var req CreateScheduledMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
-	scheduledMsg := &database.ScheduledMessage{
-		Name:        req.Name,
-		Recipients:  string(recipientsJSON),
-		MessageType: req.MessageType,
-		Content:     req.Content,
-		MediaURL:    req.MediaURL,
-		ScheduledAt: scheduledAt,
-		Status:      "pending",
-		CronExpr:    req.CronExpr,
-		IsRecurring: req.IsRecurring,
+	scheduledMsg, err := s.newScheduledMessage(userID, &req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
 	}
 
 	if err := s.db.Create(scheduledMsg).Error; err != nil {
@@ -464,6 +1160,8 @@ func (s *Server) handleCreateScheduledMessage(c *gin.Context) {
 		return
 	}
 
+	s.SendWebhook("scheduled.created", scheduler.NewEvent(scheduledMsg))
+
 	c.JSON(201, gin.H{
 		"message":           "Scheduled message created successfully",
 		"scheduled_message": scheduledMsg,
@@ -495,7 +1193,7 @@ func (s *Server) handleUpdateScheduledMessage(c *gin.Context) {
 
 	var req CreateScheduledMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
@@ -511,29 +1209,22 @@ func (s *Server) handleUpdateScheduledMessage(c *gin.Context) {
 		return
 	}
 
-	// Parse scheduled time
-	scheduledAt, err := time.Parse(time.RFC3339, req.ScheduledAt)
-	if err != nil {
-		c.JSON(400, gin.H{"error": "Invalid scheduled_at format. Use RFC3339 format"})
-		return
-	}
-
-	// Convert recipients to JSON
-	recipientsJSON, err := json.Marshal(req.Recipients)
+	// Validated exactly like a new scheduled message
+	updated, err := s.newScheduledMessage(scheduledMsg.UserID, &req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to process recipients"})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Update fields
-	scheduledMsg.Name = req.Name
-	scheduledMsg.Recipients = string(recipientsJSON)
-	scheduledMsg.MessageType = req.MessageType
-	scheduledMsg.Content = req.Content
-	scheduledMsg.MediaURL = req.MediaURL
-	scheduledMsg.ScheduledAt = scheduledAt
-	scheduledMsg.CronExpr = req.CronExpr
-	scheduledMsg.IsRecurring = req.IsRecurring
+	scheduledMsg.Name = updated.Name
+	scheduledMsg.Recipients = updated.Recipients
+	scheduledMsg.MessageType = updated.MessageType
+	scheduledMsg.Content = updated.Content
+	scheduledMsg.MediaURL = updated.MediaURL
+	scheduledMsg.ScheduledAt = updated.ScheduledAt
+	scheduledMsg.CronExpr = updated.CronExpr
+	scheduledMsg.IsRecurring = updated.IsRecurring
 
 	if err := s.db.Save(&scheduledMsg).Error; err != nil {
 		c.JSON(500, gin.H{"error": "Failed to update scheduled message"})
@@ -546,6 +1237,47 @@ func (s *Server) handleUpdateScheduledMessage(c *gin.Context) {
 	})
 }
 
+func (s *Server) handleCancelScheduledMessage(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid scheduled message ID"})
+		return
+	}
+
+	var scheduledMsg database.ScheduledMessage
+	if err := s.db.Where("user_id = ?", userID).First(&scheduledMsg, uint(id)).Error; err != nil {
+		c.JSON(404, gin.H{"error": "Scheduled message not found"})
+		return
+	}
+
+	// Only pending messages can be cancelled; the status guard in the update
+	// also protects against the message being dispatched concurrently
+	result := s.db.Model(&database.ScheduledMessage{}).
+		Where("id = ? AND status = ?", scheduledMsg.ID, "pending").
+		Update("status", "cancelled")
+	if result.Error != nil {
+		c.JSON(500, gin.H{"error": "Failed to cancel scheduled message"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(400, gin.H{"error": "Cannot cancel non-pending scheduled message"})
+		return
+	}
+
+	scheduledMsg.Status = "cancelled"
+	c.JSON(200, gin.H{
+		"message":           "Scheduled message cancelled successfully",
+		"scheduled_message": scheduledMsg,
+	})
+}
+
 func (s *Server) handleDeleteScheduledMessage(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChatSummary is a conversation with its most recent message
+type ChatSummary struct {
+	JID           string            `json:"jid"`
+	Name          string            `json:"name"`
+	IsGroup       bool              `json:"is_group"`
+	LastMessage   *database.Message `json:"last_message"`
+	LastMessageAt time.Time         `json:"last_message_at"`
+	UnreadCount   int64             `json:"unread_count"`
+}
+
+func (s *Server) handleGetChats(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	// Messages are keyed by chat in to_jid, for both directions
+	base := s.db.Model(&database.Message{}).Where("user_id = ?", userID)
+
+	var total int64
+	base.Session(&gorm.Session{}).Distinct("to_jid").Count(&total)
+
+	var chatJIDs []string
+	base.Session(&gorm.Session{}).
+		Select("to_jid").
+		Group("to_jid").
+		Order("MAX(timestamp) DESC").
+		Offset(offset).Limit(limit).
+		Pluck("to_jid", &chatJIDs)
+
+	chats := make([]ChatSummary, 0, len(chatJIDs))
+	for _, jid := range chatJIDs {
+		chats = append(chats, s.buildChatSummary(userID, jid))
+	}
+
+	c.JSON(200, gin.H{
+		"chats": chats,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (s *Server) handleMarkChatRead(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	jid := c.Param("jid")
+
+	var unread []database.Message
+	s.db.Where("user_id = ? AND to_jid = ? AND is_from_me = ? AND is_read = ?", userID, jid, false, false).
+		Find(&unread)
+
+	if len(unread) == 0 {
+		c.JSON(200, gin.H{
+			"jid":          jid,
+			"marked":       0,
+			"unread_count": 0,
+		})
+		return
+	}
+
+	// Receipts are per sender, which matters in groups
+	bySender := make(map[string][]string)
+	ids := make([]uint, 0, len(unread))
+	for _, msg := range unread {
+		bySender[msg.FromJID] = append(bySender[msg.FromJID], msg.MessageID)
+		ids = append(ids, msg.ID)
+	}
+
+	for sender, messageIDs := range bySender {
+		if err := s.waClient.MarkRead(jid, sender, messageIDs); err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to send read receipts: %v", err)})
+			return
+		}
+	}
+
+	s.db.Model(&database.Message{}).Where("id IN ?", ids).Update("is_read", true)
+
+	c.JSON(200, gin.H{
+		"jid":          jid,
+		"marked":       len(ids),
+		"unread_count": s.chatUnreadCount(userID, jid),
+	})
+}
+
+func (s *Server) handleExportChat(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	jid := c.Param("jid")
+	format := c.DefaultQuery("format", "txt")
+
+	var contentType string
+	switch format {
+	case "txt":
+		contentType = "text/plain; charset=utf-8"
+	case "json":
+		contentType = "application/json"
+	case "html":
+		contentType = "text/html; charset=utf-8"
+	default:
+		c.JSON(400, gin.H{"error": "Invalid format, must be txt, json or html"})
+		return
+	}
+
+	query := s.db.Model(&database.Message{}).Where("user_id = ? AND to_jid = ?", userID, jid)
+
+	var total int64
+	query.Session(&gorm.Session{}).Count(&total)
+	if total == 0 {
+		c.JSON(404, gin.H{"error": "Chat not found"})
+		return
+	}
+
+	rows, err := query.Order("timestamp ASC").Rows()
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to load messages"})
+		return
+	}
+	defer rows.Close()
+
+	chat := s.buildChatSummary(userID, jid)
+	title := chat.Name
+	if title == "" {
+		title = jid
+	}
+
+	filename := fmt.Sprintf("chat-%s-%s.%s", strings.Split(jid, "@")[0], time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", contentType)
+	c.Status(200)
+
+	// Resolve sender names once per sender
+	senderNames := make(map[string]string)
+	senderLabel := func(msg *database.Message) string {
+		if msg.IsFromMe {
+			return "Me"
+		}
+		if name, ok := senderNames[msg.FromJID]; ok {
+			return name
+		}
+		name := msg.FromJID
+		var contact database.Contact
+		if err := s.db.Where("user_id = ? AND jid = ?", userID, msg.FromJID).First(&contact).Error; err == nil {
+			if contact.Name != "" {
+				name = contact.Name
+			} else if contact.PushName != "" {
+				name = contact.PushName
+			}
+		}
+		senderNames[msg.FromJID] = name
+		return name
+	}
+
+	w := c.Writer
+	switch format {
+	case "txt":
+		fmt.Fprintf(w, "Chat with %s\n\n", title)
+	case "json":
+		fmt.Fprintf(w, `{"jid":%q,"name":%q,"messages":[`, jid, chat.Name)
+	case "html":
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title></head><body>\n<h1>%s</h1>\n",
+			html.EscapeString(title), html.EscapeString(title))
+	}
+
+	// Stream rows so large conversations are never held in memory
+	first := true
+	for rows.Next() {
+		var msg database.Message
+		if err := s.db.ScanRows(rows, &msg); err != nil {
+			continue
+		}
+
+		sender := senderLabel(&msg)
+		timestamp := msg.Timestamp.Format("2006-01-02 15:04:05")
+
+		switch format {
+		case "txt":
+			text := msg.Content
+			if msg.MediaURL != "" {
+				text = strings.TrimSpace(fmt.Sprintf("[%s: %s] %s", msg.Type, msg.MediaURL, msg.Content))
+			} else if text == "" && msg.Type != "text" {
+				text = fmt.Sprintf("[%s]", msg.Type)
+			}
+			fmt.Fprintf(w, "[%s] %s: %s\n", timestamp, sender, text)
+		case "json":
+			entry, _ := json.Marshal(gin.H{
+				"message_id": msg.MessageID,
+				"timestamp":  msg.Timestamp,
+				"sender":     sender,
+				"from_jid":   msg.FromJID,
+				"is_from_me": msg.IsFromMe,
+				"type":       msg.Type,
+				"content":    msg.Content,
+				"media_url":  msg.MediaURL,
+			})
+			if !first {
+				w.WriteString(",")
+			}
+			w.Write(entry)
+		case "html":
+			body := html.EscapeString(msg.Content)
+			if msg.MediaURL != "" {
+				body = fmt.Sprintf("<a href=\"%s\">[%s]</a> %s", html.EscapeString(msg.MediaURL), html.EscapeString(msg.Type), body)
+			} else if body == "" && msg.Type != "text" {
+				body = fmt.Sprintf("[%s]", html.EscapeString(msg.Type))
+			}
+			fmt.Fprintf(w, "<p><small>%s</small> <b>%s</b>: %s</p>\n", timestamp, html.EscapeString(sender), body)
+		}
+		first = false
+		w.Flush()
+	}
+
+	switch format {
+	case "json":
+		w.WriteString("]}")
+	case "html":
+		w.WriteString("</body></html>\n")
+	}
+}
+
+// buildChatSummary loads the last message, unread count and display name of a chat
+func (s *Server) buildChatSummary(userID uint, jid string) ChatSummary {
+	chat := ChatSummary{JID: jid}
+
+	var lastMessage database.Message
+	if err := s.db.Where("user_id = ? AND to_jid = ?", userID, jid).
+		Order("timestamp DESC").First(&lastMessage).Error; err == nil {
+		chat.LastMessage = &lastMessage
+		chat.LastMessageAt = lastMessage.Timestamp
+	}
+
+	chat.UnreadCount = s.chatUnreadCount(userID, jid)
+
+	var group database.Group
+	if err := s.db.Where("user_id = ? AND jid = ?", userID, jid).First(&group).Error; err == nil {
+		chat.Name = group.Name
+		chat.IsGroup = true
+		return chat
+	}
+
+	var contact database.Contact
+	if err := s.db.Where("user_id = ? AND jid = ?", userID, jid).First(&contact).Error; err == nil {
+		chat.Name = contact.Name
+		if chat.Name == "" {
+			chat.Name = contact.PushName
+		}
+		chat.IsGroup = contact.IsGroup
+	}
+
+	return chat
+}
+
+// chatUnreadCount counts received messages in a chat that haven't been read
+func (s *Server) chatUnreadCount(userID uint, jid string) int64 {
+	var unread int64
+	s.db.Model(&database.Message{}).
+		Where("user_id = ? AND to_jid = ? AND is_from_me = ? AND is_read = ?", userID, jid, false, false).
+		Count(&unread)
+	return unread
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gowa-broadcast/internal/broadcast"
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// handleGetConfig shows the effective configuration after env and flag
+// merging, with secrets redacted
+func (s *Server) handleGetConfig(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"config":  s.cfg.Redacted(),
+		"sources": s.cfg.Sources,
+	})
+}
+
+func (s *Server) handleGetBroadcastConfig(c *gin.Context) {
+	c.JSON(200, s.broadcastMgr.RuntimeConfig())
+}
+
+// handleUpdateBroadcastConfig tunes broadcast throttling without a restart,
+// e.g. to slow down quickly when WhatsApp flags the account
+func (s *Server) handleUpdateBroadcastConfig(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	var req broadcast.RuntimeConfigUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	previous := s.broadcastMgr.RuntimeConfig()
+	updated, err := s.broadcastMgr.UpdateRuntimeConfig(req)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update broadcast config"})
+		return
+	}
+
+	details, _ := json.Marshal(gin.H{"from": previous, "to": updated})
+	audit := &database.AuditLog{
+		ActorID:    actorID,
+		Action:     "config.broadcast.update",
+		TargetType: "config",
+		Details:    string(details),
+		IPAddress:  c.ClientIP(),
+	}
+	if err := s.db.Create(audit).Error; err != nil {
+		logrus.Errorf("Failed to record audit entry config.broadcast.update: %v", err)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Broadcast config updated successfully",
+		"config":  updated,
+	})
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxDeliveriesLimit caps the page size of the delivery history
+const maxDeliveriesLimit = 200
+
+// DeliveryEntry is one outbound message to a recipient, either from a
+// broadcast or sent directly
+type DeliveryEntry struct {
+	Source      string    `json:"source"` // broadcast, message
+	BroadcastID uint      `json:"broadcast_id,omitempty"`
+	MessageID   string    `json:"message_id,omitempty"`
+	JID         string    `json:"jid"`
+	Type        string    `json:"type,omitempty"`
+	Content     string    `json:"content,omitempty"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// handleGetDeliveries returns the delivery timeline of one recipient across
+// all of the user's broadcasts and direct messages, newest first
+func (s *Server) handleGetDeliveries(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	jid := strings.TrimSpace(c.Query("jid"))
+	if jid == "" {
+		c.JSON(400, gin.H{"error": "jid is required"})
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > maxDeliveriesLimit {
+		limit = maxDeliveriesLimit
+	}
+	offset := (page - 1) * limit
+
+	// Lists may hold bare phone numbers or full JIDs, match both forms
+	userJID := normalizeRecipientJID(jid)
+	jids := []string{jid, userJID, strings.TrimSuffix(userJID, "@s.whatsapp.net")}
+
+	deliveryQuery := s.db.Model(&database.BroadcastDelivery{}).
+		Joins("JOIN broadcast_messages ON broadcast_messages.id = broadcast_deliveries.broadcast_message_id").
+		Where("broadcast_messages.user_id = ? AND broadcast_deliveries.jid IN ?", userID, jids)
+	messageQuery := s.db.Model(&database.Message{}).
+		Where("user_id = ? AND is_from_me = ? AND to_jid IN ?", userID, true, jids)
+
+	var deliveryTotal, messageTotal int64
+	if err := deliveryQuery.Session(&gorm.Session{}).Count(&deliveryTotal).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to load deliveries"})
+		return
+	}
+	if err := messageQuery.Session(&gorm.Session{}).Count(&messageTotal).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to load deliveries"})
+		return
+	}
+
+	// The page is within the newest offset+limit entries of the merged
+	// timeline, so each source only needs that many rows
+	var deliveries []database.BroadcastDelivery
+	if err := deliveryQuery.Session(&gorm.Session{}).
+		Select("broadcast_deliveries.*").
+		Order("COALESCE(broadcast_deliveries.sent_at, broadcast_deliveries.created_at) DESC, broadcast_deliveries.id DESC").
+		Limit(offset + limit).Find(&deliveries).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to load deliveries"})
+		return
+	}
+
+	var messages []database.Message
+	if err := messageQuery.Session(&gorm.Session{}).
+		Order("timestamp DESC, id DESC").
+		Limit(offset + limit).Find(&messages).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to load deliveries"})
+		return
+	}
+
+	entries := make([]DeliveryEntry, 0, len(deliveries)+len(messages))
+	for _, delivery := range deliveries {
+		timestamp := delivery.CreatedAt
+		if delivery.SentAt != nil {
+			timestamp = *delivery.SentAt
+		}
+		entries = append(entries, DeliveryEntry{
+			Source:      "broadcast",
+			BroadcastID: delivery.BroadcastMessageID,
+			MessageID:   delivery.MessageID,
+			JID:         delivery.JID,
+			Status:      delivery.Status,
+			Attempts:    delivery.Attempts,
+			Error:       delivery.Error,
+			Timestamp:   timestamp,
+		})
+	}
+	for _, msg := range messages {
+		status := "sent"
+		if msg.IsRead {
+			status = "read"
+		}
+		entries = append(entries, DeliveryEntry{
+			Source:    "message",
+			MessageID: msg.MessageID,
+			JID:       msg.ToJID,
+			Type:      msg.Type,
+			Content:   msg.Content,
+			Status:    status,
+			Timestamp: msg.Timestamp,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	end := offset + limit
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	c.JSON(200, gin.H{
+		"jid":        userJID,
+		"deliveries": entries[offset:end],
+		"total":      deliveryTotal + messageTotal,
+		"page":       page,
+		"limit":      limit,
+	})
+}
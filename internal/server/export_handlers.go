@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// handleExportMyData exports everything stored for the authenticated user
+func (s *Server) handleExportMyData(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	s.streamUserExport(c, userID)
+}
+
+// handleExportUserData exports another user's data (admin only)
+func (s *Server) handleExportUserData(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	actorID, _ := middleware.GetCurrentUserID(c)
+	audit := &database.AuditLog{
+		ActorID:    actorID,
+		Action:     "user.export",
+		TargetType: "user",
+		TargetID:   uint(id),
+		IPAddress:  c.ClientIP(),
+	}
+	if err := s.db.Create(audit).Error; err != nil {
+		logrus.Errorf("Failed to record audit entry user.export for user %d: %v", id, err)
+	}
+
+	s.streamUserExport(c, uint(id))
+}
+
+// streamUserExport writes the user's data as a JSON document, section by
+// section, so large message histories are never held in memory
+func (s *Server) streamUserExport(c *gin.Context, userID uint) {
+	var user database.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		c.JSON(404, gin.H{"error": "User not found"})
+		return
+	}
+
+	filename := fmt.Sprintf("gowa-export-%s-%s.json", user.Username, time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "application/json")
+	c.Header("Cache-Control", "no-store")
+	c.Status(200)
+
+	w := c.Writer
+	userJSON, _ := json.Marshal(user)
+	fmt.Fprintf(w, `{"exported_at":%q,"user":%s`, time.Now().Format(time.RFC3339), userJSON)
+
+	sections := []struct {
+		name  string
+		query *gorm.DB
+		item  func() interface{}
+	}{
+		{"contacts", s.db.Model(&database.Contact{}).Where("user_id = ?", userID), func() interface{} { return &database.Contact{} }},
+		{"groups", s.db.Model(&database.Group{}).Where("user_id = ?", userID), func() interface{} { return &database.Group{} }},
+		{"broadcast_lists", s.db.Model(&database.BroadcastList{}).Where("user_id = ?", userID), func() interface{} { return &database.BroadcastList{} }},
+		{"broadcast_recipients", s.db.Model(&database.BroadcastRecipient{}).
+			Where("broadcast_list_id IN (?)", s.db.Model(&database.BroadcastList{}).Select("id").Where("user_id = ?", userID)),
+			func() interface{} { return &database.BroadcastRecipient{} }},
+		{"broadcasts", s.db.Model(&database.BroadcastMessage{}).Where("user_id = ?", userID), func() interface{} { return &database.BroadcastMessage{} }},
+		{"messages", s.db.Model(&database.Message{}).Where("user_id = ?", userID), func() interface{} { return &database.Message{} }},
+		{"scheduled_messages", s.db.Model(&database.ScheduledMessage{}).Where("user_id = ?", userID), func() interface{} { return &database.ScheduledMessage{} }},
+		{"status_updates", s.db.Model(&database.StatusUpdate{}).Where("user_id = ?", userID), func() interface{} { return &database.StatusUpdate{} }},
+	}
+
+	// Webhooks are not owned by a user, so they are not part of the export
+	for _, section := range sections {
+		fmt.Fprintf(w, `,%q:[`, section.name)
+
+		rows, err := section.query.Order("id ASC").Rows()
+		if err != nil {
+			logrus.Errorf("Failed to export %s for user %d: %v", section.name, userID, err)
+			w.WriteString("]")
+			continue
+		}
+
+		first := true
+		for rows.Next() {
+			item := section.item()
+			if err := s.db.ScanRows(rows, item); err != nil {
+				continue
+			}
+			data, err := json.Marshal(item)
+			if err != nil {
+				continue
+			}
+			if !first {
+				w.WriteString(",")
+			}
+			w.Write(data)
+			first = false
+		}
+		rows.Close()
+
+		w.WriteString("]")
+		w.Flush()
+	}
+
+	w.WriteString("}")
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleGetGroupInviteLink(c *gin.Context) {
+	link, err := s.waClient.GetGroupInviteLink(c.Param("jid"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"jid":         c.Param("jid"),
+		"invite_link": link,
+	})
+}
+
+// handleGetGroupMetadata returns live group info, including current members
+func (s *Server) handleGetGroupMetadata(c *gin.Context) {
+	metadata, err := s.waClient.GetGroupMetadata(c.Param("jid"))
+	switch {
+	case errors.Is(err, whatsapp.ErrClientNotReady):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrInvalidJID):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrNotInGroup):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrGroupNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, metadata)
+	}
+}
+
+func (s *Server) handleJoinGroup(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	var req whatsapp.JoinGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	info, err := s.waClient.JoinGroupWithLink(req.Link)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Persist the joined group for the current user
+	group := database.Group{}
+	if err := s.db.Where(database.Group{UserID: userID, JID: info.JID.String()}).
+		Assign(database.Group{
+			Name:        info.Name,
+			Description: info.Topic,
+			OwnerJID:    info.OwnerJID.String(),
+		}).
+		FirstOrCreate(&group).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Joined group but failed to save it"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Joined group successfully",
+		"group":   group,
+	})
+}
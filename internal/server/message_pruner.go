@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/media"
+
+	"github.com/sirupsen/logrus"
+)
+
+// messagePruneInterval is how often stored messages are checked against
+// MAX_STORED_MESSAGES_PER_USER
+const messagePruneInterval = 10 * time.Minute
+
+// pruneBatchSize bounds how many rows a single delete touches
+const pruneBatchSize = 1000
+
+// pruneMessagesLoop keeps the message table bounded in the background
+func (s *Server) pruneMessagesLoop() {
+	ticker := time.NewTicker(messagePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		s.pruneStoredMessages()
+		<-ticker.C
+	}
+}
+
+// pruneStoredMessages deletes the oldest messages of every user above the
+// limit. Starred messages are kept and don't count towards it.
+func (s *Server) pruneStoredMessages() {
+	limit := int64(s.cfg.WhatsApp.MaxStoredMessages)
+	if limit <= 0 {
+		return
+	}
+
+	type userCount struct {
+		UserID uint
+		Total  int64
+	}
+	var counts []userCount
+	if err := s.db.Model(&database.Message{}).
+		Select("user_id, COUNT(*) AS total").
+		Where("is_starred = ?", false).
+		Group("user_id").
+		Having("COUNT(*) > ?", limit).
+		Scan(&counts).Error; err != nil {
+		logrus.Errorf("Failed to count stored messages: %v", err)
+		return
+	}
+
+	for _, count := range counts {
+		excess := count.Total - limit
+		var pruned int64
+
+		for excess > 0 {
+			batch := excess
+			if batch > pruneBatchSize {
+				batch = pruneBatchSize
+			}
+
+			var ids []uint
+			if err := s.db.Model(&database.Message{}).
+				Where("user_id = ? AND is_starred = ?", count.UserID, false).
+				Order("timestamp ASC, id ASC").
+				Limit(int(batch)).
+				Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+				break
+			}
+
+			var mediaKeys []string
+			s.db.Model(&database.Message{}).
+				Where("id IN ? AND media_url <> ''", ids).
+				Pluck("media_url", &mediaKeys)
+
+			result := s.db.Where("id IN ?", ids).Delete(&database.Message{})
+			if result.Error != nil {
+				logrus.Errorf("Failed to prune messages of user %d: %v", count.UserID, result.Error)
+				break
+			}
+			media.DeleteInbound(context.Background(), s.waClient.MediaStore(), mediaKeys)
+			pruned += result.RowsAffected
+			excess -= int64(len(ids))
+		}
+
+		if pruned > 0 {
+			logrus.Infof("Pruned %d oldest stored messages of user %d (limit %d)", pruned, count.UserID, limit)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxProfilePictureSize caps uploaded profile pictures
+const maxProfilePictureSize = 5 << 20
+
+func (s *Server) handleGetProfile(c *gin.Context) {
+	profile, err := s.waClient.GetProfile()
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, profile)
+}
+
+// handleUpdateProfile accepts a multipart form with optional name, status and
+// picture fields. Only the fields that are present are changed.
+func (s *Server) handleUpdateProfile(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxProfilePictureSize+(1<<20))
+
+	name, hasName := c.GetPostForm("name")
+	status, hasStatus := c.GetPostForm("status")
+	fileHeader, fileErr := c.FormFile("picture")
+	hasPicture := fileErr == nil
+
+	if !hasName && !hasStatus && !hasPicture {
+		c.JSON(400, gin.H{"error": "At least one of name, status or picture is required"})
+		return
+	}
+
+	updated := gin.H{}
+
+	if hasName {
+		if name == "" {
+			c.JSON(400, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		if err := s.waClient.SetProfileName(name); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		updated["name"] = name
+	}
+
+	if hasStatus {
+		if err := s.waClient.SetProfileStatus(status); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		updated["status"] = status
+	}
+
+	if hasPicture {
+		if fileHeader.Size > maxProfilePictureSize {
+			c.JSON(400, gin.H{"error": "picture is too large"})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		image, err := io.ReadAll(file)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		pictureID, err := s.waClient.SetProfilePicture(image)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updated["picture_id"] = pictureID
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Profile updated successfully",
+		"updated": updated,
+	})
+}
+
+func (s *Server) handleSendStatus(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	var req whatsapp.StatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	resp, err := s.waClient.SendStatus(&req)
+	if err != nil {
+		c.JSON(sendErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	status := &database.StatusUpdate{
+		UserID:    userID,
+		MessageID: resp.MessageID,
+		Type:      req.Type,
+		Content:   req.Content,
+		MediaURL:  req.MediaURL,
+	}
+	if err := s.db.Create(status).Error; err != nil {
+		logrus.Errorf("Failed to record status %s: %v", resp.MessageID, err)
+	}
+
+	c.JSON(200, gin.H{
+		"message_id": resp.MessageID,
+		"timestamp":  resp.Timestamp,
+		"status":     status,
+	})
+}
+
+func (s *Server) handleGetStatuses(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	// Pagination
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	var statuses []database.StatusUpdate
+	var total int64
+	query := s.db.Model(&database.StatusUpdate{}).Where("user_id = ?", userID)
+	query.Count(&total)
+	query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&statuses)
+
+	c.JSON(200, gin.H{
+		"statuses": statuses,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
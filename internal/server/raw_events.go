@@ -0,0 +1,26 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"gowa-broadcast/internal/whatsapp"
+)
+
+// queueRawEvent buffers a raw event for deliverRawEvents. It runs on the
+// WhatsApp event loop, so when the buffer is full the event is dropped and
+// counted with the other dropped deliveries rather than waited on.
+func (s *Server) queueRawEvent(event whatsapp.RawEvent) {
+	select {
+	case s.rawEvents <- event:
+	default:
+		atomic.AddInt64(&s.webhookQueue.dropped, 1)
+	}
+}
+
+// deliverRawEvents hands buffered raw events to the subscribed webhooks one
+// at a time
+func (s *Server) deliverRawEvents() {
+	for event := range s.rawEvents {
+		s.SendWebhook("raw", event)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxScheduledImportRows caps how many messages a single CSV import may schedule
+const maxScheduledImportRows = 1000
+
+// scheduledImportColumns is the column order of a scheduled message CSV
+const scheduledImportColumns = "name,recipients,type,content,media_url,scheduled_at,cron"
+
+// ScheduledImportResult reports the outcome of importing one CSV row
+type ScheduledImportResult struct {
+	Row                int          `json:"row"`
+	Name               string       `json:"name"`
+	Success            bool         `json:"success"`
+	ScheduledMessageID uint         `json:"scheduled_message_id,omitempty"`
+	Error              string       `json:"error,omitempty"`
+	Fields             []FieldError `json:"fields,omitempty"`
+}
+
+// handleImportScheduledMessages schedules messages from a CSV file with the
+// columns name,recipients,type,content,media_url,scheduled_at,cron. Recipients
+// are separated by ";" and a row with a cron expression is recurring. Every
+// row is validated like POST /scheduled, the valid ones are created together.
+func (s *Server) handleImportScheduledMessages(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	results := make([]ScheduledImportResult, 0)
+	var messages []*database.ScheduledMessage
+	var messageResults []int // Index into results of each message
+	row := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid CSV at row %d: %v", row, err)})
+			return
+		}
+
+		// Skip the header row if present
+		if row == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue
+		}
+
+		if len(results) >= maxScheduledImportRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many rows. Maximum allowed: %d", maxScheduledImportRows)})
+			return
+		}
+
+		result := ScheduledImportResult{Row: row}
+		if len(record) != 7 {
+			result.Error = "expected 7 columns: " + scheduledImportColumns
+			results = append(results, result)
+			continue
+		}
+
+		req := CreateScheduledMessageRequest{
+			Name:        strings.TrimSpace(record[0]),
+			Recipients:  splitRecipients(record[1]),
+			MessageType: strings.ToLower(strings.TrimSpace(record[2])),
+			Content:     record[3],
+			MediaURL:    strings.TrimSpace(record[4]),
+			ScheduledAt: strings.TrimSpace(record[5]),
+			CronExpr:    strings.TrimSpace(record[6]),
+		}
+		req.IsRecurring = req.CronExpr != ""
+		result.Name = req.Name
+
+		// Apply the same validation rules as the JSON endpoint
+		if err := binding.Validator.ValidateStruct(&req); err != nil {
+			result.Error = "Validation failed"
+			result.Fields = validationErrors(err)
+			results = append(results, result)
+			continue
+		}
+
+		msg, err := s.newScheduledMessage(userID, &req)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		messages = append(messages, msg)
+		messageResults = append(messageResults, len(results))
+		results = append(results, result)
+	}
+
+	if len(messages) > 0 {
+		if err := s.db.Create(&messages).Error; err != nil {
+			c.JSON(500, gin.H{"error": "Failed to create scheduled messages"})
+			return
+		}
+	}
+	for i, msg := range messages {
+		results[messageResults[i]].Success = true
+		results[messageResults[i]].ScheduledMessageID = msg.ID
+		s.SendWebhook("scheduled.created", scheduler.NewEvent(msg))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%d of %d scheduled messages imported", len(messages), len(results)),
+		"created": len(messages),
+		"failed":  len(results) - len(messages),
+		"results": results,
+	})
+}
+
+// splitRecipients splits a CSV recipients cell on ";" into trimmed,
+// non-empty recipients
+func splitRecipients(cell string) []string {
+	recipients := make([]string, 0)
+	for _, recipient := range strings.Split(cell, ";") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// handleGetSchedulerStatus shows whether the scheduler is paused, when it
+// last ran and which scheduled message is due next
+func (s *Server) handleGetSchedulerStatus(c *gin.Context) {
+	status, err := s.scheduler.Status()
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to get scheduler status"})
+		return
+	}
+
+	c.JSON(200, status)
+}
+
+// handlePauseScheduler halts all scheduled sends, e.g. during maintenance,
+// without touching the schedules themselves
+func (s *Server) handlePauseScheduler(c *gin.Context) {
+	s.setSchedulerPaused(c, true)
+}
+
+// handleResumeScheduler lets the scheduler dispatch due messages again
+func (s *Server) handleResumeScheduler(c *gin.Context) {
+	s.setSchedulerPaused(c, false)
+}
+
+func (s *Server) setSchedulerPaused(c *gin.Context, paused bool) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	if err := s.scheduler.SetPaused(paused); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update scheduler state"})
+		return
+	}
+
+	action, message := "scheduler.resume", "Scheduler resumed successfully"
+	if paused {
+		action, message = "scheduler.pause", "Scheduler paused successfully"
+	}
+	audit := &database.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "scheduler",
+		IPAddress:  c.ClientIP(),
+	}
+	if err := s.db.Create(audit).Error; err != nil {
+		logrus.Errorf("Failed to record audit entry %s: %v", action, err)
+	}
+
+	c.JSON(200, gin.H{
+		"message": message,
+		"paused":  paused,
+	})
+}
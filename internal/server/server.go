@@ -2,17 +2,24 @@ package server
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"gowa-broadcast/internal/auth"
 	"gowa-broadcast/internal/broadcast"
 	"gowa-broadcast/internal/config"
 	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/media"
 	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/scheduler"
 	"gowa-broadcast/internal/whatsapp"
 
 	"github.com/gin-gonic/gin"
@@ -29,19 +36,25 @@ type Server struct {
 	authHandlers    *AuthHandlers
 	router          *gin.Engine
 	basicAuthUsers  map[string]string
+	webhookQueue    *webhookQueue
+	webhookBatcher  *webhookBatcher
+	scheduler       *scheduler.Scheduler
+	rawEvents       chan whatsapp.RawEvent
+	selfTestMu      sync.Mutex
+	lastSelfTest    time.Time
 }
 
-func NewServer(cfg *config.Config, db *gorm.DB, waClient *whatsapp.Client) *Server {
+func NewServer(cfg *config.Config, db *gorm.DB, waClient *whatsapp.Client) (*Server, error) {
 	// Setup Gin mode
 	if !cfg.App.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Create broadcast manager
-	broadcastMgr := broadcast.NewManager(cfg, db, waClient)
-
 	// Create auth service
-	authService := auth.NewAuthService(db, cfg.JWT.Secret)
+	authService, err := auth.NewAuthService(db, cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
 
 	// Parse basic auth users
 	basicAuthUsers := cfg.App.ParseBasicAuth()
@@ -50,16 +63,50 @@ func NewServer(cfg *config.Config, db *gorm.DB, waClient *whatsapp.Client) *Serv
 		cfg:            cfg,
 		db:             db,
 		waClient:       waClient,
-		broadcastMgr:   broadcastMgr,
 		authService:    authService,
 		basicAuthUsers: basicAuthUsers,
 	}
 
+	authService.SetMediaStore(waClient.MediaStore())
+
 	// Create auth handlers
 	server.authHandlers = NewAuthHandlers(authService)
 
+	// Start webhook delivery workers
+	server.webhookQueue = newWebhookQueue(cfg.Webhook.QueueSize, cfg.Webhook.MaxConcurrent, func(job webhookJob) {
+		server.sendWebhookRequest(job.webhook, job.payload, job.event)
+	}, func(job webhookJob) {
+		// Logged like a failed delivery so a replay of the period resends it
+		server.logWebhookError(job.webhook.ID, job.event, job.payload, 0, "", "queue full", false)
+	})
+	server.webhookBatcher = newWebhookBatcher(server.webhookQueue)
+
+	server.broadcastMgr = broadcast.NewManager(cfg, db, waClient, server.notifyBroadcast)
+	server.scheduler = scheduler.New(cfg, db, waClient, server.SendWebhook)
+
+	if cfg.Webhook.RawEvents {
+		server.rawEvents = make(chan whatsapp.RawEvent, cfg.Webhook.QueueSize)
+		go server.deliverRawEvents()
+		waClient.SetRawEventHandler(server.queueRawEvent)
+	}
+	waClient.SetReceiptHandler(func(receipt whatsapp.MessageReceipt) {
+		go server.SendWebhook(receipt.Event, receipt)
+	})
+	waClient.SetMessageHandler(func(msg whatsapp.IncomingMessage) {
+		go server.SendWebhook("message.received", MessageWebhookData{
+			MessageID:       msg.MessageID,
+			FromJID:         msg.From,
+			ToJID:           msg.Chat,
+			Type:            msg.Type,
+			Content:         msg.Content,
+			Timestamp:       msg.Timestamp.Unix(),
+			QuotedMessageID: msg.QuotedMessageID,
+			QuotedContent:   msg.QuotedContent,
+		})
+	})
+
 	server.setupRoutes()
-	return server
+	return server, nil
 }
 
 func (s *Server) setupRoutes() {
@@ -69,6 +116,7 @@ func (s *Server) setupRoutes() {
 	s.router.Use(gin.Logger())
 	s.router.Use(gin.Recovery())
 	s.router.Use(s.corsMiddleware())
+	s.router.Use(middleware.MaxRequestBody(s.cfg.App.MaxBodyBytes))
 
 	// Base path
 	var api *gin.RouterGroup
@@ -81,6 +129,7 @@ func (s *Server) setupRoutes() {
 	// Public routes
 	api.GET("/", s.handleIndex)
 	api.GET("/health", s.handleHealth)
+	api.GET("/ready", s.handleReady)
 
 	// Authentication routes (public)
 	auth := api.Group("/auth")
@@ -100,17 +149,24 @@ func (s *Server) setupRoutes() {
 		users.GET("/profile", s.authHandlers.GetProfile)
 		users.PUT("/profile", s.authHandlers.UpdateProfile)
 		users.POST("/change-password", s.authHandlers.ChangeMyPassword)
+		users.GET("/profile/export", s.handleExportMyData)
 
 		// Admin only routes
 		adminUsers := users.Group("/")
 		adminUsers.Use(middleware.AdminOnlyMiddleware())
 		{
 			adminUsers.POST("/", s.authHandlers.CreateUser)
+			adminUsers.POST("/import", middleware.MaxRequestBody(s.cfg.App.MaxUploadBytes), s.authHandlers.ImportUsers)
 			adminUsers.GET("/", s.authHandlers.GetUsers)
 			adminUsers.GET("/:id", s.authHandlers.GetUser)
 			adminUsers.PUT("/:id", s.authHandlers.UpdateUser)
 			adminUsers.DELETE("/:id", s.authHandlers.DeleteUser)
 			adminUsers.POST("/:id/change-password", s.authHandlers.ChangePassword)
+			adminUsers.POST("/:id/deactivate", s.authHandlers.DeactivateUser)
+			adminUsers.POST("/:id/reactivate", s.authHandlers.ReactivateUser)
+			adminUsers.GET("/:id/export", s.handleExportUserData)
+			adminUsers.GET("/:id/limits", s.authHandlers.GetUserLimits)
+			adminUsers.PUT("/:id/limits", s.authHandlers.SetUserLimits)
 		}
 	}
 
@@ -124,10 +180,34 @@ func (s *Server) setupRoutes() {
 	wa := protected.Group("/whatsapp")
 	{
 		wa.GET("/qr", s.handleGetQR)
+		wa.POST("/qr/refresh", s.handleRefreshQR)
 		wa.GET("/status", s.handleGetStatus)
+		wa.GET("/diagnostics", s.handleGetDiagnostics)
 		wa.POST("/logout", s.handleLogout)
+		wa.GET("/profile", s.handleGetProfile)
+		wa.PUT("/profile", middleware.MaxRequestBody(s.cfg.App.MaxUploadBytes), s.handleUpdateProfile)
+		wa.POST("/status", s.handleSendStatus)
+		wa.GET("/statuses", s.handleGetStatuses)
 		wa.GET("/contacts", s.handleGetContacts)
+		wa.POST("/contacts/sync", s.handleSyncContacts)
+		wa.GET("/contacts/:jid/presence", s.handleGetContactPresence)
+		wa.GET("/contacts/:jid/business", s.handleGetBusinessProfile)
+		wa.GET("/resolve", s.handleResolveJID)
 		wa.GET("/groups", s.handleGetGroups)
+		wa.POST("/groups/sync", s.handleSyncGroups)
+		wa.GET("/groups/:jid", s.handleGetGroupMetadata)
+		wa.GET("/groups/:jid/invite", s.handleGetGroupInviteLink)
+		wa.POST("/groups/join", s.handleJoinGroup)
+		wa.POST("/send-raw", middleware.AdminOnlyMiddleware(), s.handleSendRaw)
+		wa.POST("/self-test", middleware.AdminOnlyMiddleware(), s.handleSelfTest)
+
+		// Session backup is sensitive, restrict it to admins
+		session := wa.Group("/session")
+		session.Use(middleware.AdminOnlyMiddleware())
+		{
+			session.GET("/export", s.handleExportSession)
+			session.POST("/import", middleware.MaxRequestBody(s.cfg.App.MaxUploadBytes), s.handleImportSession)
+		}
 	}
 
 	// Message routes
@@ -135,19 +215,40 @@ func (s *Server) setupRoutes() {
 	{
 		messages.POST("/text", s.handleSendText)
 		messages.POST("/media", s.handleSendMedia)
+		messages.POST("/album", s.handleSendAlbum)
 		messages.POST("/location", s.handleSendLocation)
 		messages.POST("/contact", s.handleSendContact)
+		messages.POST("/contact/validate", s.handleValidateVCard)
+		messages.POST("/contacts", s.handleSendContacts)
 		messages.GET("/", s.handleGetMessages)
+		messages.PUT("/:id", s.handleEditMessage)
+		messages.PATCH("/:id/star", s.handleToggleMessageStar)
+		messages.GET("/:id/media", s.handleGetMessageMedia)
+	}
+
+	// Chat routes
+	chats := protected.Group("/chats")
+	{
+		chats.GET("/", s.handleGetChats)
+		chats.POST("/:jid/read", s.handleMarkChatRead)
+		chats.GET("/:jid/export", s.handleExportChat)
 	}
 
+	// Delivery history of a single recipient
+	protected.GET("/deliveries", s.handleGetDeliveries)
+
 	// Broadcast List routes
 	broadcastLists := protected.Group("/broadcast-lists")
 	{
 		broadcastLists.GET("/", s.handleGetBroadcastLists)
 		broadcastLists.POST("/", s.handleCreateBroadcastList)
+		broadcastLists.POST("/deactivate-stale", s.handleDeactivateStaleContacts)
 		broadcastLists.GET("/:id", s.handleGetBroadcastList)
 		broadcastLists.PUT("/:id", s.handleUpdateBroadcastList)
 		broadcastLists.DELETE("/:id", s.handleDeleteBroadcastList)
+		broadcastLists.GET("/:id/recipients", s.handleGetBroadcastListRecipients)
+		broadcastLists.GET("/:id/preview-recipients", s.handlePreviewRecipients)
+		broadcastLists.GET("/:id/check", s.handleCheckRecipient)
 		broadcastLists.POST("/:id/recipients", s.handleAddRecipients)
 		broadcastLists.DELETE("/:id/recipients/:recipientId", s.handleRemoveRecipient)
 	}
@@ -158,8 +259,13 @@ func (s *Server) setupRoutes() {
 		broadcasts.POST("/", s.handleCreateBroadcast)
 		broadcasts.GET("/:id/status", s.handleGetBroadcastStatus)
 		broadcasts.POST("/:id/cancel", s.handleCancelBroadcast)
+		broadcasts.POST("/:id/confirm", s.handleConfirmBroadcast)
+		broadcasts.GET("/:id/replies", s.handleGetBroadcastReplies)
+		broadcasts.GET("/:id/report.pdf", s.handleGetBroadcastReport)
+		broadcasts.POST("/:id/deliveries/:jid/resend", s.handleResendDelivery)
 		broadcasts.GET("/active", s.handleGetActiveBroadcasts)
 		broadcasts.GET("/history", s.handleGetBroadcastHistory)
+		broadcasts.GET("/pacing", s.handleGetBroadcastPacing)
 	}
 
 	// Scheduled messages routes
@@ -167,9 +273,21 @@ func (s *Server) setupRoutes() {
 	{
 		scheduled.GET("/", s.handleGetScheduledMessages)
 		scheduled.POST("/", s.handleCreateScheduledMessage)
+		scheduled.POST("/import", middleware.MaxRequestBody(s.cfg.App.MaxUploadBytes), s.handleImportScheduledMessages)
+		scheduled.GET("/upcoming", s.handleGetUpcomingScheduled)
 		scheduled.GET("/:id", s.handleGetScheduledMessage)
 		scheduled.PUT("/:id", s.handleUpdateScheduledMessage)
 		scheduled.DELETE("/:id", s.handleDeleteScheduledMessage)
+		scheduled.POST("/:id/cancel", s.handleCancelScheduledMessage)
+	}
+
+	// Scheduler control routes (admin only)
+	schedulerControl := protected.Group("/scheduler")
+	schedulerControl.Use(middleware.AdminOnlyMiddleware())
+	{
+		schedulerControl.GET("/status", s.handleGetSchedulerStatus)
+		schedulerControl.POST("/pause", s.handlePauseScheduler)
+		schedulerControl.POST("/resume", s.handleResumeScheduler)
 	}
 
 	// Statistics routes
@@ -178,6 +296,7 @@ func (s *Server) setupRoutes() {
 		stats.GET("/dashboard", s.handleGetDashboardStats)
 		stats.GET("/messages", s.handleGetMessageStats)
 		stats.GET("/broadcasts", s.handleGetBroadcastStats)
+		stats.GET("/system", middleware.AdminOnlyMiddleware(), s.handleGetSystemStats)
 	}
 
 	// Webhook routes
@@ -185,24 +304,74 @@ func (s *Server) setupRoutes() {
 	{
 		webhooks.POST("/", s.handleCreateWebhook)
 		webhooks.GET("/", s.handleGetWebhooks)
+		webhooks.GET("/queue", s.handleGetWebhookQueueStats)
+		webhooks.GET("/logs", middleware.AdminOnlyMiddleware(), s.handleGetAllWebhookLogs)
 		webhooks.GET("/:id", s.handleGetWebhook)
 		webhooks.PUT("/:id", s.handleUpdateWebhook)
 		webhooks.DELETE("/:id", s.handleDeleteWebhook)
 		webhooks.POST("/:id/toggle", s.handleToggleWebhook)
 		webhooks.GET("/:id/logs", s.handleGetWebhookLogs)
+		webhooks.POST("/:id/replay", s.handleReplayWebhook)
+	}
+
+	// Runtime config routes (admin only)
+	runtimeConfig := protected.Group("/config")
+	runtimeConfig.Use(middleware.AdminOnlyMiddleware())
+	{
+		runtimeConfig.GET("", s.handleGetConfig)
+		runtimeConfig.GET("/broadcast", s.handleGetBroadcastConfig)
+		runtimeConfig.PUT("/broadcast", s.handleUpdateBroadcastConfig)
 	}
 }
 
 func (s *Server) Start() error {
+	if s.cfg.Scheduler.Enabled {
+		s.scheduler.Start()
+	}
+	if s.cfg.WhatsApp.MaxStoredMessages > 0 {
+		go s.pruneMessagesLoop()
+	}
+
+	if s.cfg.App.TLSCertFile != "" && s.cfg.App.TLSKeyFile != "" {
+		if s.cfg.App.TLSRedirectPort != "" {
+			go s.serveHTTPSRedirect()
+		}
+		logrus.Infof("Starting HTTPS server on port %s", s.cfg.App.Port)
+		return s.router.RunTLS(":"+s.cfg.App.Port, s.cfg.App.TLSCertFile, s.cfg.App.TLSKeyFile)
+	}
+	if s.cfg.App.TLSCertFile != "" || s.cfg.App.TLSKeyFile != "" {
+		logrus.Warn("TLS_CERT_FILE and TLS_KEY_FILE must both be set, serving plain HTTP")
+	}
+
 	logrus.Infof("Starting HTTP server on port %s", s.cfg.App.Port)
 	return s.router.Run(":" + s.cfg.App.Port)
 }
 
+// serveHTTPSRedirect answers plain HTTP on TLSRedirectPort with a permanent
+// redirect to the same path on the HTTPS port
+func (s *Server) serveHTTPSRedirect() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if s.cfg.App.Port != "443" {
+			host = net.JoinHostPort(host, s.cfg.App.Port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+
+	logrus.Infof("Redirecting HTTP on port %s to HTTPS", s.cfg.App.TLSRedirectPort)
+	if err := http.ListenAndServe(":"+s.cfg.App.TLSRedirectPort, handler); err != nil {
+		logrus.Errorf("HTTP redirect listener stopped: %v", err)
+	}
+}
+
 // Middleware
 func (s *Server) corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
 
 		if c.Request.Method == "OPTIONS" {
@@ -280,6 +449,39 @@ func (s *Server) handleHealth(c *gin.Context) {
 	})
 }
 
+// handleReady reports whether the service can take traffic: the WhatsApp
+// session must be connected and the database reachable
+func (s *Server) handleReady(c *gin.Context) {
+	whatsappReady := s.waClient.IsReady()
+
+	databaseReady := false
+	if sqlDB, err := s.db.DB(); err == nil {
+		databaseReady = sqlDB.Ping() == nil
+	}
+
+	whatsappStatus := "disconnected"
+	if whatsappReady {
+		whatsappStatus = "connected"
+	}
+
+	status := 200
+	readyStatus := "ready"
+	if !whatsappReady || !databaseReady {
+		status = http.StatusServiceUnavailable
+		readyStatus = "not_ready"
+	}
+
+	c.JSON(status, gin.H{
+		"status":            readyStatus,
+		"whatsapp":          whatsappStatus,
+		"database":          databaseReady,
+		"logged_in":         s.waClient.GetClient().Store.ID != nil,
+		"qr_pending":        s.waClient.IsQRPending(),
+		"last_connected_at": s.waClient.LastConnectedAt(),
+		"timestamp":         time.Now().Unix(),
+	})
+}
+
 func (s *Server) handleGetQR(c *gin.Context) {
 	if s.waClient.GetClient().Store.ID != nil {
 		c.JSON(200, gin.H{
@@ -289,17 +491,39 @@ func (s *Server) handleGetQR(c *gin.Context) {
 		return
 	}
 
-	qrCode, err := s.waClient.GetQRCode()
+	if userID, exists := middleware.GetCurrentUserID(c); exists {
+		s.waClient.SetPairingUser(userID)
+	}
+
+	qr, err := s.waClient.GetQRCode()
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"qr_code":   qrCode,
-		"connected": false,
-		"timeout":   30,
-	})
+	c.JSON(200, qr)
+}
+
+func (s *Server) handleRefreshQR(c *gin.Context) {
+	if s.waClient.GetClient().Store.ID != nil {
+		c.JSON(200, gin.H{
+			"connected": true,
+			"message":   "Already connected to WhatsApp",
+		})
+		return
+	}
+
+	if userID, exists := middleware.GetCurrentUserID(c); exists {
+		s.waClient.SetPairingUser(userID)
+	}
+
+	qr, err := s.waClient.RefreshQRCode()
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, qr)
 }
 
 func (s *Server) handleGetStatus(c *gin.Context) {
@@ -313,13 +537,19 @@ func (s *Server) handleGetStatus(c *gin.Context) {
 	}
 
 	c.JSON(200, gin.H{
-		"connected":  connected,
-		"jid":        jid,
-		"device":     device,
-		"timestamp":  time.Now().Unix(),
+		"connected": connected,
+		"jid":       jid,
+		"device":    device,
+		"timestamp": time.Now().Unix(),
 	})
 }
 
+// handleGetDiagnostics returns a snapshot of the WhatsApp link for debugging
+// connectivity problems
+func (s *Server) handleGetDiagnostics(c *gin.Context) {
+	c.JSON(200, s.waClient.Diagnostics())
+}
+
 func (s *Server) handleLogout(c *gin.Context) {
 	if err := s.waClient.Logout(); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
@@ -347,13 +577,26 @@ func (s *Server) handleGetContacts(c *gin.Context) {
 
 	// Search
 	if search := c.Query("search"); search != "" {
-		query = query.Where("name LIKE ? OR phone_number LIKE ?", "%"+search+"%", "%"+search+"%")
+		query = query.Where("name LIKE ? OR push_name LIKE ? OR phone_number LIKE ?", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+	}
+
+	// Filter contacts that have a saved or push name
+	if hasName := c.Query("has_name"); hasName != "" {
+		if hasName == "true" {
+			query = query.Where("(name <> '' OR push_name <> '')")
+		} else if hasName == "false" {
+			query = query.Where("COALESCE(name, '') = '' AND COALESCE(push_name, '') = ''")
+		}
 	}
 
 	var total int64
 	query.Count(&total)
 	query.Offset(offset).Limit(limit).Find(&contacts)
 
+	for i := range contacts {
+		contacts[i].ResolveDisplayName()
+	}
+
 	c.JSON(200, gin.H{
 		"contacts": contacts,
 		"total":    total,
@@ -362,6 +605,93 @@ func (s *Server) handleGetContacts(c *gin.Context) {
 	})
 }
 
+// handleResolveJID shows which JID a number resolves to and whether it is on WhatsApp
+func (s *Server) handleResolveJID(c *gin.Context) {
+	number := strings.TrimSpace(c.Query("number"))
+	if number == "" {
+		c.JSON(400, gin.H{"error": "number is required"})
+		return
+	}
+
+	resolved, err := s.waClient.ResolveJID(number, c.Query("country_code"))
+	if resolved == nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		// The JID itself is fine, only the registration lookup failed
+		c.JSON(200, gin.H{
+			"resolved":           resolved,
+			"registration_error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{"resolved": resolved})
+}
+
+// presenceWait is how long to wait for the first presence update after subscribing
+const presenceWait = 3 * time.Second
+
+// handleGetBusinessProfile returns a contact's WhatsApp Business profile,
+// is_business is false for regular accounts
+func (s *Server) handleGetBusinessProfile(c *gin.Context) {
+	profile, err := s.waClient.GetBusinessProfile(c.Param("jid"))
+	switch {
+	case errors.Is(err, whatsapp.ErrClientNotReady):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrInvalidJID):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(502, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, profile)
+	}
+}
+
+func (s *Server) handleGetContactPresence(c *gin.Context) {
+	jid := c.Param("jid")
+
+	if err := s.waClient.SubscribePresence(jid); err != nil {
+		if err == whatsapp.ErrClientNotReady {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Presence arrives asynchronously, give WhatsApp a moment to answer
+	presence, _ := s.waClient.GetPresence(jid)
+	for deadline := time.Now().Add(presenceWait); presence == nil && time.Now().Before(deadline); {
+		time.Sleep(200 * time.Millisecond)
+		presence, _ = s.waClient.GetPresence(jid)
+	}
+
+	if presence == nil {
+		// No update at all usually means the contact hides their presence
+		c.JSON(200, gin.H{
+			"jid":                jid,
+			"status":             "unknown",
+			"privacy_restricted": true,
+		})
+		return
+	}
+
+	status := "offline"
+	if presence.Online {
+		status = "online"
+	}
+
+	c.JSON(200, gin.H{
+		"jid":                presence.JID,
+		"status":             status,
+		"last_seen":          presence.LastSeen,
+		"privacy_restricted": presence.Hidden,
+		"updated_at":         presence.UpdatedAt,
+	})
+}
+
 func (s *Server) handleGetGroups(c *gin.Context) {
 	// Get current user ID
 	userID, exists := middleware.GetCurrentUserID(c)
@@ -396,31 +726,168 @@ func (s *Server) handleGetGroups(c *gin.Context) {
 }
 
 func (s *Server) handleSendText(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	// Form bodies are accepted as well, so plain curl -d calls work
 	var req whatsapp.MessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
-	resp, err := s.waClient.SendTextMessage(req.To, req.Message)
+	if req.CountryCode != "" {
+		req.To = s.waClient.WithCountryCode(req.To, req.CountryCode)
+	}
+
+	if req.SendAt != "" {
+		// Scheduled messages are sent as plain text
+		if req.LinkPreview != nil {
+			c.JSON(400, gin.H{"error": "link_preview can't be combined with send_at"})
+			return
+		}
+		s.scheduleSend(c, req.SendAt, req.To, "text", req.Message, "")
+		return
+	}
+
+	resp, err := s.waClient.SendTextMessageWithPreview(req.To, req.Message, req.LinkPreview)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(sendErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	s.waClient.RecordSentMessage(userID, req.To, "text", req.Message, "", resp)
 
 	c.JSON(200, resp)
 }
 
+// sendErrorStatus is the HTTP status for a failed send, 403 when the
+// recipient isn't on the allowlist
+func sendErrorStatus(err error) int {
+	if errors.Is(err, whatsapp.ErrRecipientNotAllowed) {
+		return 403
+	}
+	return 500
+}
+
 func (s *Server) handleSendMedia(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
 	var req whatsapp.MediaMessageRequest
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.CountryCode != "" {
+		req.To = s.waClient.WithCountryCode(req.To, req.CountryCode)
+	}
+
+	if req.SendAt != "" {
+		// The scheduler fetches media without request headers
+		if len(req.MediaHeaders) > 0 {
+			c.JSON(400, gin.H{"error": "media_headers can't be combined with send_at"})
+			return
+		}
+		s.scheduleSend(c, req.SendAt, req.To, strings.ToLower(req.Type), req.Caption, req.MediaURL)
+		return
+	}
+
+	resp, err := s.waClient.SendMediaMessage(&req)
+	if err != nil {
+		c.JSON(sendErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	s.waClient.RecordSentMessage(userID, req.To, strings.ToLower(req.Type), req.Caption, req.MediaURL, resp)
+
+	c.JSON(200, resp)
+}
+
+// scheduleSend stores a message that has send_at as a scheduled message for
+// one recipient instead of sending it now
+func (s *Server) scheduleSend(c *gin.Context, sendAt, to, messageType, content, mediaURL string) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	if _, err := time.Parse(time.RFC3339, sendAt); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid send_at format. Use RFC3339 format"})
+		return
+	}
+
+	switch messageType {
+	case "text", "image", "document", "audio", "video":
+	default:
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Unsupported message type for send_at: %s", messageType)})
+		return
+	}
+
+	scheduledMsg, err := s.newScheduledMessage(userID, &CreateScheduledMessageRequest{
+		Name:        fmt.Sprintf("%s message to %s", messageType, to),
+		Recipients:  []string{to},
+		MessageType: messageType,
+		Content:     content,
+		MediaURL:    mediaURL,
+		ScheduledAt: sendAt,
+	})
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.db.Create(scheduledMsg).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to schedule message"})
+		return
+	}
+
+	s.SendWebhook("scheduled.created", scheduler.NewEvent(scheduledMsg))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":              "Message scheduled",
+		"scheduled_message_id": scheduledMsg.ID,
+		"scheduled_at":         scheduledMsg.ScheduledAt,
+	})
+}
+
+func (s *Server) handleSendAlbum(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	var req whatsapp.AlbumMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if err := whatsapp.ValidateAlbum(req.Items); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	resp, err := s.waClient.SendMediaMessage(&req)
+	if req.CountryCode != "" {
+		req.To = s.waClient.WithCountryCode(req.To, req.CountryCode)
+	}
+
+	resp, err := s.waClient.SendAlbum(&req)
+	for i, id := range resp.MessageIDs {
+		// A failed album still stores the items that went out
+		item := req.Items[i]
+		sent := &whatsapp.MessageResponse{MessageID: id, Timestamp: resp.Timestamp}
+		s.waClient.RecordSentMessage(userID, req.To, strings.ToLower(item.Type), item.Caption, item.MediaURL, sent)
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(sendErrorStatus(err), gin.H{"error": err.Error(), "message_ids": resp.MessageIDs})
 		return
 	}
 
@@ -428,29 +895,161 @@ func (s *Server) handleSendMedia(c *gin.Context) {
 }
 
 func (s *Server) handleSendLocation(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
 	var req whatsapp.LocationMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
+	if req.CountryCode != "" {
+		req.To = s.waClient.WithCountryCode(req.To, req.CountryCode)
+	}
+
 	resp, err := s.waClient.SendLocationMessage(&req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(sendErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	s.waClient.RecordSentMessage(userID, req.To, "location", req.Name, "", resp)
 
 	c.JSON(200, resp)
 }
 
 func (s *Server) handleSendContact(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
 	var req whatsapp.ContactMessageRequest
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.CountryCode != "" {
+		req.To = s.waClient.WithCountryCode(req.To, req.CountryCode)
+	}
+
+	resp, err := s.waClient.SendContactMessage(&req)
+	if err == nil {
+		s.waClient.RecordSentMessage(userID, req.To, "contact", req.DisplayName, "", resp)
+	}
+	switch {
+	case errors.Is(err, whatsapp.ErrInvalidVCard), errors.Is(err, whatsapp.ErrInvalidJID):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrRecipientNotAllowed):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, resp)
+	}
+}
+
+// handleValidateVCard checks a vCard, or builds one from structured fields,
+// without sending anything
+func (s *Server) handleValidateVCard(c *gin.Context) {
+	var req whatsapp.VCardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	countryCode := req.CountryCode
+	if countryCode == "" {
+		countryCode = s.cfg.WhatsApp.DefaultCountryCode
+	}
+	vcard, err := whatsapp.ResolveVCard(req.VCard, req.Contact, countryCode)
+	if err != nil {
+		c.JSON(400, gin.H{"valid": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"valid": true, "vcard": vcard})
+}
+
+func (s *Server) handleSendContacts(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	var req whatsapp.ContactsArrayMessageRequest
+	if err := bindBody(c, &req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.CountryCode != "" {
+		req.To = s.waClient.WithCountryCode(req.To, req.CountryCode)
+	}
+
+	resp, err := s.waClient.SendContactsArray(&req)
+	if err == nil {
+		s.waClient.RecordSentMessage(userID, req.To, "contact", req.DisplayName, "", resp)
+	}
+	switch {
+	case errors.Is(err, whatsapp.ErrInvalidVCard), errors.Is(err, whatsapp.ErrInvalidJID):
 		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrRecipientNotAllowed):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, resp)
+	}
+}
+
+// handleSendRaw sends a protobuf JSON message as is. It exposes the raw
+// protocol, so it is admin only and every use is audited.
+func (s *Server) handleSendRaw(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
-	resp, err := s.waClient.SendContactMessage(&req)
+	var req whatsapp.RawMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.CountryCode != "" {
+		req.To = s.waClient.WithCountryCode(req.To, req.CountryCode)
+	}
+
+	resp, err := s.waClient.SendRawMessage(req.To, req.Message)
+	if errors.Is(err, whatsapp.ErrInvalidRawMessage) || errors.Is(err, whatsapp.ErrInvalidJID) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, whatsapp.ErrRecipientNotAllowed) {
+		c.JSON(403, gin.H{"error": err.Error()})
+		return
+	}
+
+	details, _ := json.Marshal(gin.H{"to": req.To, "message": req.Message, "message_id": resp.MessageID})
+	audit := &database.AuditLog{
+		ActorID:    actorID,
+		Action:     "whatsapp.send_raw",
+		TargetType: "message",
+		Details:    string(details),
+		IPAddress:  c.ClientIP(),
+	}
+	if err := s.db.Create(audit).Error; err != nil {
+		logrus.Errorf("Failed to record audit entry whatsapp.send_raw: %v", err)
+	}
+
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -459,6 +1058,61 @@ func (s *Server) handleSendContact(c *gin.Context) {
 	c.JSON(200, resp)
 }
 
+// selfTestInterval is the minimum time between self-tests, so the endpoint
+// can't be used to flood the account's own chat
+const selfTestInterval = time.Minute
+
+// handleSelfTest sends a marker message to the connected account itself to
+// smoke test the whole send path after a deploy
+func (s *Server) handleSelfTest(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	s.selfTestMu.Lock()
+	if wait := selfTestInterval - time.Since(s.lastSelfTest); wait > 0 {
+		s.selfTestMu.Unlock()
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Self-test was run recently, try again later"})
+		return
+	}
+	s.lastSelfTest = time.Now()
+	s.selfTestMu.Unlock()
+
+	result, err := s.waClient.SelfTest()
+
+	outcome := gin.H{"result": result}
+	if err != nil {
+		outcome["error"] = err.Error()
+	}
+	details, _ := json.Marshal(outcome)
+	audit := &database.AuditLog{
+		ActorID:    actorID,
+		Action:     "whatsapp.self_test",
+		TargetType: "whatsapp",
+		Details:    string(details),
+		IPAddress:  c.ClientIP(),
+	}
+	if err := s.db.Create(audit).Error; err != nil {
+		logrus.Errorf("Failed to record audit entry whatsapp.self_test: %v", err)
+	}
+
+	switch {
+	case errors.Is(err, whatsapp.ErrClientNotReady):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success":          false,
+			"error":            err.Error(),
+			"connection_state": s.waClient.ConnectionState(),
+		})
+	case err != nil:
+		c.JSON(502, gin.H{"success": false, "error": err.Error()})
+	default:
+		c.JSON(200, gin.H{"success": true, "result": result})
+	}
+}
+
 func (s *Server) handleGetMessages(c *gin.Context) {
 	// Get current user ID
 	userID, exists := middleware.GetCurrentUserID(c)
@@ -482,6 +1136,11 @@ func (s *Server) handleGetMessages(c *gin.Context) {
 	if msgType := c.Query("type"); msgType != "" {
 		query = query.Where("type = ?", msgType)
 	}
+	if starred := c.Query("starred"); starred == "true" {
+		query = query.Where("is_starred = ?", true)
+	} else if starred == "false" {
+		query = query.Where("is_starred = ?", false)
+	}
 
 	var total int64
 	query.Count(&total)
@@ -493,4 +1152,132 @@ func (s *Server) handleGetMessages(c *gin.Context) {
 		"page":     page,
 		"limit":    limit,
 	})
-}
\ No newline at end of file
+}
+
+func (s *Server) handleEditMessage(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req whatsapp.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	var message database.Message
+	if err := s.db.Where("user_id = ?", userID).First(&message, uint(id)).Error; err != nil {
+		c.JSON(404, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if !message.IsFromMe {
+		c.JSON(400, gin.H{"error": "Only messages sent from this account can be edited"})
+		return
+	}
+
+	if time.Since(message.Timestamp) > whatsapp.EditWindow {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Messages can only be edited within %s of sending", whatsapp.EditWindow)})
+		return
+	}
+
+	resp, err := s.waClient.EditMessage(message.ToJID, message.MessageID, req.Message)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	editedAt := time.Now()
+	if err := s.db.Model(&message).Updates(map[string]interface{}{
+		"content":   req.Message,
+		"is_edited": true,
+		"edited_at": editedAt,
+	}).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update stored message"})
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+// handleGetMessageMedia streams a stored inbound attachment
+func (s *Server) handleGetMessageMedia(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var message database.Message
+	if err := s.db.Where("user_id = ?", userID).First(&message, uint(id)).Error; err != nil {
+		c.JSON(404, gin.H{"error": "Message not found"})
+		return
+	}
+	if message.MediaURL == "" {
+		c.JSON(404, gin.H{"error": "Message has no stored media"})
+		return
+	}
+
+	body, contentType, err := s.waClient.MediaStore().Get(c.Request.Context(), message.MediaURL)
+	if errors.Is(err, media.ErrNotFound) {
+		c.JSON(404, gin.H{"error": "Media not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to read media: %v", err)})
+		return
+	}
+	defer body.Close()
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(200, -1, contentType, body, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", path.Base(message.MediaURL)),
+	})
+}
+
+func (s *Server) handleToggleMessageStar(c *gin.Context) {
+	// Get current user ID
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var message database.Message
+	if err := s.db.Where("user_id = ?", userID).First(&message, uint(id)).Error; err != nil {
+		c.JSON(404, gin.H{"error": "Message not found"})
+		return
+	}
+
+	message.IsStarred = !message.IsStarred
+	if err := s.db.Model(&message).Update("is_starred", message.IsStarred).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to update message"})
+		return
+	}
+
+	c.JSON(200, gin.H{"id": message.ID, "is_starred": message.IsStarred})
+}
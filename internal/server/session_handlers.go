@@ -0,0 +1,92 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSessionBackupSize caps uploaded session backups
+const maxSessionBackupSize = 64 << 20
+
+func (s *Server) handleExportSession(c *gin.Context) {
+	// The passphrase is taken from a header so it never ends up in access logs
+	passphrase := c.GetHeader("X-Session-Passphrase")
+	if passphrase == "" {
+		c.JSON(400, gin.H{"error": "X-Session-Passphrase header is required"})
+		return
+	}
+
+	backup, err := s.waClient.ExportSession(passphrase)
+	if err != nil {
+		if errors.Is(err, whatsapp.ErrWeakPassphrase) || errors.Is(err, whatsapp.ErrSessionNotLinked) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	username, _ := middleware.GetCurrentUsername(c)
+	logrus.Warnf("WhatsApp session exported by %s from %s", username, c.ClientIP())
+
+	filename := fmt.Sprintf("gowa-session-%s.bin", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(200, "application/octet-stream", backup)
+}
+
+func (s *Server) handleImportSession(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSessionBackupSize)
+
+	passphrase := c.PostForm("passphrase")
+	if passphrase == "" {
+		c.JSON(400, gin.H{"error": "passphrase is required"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	backup, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.waClient.ImportSession(backup, passphrase); err != nil {
+		if errors.Is(err, whatsapp.ErrWeakPassphrase) || errors.Is(err, whatsapp.ErrInvalidSession) ||
+			errors.Is(err, whatsapp.ErrSessionDecrypt) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	username, _ := middleware.GetCurrentUsername(c)
+	logrus.Warnf("WhatsApp session imported by %s from %s", username, c.ClientIP())
+
+	c.JSON(200, gin.H{
+		"message":   "Session imported successfully",
+		"logged_in": s.waClient.GetClient().Store.ID != nil,
+	})
+}
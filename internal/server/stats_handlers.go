@@ -9,6 +9,7 @@ import (
 	"gowa-broadcast/internal/middleware"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type DashboardStats struct {
@@ -183,6 +184,19 @@ func (s *Server) getRecentActivity(userID uint) []RecentActivityItem {
 	return activity
 }
 
+// allUsers passed as the user ID aggregates the stats across every user
+const allUsers uint = 0
+
+// forUser limits a stats query to one user's rows, or none for allUsers
+func forUser(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userID == allUsers {
+			return db
+		}
+		return db.Where("user_id = ?", userID)
+	}
+}
+
 func (s *Server) getMessageStats(userID uint) MessageStatsResponse {
 	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
@@ -213,9 +227,9 @@ func (s *Server) getMessageStats(userID uint) MessageStatsResponse {
 func (s *Server) getMessageStatsForPeriod(userID uint, start, end time.Time) MessageStatsPeriod {
 	var stats MessageStatsPeriod
 
-	s.db.Model(&database.Message{}).Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, start, end).Count(&stats.Total)
-	s.db.Model(&database.Message{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND is_from_me = ?", userID, start, end, true).Count(&stats.Sent)
-	s.db.Model(&database.Message{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND is_from_me = ?", userID, start, end, false).Count(&stats.Received)
+	s.db.Model(&database.Message{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ?", start, end).Count(&stats.Total)
+	s.db.Model(&database.Message{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND is_from_me = ?", start, end, true).Count(&stats.Sent)
+	s.db.Model(&database.Message{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND is_from_me = ?", start, end, false).Count(&stats.Received)
 
 	return stats
 }
@@ -233,9 +247,9 @@ func (s *Server) getDailyMessageStats(userID uint, days int) []DailyStats {
 			Date: dayStart.Format("2006-01-02"),
 		}
 
-		s.db.Model(&database.Message{}).Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, dayStart, dayEnd).Count(&dailyStat.Total)
-		s.db.Model(&database.Message{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND is_from_me = ?", userID, dayStart, dayEnd, true).Count(&dailyStat.Sent)
-		s.db.Model(&database.Message{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND is_from_me = ?", userID, dayStart, dayEnd, false).Count(&dailyStat.Received)
+		s.db.Model(&database.Message{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ?", dayStart, dayEnd).Count(&dailyStat.Total)
+		s.db.Model(&database.Message{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND is_from_me = ?", dayStart, dayEnd, true).Count(&dailyStat.Sent)
+		s.db.Model(&database.Message{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND is_from_me = ?", dayStart, dayEnd, false).Count(&dailyStat.Received)
 
 		stats = append(stats, dailyStat)
 	}
@@ -273,10 +287,10 @@ func (s *Server) getBroadcastStats(userID uint) BroadcastStatsResponse {
 func (s *Server) getBroadcastStatsForPeriod(userID uint, start, end time.Time) BroadcastStatsPeriod {
 	var stats BroadcastStatsPeriod
 
-	s.db.Model(&database.BroadcastMessage{}).Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, start, end).Count(&stats.Total)
-	s.db.Model(&database.BroadcastMessage{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND status = ?", userID, start, end, "completed").Count(&stats.Completed)
-	s.db.Model(&database.BroadcastMessage{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND status = ?", userID, start, end, "failed").Count(&stats.Failed)
-	s.db.Model(&database.BroadcastMessage{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND status = ?", userID, start, end, "cancelled").Count(&stats.Cancelled)
+	s.db.Model(&database.BroadcastMessage{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ?", start, end).Count(&stats.Total)
+	s.db.Model(&database.BroadcastMessage{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND status = ?", start, end, "completed").Count(&stats.Completed)
+	s.db.Model(&database.BroadcastMessage{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND status = ?", start, end, "failed").Count(&stats.Failed)
+	s.db.Model(&database.BroadcastMessage{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND status = ?", start, end, "cancelled").Count(&stats.Cancelled)
 
 	// Get total sent and failed counts
 	type SumResult struct {
@@ -284,7 +298,7 @@ func (s *Server) getBroadcastStatsForPeriod(userID uint, start, end time.Time) B
 		TotalFailed int64
 	}
 	var sumResult SumResult
-	s.db.Model(&database.BroadcastMessage{}).Select("COALESCE(SUM(sent_count), 0) as total_sent, COALESCE(SUM(failed_count), 0) as total_failed").Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, start, end).Scan(&sumResult)
+	s.db.Model(&database.BroadcastMessage{}).Select("COALESCE(SUM(sent_count), 0) as total_sent, COALESCE(SUM(failed_count), 0) as total_failed").Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ?", start, end).Scan(&sumResult)
 	stats.TotalSent = sumResult.TotalSent
 	stats.TotalFailed = sumResult.TotalFailed
 
@@ -304,9 +318,9 @@ func (s *Server) getDailyBroadcastStats(userID uint, days int) []DailyBroadcastS
 			Date: dayStart.Format("2006-01-02"),
 		}
 
-		s.db.Model(&database.BroadcastMessage{}).Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, dayStart, dayEnd).Count(&dailyStat.Total)
-		s.db.Model(&database.BroadcastMessage{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND status = ?", userID, dayStart, dayEnd, "completed").Count(&dailyStat.Completed)
-		s.db.Model(&database.BroadcastMessage{}).Where("user_id = ? AND created_at >= ? AND created_at < ? AND status = ?", userID, dayStart, dayEnd, "failed").Count(&dailyStat.Failed)
+		s.db.Model(&database.BroadcastMessage{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ?", dayStart, dayEnd).Count(&dailyStat.Total)
+		s.db.Model(&database.BroadcastMessage{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND status = ?", dayStart, dayEnd, "completed").Count(&dailyStat.Completed)
+		s.db.Model(&database.BroadcastMessage{}).Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ? AND status = ?", dayStart, dayEnd, "failed").Count(&dailyStat.Failed)
 
 		// Get total sent and failed counts for the day
 		type DaySumResult struct {
@@ -314,7 +328,7 @@ func (s *Server) getDailyBroadcastStats(userID uint, days int) []DailyBroadcastS
 			TotalFailed int64
 		}
 		var daySumResult DaySumResult
-		s.db.Model(&database.BroadcastMessage{}).Select("COALESCE(SUM(sent_count), 0) as total_sent, COALESCE(SUM(failed_count), 0) as total_failed").Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, dayStart, dayEnd).Scan(&daySumResult)
+		s.db.Model(&database.BroadcastMessage{}).Select("COALESCE(SUM(sent_count), 0) as total_sent, COALESCE(SUM(failed_count), 0) as total_failed").Scopes(forUser(userID)).Where("created_at >= ? AND created_at < ?", dayStart, dayEnd).Scan(&daySumResult)
 		dailyStat.TotalSent = daySumResult.TotalSent
 		dailyStat.TotalFailed = daySumResult.TotalFailed
 
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleSyncContacts stores WhatsApp's address book as the user's contacts
+func (s *Server) handleSyncContacts(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	result, err := s.waClient.SyncContacts(userID)
+	respondSync(c, "contacts", result, err)
+}
+
+// handleSyncGroups stores the groups the account is a member of for the user
+func (s *Server) handleSyncGroups(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	result, err := s.waClient.SyncGroups(userID)
+	respondSync(c, "groups", result, err)
+}
+
+func respondSync(c *gin.Context, what string, result *whatsapp.SyncResult, err error) {
+	switch {
+	case errors.Is(err, whatsapp.ErrClientNotReady):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": "Failed to sync " + what})
+	default:
+		c.JSON(200, result)
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhookStatsWindow is how far back webhook deliveries count towards the
+// failure rate
+const webhookStatsWindow = 7 * 24 * time.Hour
+
+// SystemStats are the totals across every user of the service
+type SystemStats struct {
+	TotalUsers          int64                  `json:"total_users"`
+	ActiveUsers         int64                  `json:"active_users"`
+	TotalMessages       int64                  `json:"total_messages"`
+	TotalBroadcasts     int64                  `json:"total_broadcasts"`
+	TotalBroadcastLists int64                  `json:"total_broadcast_lists"`
+	ActiveBroadcasts    int                    `json:"active_broadcasts"`
+	PendingScheduled    int64                  `json:"pending_scheduled"`
+	ConnectedDevices    int64                  `json:"connected_devices"`
+	WhatsAppStatus      string                 `json:"whatsapp_status"`
+	Webhooks            WebhookDeliveryStats   `json:"webhooks"`
+	MessageStats        MessageStatsResponse   `json:"message_stats"`
+	BroadcastStats      BroadcastStatsResponse `json:"broadcast_stats"`
+	Users               []UserStats            `json:"users"`
+}
+
+// WebhookDeliveryStats counts webhook deliveries since a point in time, a
+// delivery failed when it errored or got a non-2xx response
+type WebhookDeliveryStats struct {
+	Since       time.Time `json:"since"`
+	Deliveries  int64     `json:"deliveries"`
+	Failed      int64     `json:"failed"`
+	FailureRate float64   `json:"failure_rate"` // 0 to 1
+}
+
+// UserStats is one user's share of the system totals
+type UserStats struct {
+	UserID           uint   `json:"user_id"`
+	Username         string `json:"username"`
+	Active           bool   `json:"active"`
+	Messages         int64  `json:"messages"`
+	Broadcasts       int64  `json:"broadcasts"`
+	BroadcastSent    int64  `json:"broadcast_sent"`
+	BroadcastFailed  int64  `json:"broadcast_failed"`
+	PendingScheduled int64  `json:"pending_scheduled"`
+}
+
+// handleGetSystemStats returns the dashboard totals across all users with a
+// breakdown per user, for operators of a multi-tenant service
+func (s *Server) handleGetSystemStats(c *gin.Context) {
+	stats := &SystemStats{}
+
+	s.db.Model(&database.User{}).Count(&stats.TotalUsers)
+	s.db.Model(&database.User{}).Where("active = ?", true).Count(&stats.ActiveUsers)
+	s.db.Model(&database.Message{}).Count(&stats.TotalMessages)
+	s.db.Model(&database.BroadcastMessage{}).Count(&stats.TotalBroadcasts)
+	s.db.Model(&database.BroadcastList{}).Count(&stats.TotalBroadcastLists)
+	s.db.Model(&database.ScheduledMessage{}).Where("status = ?", "pending").Count(&stats.PendingScheduled)
+	s.db.Model(&database.Device{}).Where("connected = ?", true).Count(&stats.ConnectedDevices)
+
+	stats.ActiveBroadcasts = len(s.broadcastMgr.ListActiveBroadcasts())
+
+	if s.waClient.IsReady() {
+		stats.WhatsAppStatus = "connected"
+	} else {
+		stats.WhatsAppStatus = "disconnected"
+	}
+
+	stats.Webhooks = s.getWebhookDeliveryStats(time.Now().Add(-webhookStatsWindow))
+	stats.MessageStats = s.getMessageStats(allUsers)
+	stats.BroadcastStats = s.getBroadcastStats(allUsers)
+	stats.Users = s.getUserStats()
+
+	c.JSON(200, stats)
+}
+
+func (s *Server) getWebhookDeliveryStats(since time.Time) WebhookDeliveryStats {
+	stats := WebhookDeliveryStats{Since: since}
+
+	s.db.Model(&database.WebhookLog{}).Where("created_at >= ?", since).Count(&stats.Deliveries)
+	s.db.Model(&database.WebhookLog{}).
+		Where("created_at >= ? AND (error <> ? OR status_code < ? OR status_code >= ?)", since, "", 200, 300).
+		Count(&stats.Failed)
+	if stats.Deliveries > 0 {
+		stats.FailureRate = float64(stats.Failed) / float64(stats.Deliveries)
+	}
+
+	return stats
+}
+
+// getUserStats breaks the totals down per user, ordered by user ID
+func (s *Server) getUserStats() []UserStats {
+	var users []database.User
+	s.db.Select("id", "username", "active").Order("id").Find(&users)
+
+	type count struct {
+		UserID uint
+		Total  int64
+	}
+	countsBy := func(model interface{}, where string, args ...interface{}) map[uint]int64 {
+		var rows []count
+		query := s.db.Model(model).Select("user_id, COUNT(*) AS total")
+		if where != "" {
+			query = query.Where(where, args...)
+		}
+		query.Group("user_id").Scan(&rows)
+
+		counts := make(map[uint]int64, len(rows))
+		for _, row := range rows {
+			counts[row.UserID] = row.Total
+		}
+		return counts
+	}
+	messages := countsBy(&database.Message{}, "")
+	broadcasts := countsBy(&database.BroadcastMessage{}, "")
+	pending := countsBy(&database.ScheduledMessage{}, "status = ?", "pending")
+
+	var sums []struct {
+		UserID      uint
+		TotalSent   int64
+		TotalFailed int64
+	}
+	s.db.Model(&database.BroadcastMessage{}).
+		Select("user_id, COALESCE(SUM(sent_count), 0) AS total_sent, COALESCE(SUM(failed_count), 0) AS total_failed").
+		Group("user_id").
+		Scan(&sums)
+
+	stats := make([]UserStats, 0, len(users))
+	index := make(map[uint]int, len(users))
+	for _, user := range users {
+		index[user.ID] = len(stats)
+		stats = append(stats, UserStats{
+			UserID:           user.ID,
+			Username:         user.Username,
+			Active:           user.Active,
+			Messages:         messages[user.ID],
+			Broadcasts:       broadcasts[user.ID],
+			PendingScheduled: pending[user.ID],
+		})
+	}
+	for _, sum := range sums {
+		if i, ok := index[sum.UserID]; ok {
+			stats[i].BroadcastSent = sum.TotalSent
+			stats[i].BroadcastFailed = sum.TotalFailed
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why a single request field failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report fields by their JSON name instead of the Go struct field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindBody decodes a request body as form data when it is sent as a form or
+// multipart upload, and as JSON otherwise. ShouldBind alone falls back to form
+// decoding for a missing or unknown Content-Type, which breaks JSON clients
+// that don't set the header.
+func bindBody(c *gin.Context, obj interface{}) error {
+	switch c.ContentType() {
+	case binding.MIMEPOSTForm, binding.MIMEMultipartPOSTForm:
+		return c.ShouldBind(obj)
+	default:
+		return c.ShouldBindJSON(obj)
+	}
+}
+
+// respondBindError writes a 400 response for a failed ShouldBind call, with
+// field level details when the failure is a validation or type error
+func respondBindError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(413, gin.H{"error": "Request body too large"})
+		return
+	}
+
+	fields := validationErrors(err)
+	if len(fields) == 0 {
+		c.JSON(400, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	c.JSON(400, gin.H{
+		"error":  "Validation failed",
+		"fields": fields,
+	})
+}
+
+// validationErrors translates binding errors into field errors
+func validationErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Message: fieldErrorMessage(fe),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type.String()),
+		}}
+	}
+
+	return nil
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "required_if":
+		return fmt.Sprintf("%s is required when %s", fe.Field(), strings.Replace(fe.Param(), " ", " is ", 1))
+	case "required_without":
+		return fmt.Sprintf("%s or %s is required", fe.Field(), strings.ToLower(fe.Param()))
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", fe.Field())
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", fe.Field())
+	case "min":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must be at least %s characters", fe.Field(), fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must be at most %s characters", fe.Field(), fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", fe.Field(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", fe.Field(), fe.Param())
+	default:
+		return fmt.Sprintf("%s failed the %s validation", fe.Field(), fe.Tag())
+	}
+}
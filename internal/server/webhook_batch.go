@@ -0,0 +1,100 @@
+package server
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"gowa-broadcast/internal/database"
+)
+
+const (
+	// batchEvent is the event recorded for batched deliveries
+	batchEvent = "batch"
+
+	// defaultBatchWindow bounds how long events wait when a webhook only sets
+	// a batch size
+	defaultBatchWindow = time.Second
+)
+
+type webhookBatch struct {
+	webhook  database.Webhook
+	payloads []string
+	timer    *time.Timer
+}
+
+// webhookBatcher buffers events per webhook and hands them to the queue as a
+// single JSON array once the window expires or the batch is full
+type webhookBatcher struct {
+	mu      sync.Mutex
+	batches map[uint]*webhookBatch
+	queue   *webhookQueue
+}
+
+func newWebhookBatcher(queue *webhookQueue) *webhookBatcher {
+	return &webhookBatcher{
+		batches: make(map[uint]*webhookBatch),
+		queue:   queue,
+	}
+}
+
+// batchingEnabled reports whether events for the webhook should be batched
+func batchingEnabled(webhook database.Webhook) bool {
+	return webhook.BatchWindowMS > 0 || webhook.BatchMaxSize > 0
+}
+
+// add buffers one event payload for the webhook
+func (b *webhookBatcher) add(webhook database.Webhook, payload string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[webhook.ID]
+	if !ok {
+		window := time.Duration(webhook.BatchWindowMS) * time.Millisecond
+		if window <= 0 {
+			window = defaultBatchWindow
+		}
+
+		batch = &webhookBatch{}
+		b.batches[webhook.ID] = batch
+		batch.timer = time.AfterFunc(window, func() {
+			b.flush(webhook.ID)
+		})
+	}
+
+	// Use the latest settings for the delivery
+	batch.webhook = webhook
+	batch.payloads = append(batch.payloads, payload)
+
+	if webhook.BatchMaxSize > 0 && len(batch.payloads) >= webhook.BatchMaxSize {
+		batch.timer.Stop()
+		delete(b.batches, webhook.ID)
+		go b.deliver(batch)
+	}
+}
+
+// flush delivers whatever is buffered for the webhook
+func (b *webhookBatcher) flush(webhookID uint) {
+	b.mu.Lock()
+	batch, ok := b.batches[webhookID]
+	delete(b.batches, webhookID)
+	b.mu.Unlock()
+
+	if ok {
+		b.deliver(batch)
+	}
+}
+
+// deliver queues the batch as a JSON array of events
+func (b *webhookBatcher) deliver(batch *webhookBatch) {
+	if len(batch.payloads) == 0 {
+		return
+	}
+
+	payload := "[" + strings.Join(batch.payloads, ",") + "]"
+	b.queue.enqueue(webhookJob{
+		webhook: batch.webhook,
+		payload: payload,
+		event:   batchEvent,
+	})
+}
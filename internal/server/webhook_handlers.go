@@ -8,7 +8,10 @@ import (
 	"strconv"
 	"time"
 
+	"gowa-broadcast/internal/broadcast"
 	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/middleware"
+	"gowa-broadcast/internal/netguard"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +21,14 @@ type WebhookRequest struct {
 	Secret  string            `json:"secret"`
 	Events  []string          `json:"events"`
 	Headers map[string]string `json:"headers"`
+
+	// Deliver events as a JSON array, flushed after BatchWindowMS or once
+	// BatchMaxSize events are buffered. Zero for both sends each event alone.
+	BatchWindowMS int `json:"batch_window_ms" binding:"min=0,max=300000"`
+	BatchMaxSize  int `json:"batch_max_size" binding:"min=0,max=1000"`
+
+	// Go text/template over the event JSON, e.g. {"text": {{json .data.content}}}
+	PayloadTemplate string `json:"payload_template"`
 }
 
 type WebhookResponse struct {
@@ -29,6 +40,15 @@ type WebhookResponse struct {
 	Active    bool              `json:"active"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
+
+	BatchWindowMS   int    `json:"batch_window_ms"`
+	BatchMaxSize    int    `json:"batch_max_size"`
+	PayloadTemplate string `json:"payload_template,omitempty"`
+}
+
+type WebhookReplayRequest struct {
+	From string `json:"from" binding:"required"` // RFC3339 format
+	To   string `json:"to" binding:"required"`   // RFC3339 format
 }
 
 type WebhookLogResponse struct {
@@ -39,6 +59,7 @@ type WebhookLogResponse struct {
 	StatusCode   int       `json:"status_code"`
 	ResponseBody string    `json:"response_body"`
 	Error        string    `json:"error"`
+	IsReplay     bool      `json:"is_replay"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -56,6 +77,10 @@ type MessageWebhookData struct {
 	Content   string `json:"content"`
 	IsFromMe  bool   `json:"is_from_me"`
 	Timestamp int64  `json:"timestamp"`
+
+	// Set when the message is a reply
+	QuotedMessageID string `json:"quoted_message_id,omitempty"`
+	QuotedContent   string `json:"quoted_content,omitempty"`
 }
 
 type BroadcastWebhookData struct {
@@ -70,24 +95,27 @@ type BroadcastWebhookData struct {
 func (s *Server) handleCreateWebhook(c *gin.Context) {
 	var req WebhookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	// Validate events
-	validEvents := map[string]bool{
-		"message.received": true,
-		"message.sent":     true,
-		"broadcast.start":  true,
-		"broadcast.end":    true,
-		"connection":       true,
-	}
-
 	for _, event := range req.Events {
-		if !validEvents[event] {
+		if !validWebhookEvents[event] {
 			c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid event: %s", event)})
 			return
 		}
+		if event == "raw" && !middleware.IsAdmin(c) {
+			c.JSON(403, gin.H{"error": "Only admins can subscribe to raw events"})
+			return
+		}
+	}
+
+	if req.PayloadTemplate != "" {
+		if _, err := parsePayloadTemplate(req.PayloadTemplate); err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid payload template: %v", err)})
+			return
+		}
 	}
 
 	// Convert events to JSON
@@ -95,11 +123,14 @@ func (s *Server) handleCreateWebhook(c *gin.Context) {
 	headersJSON, _ := json.Marshal(req.Headers)
 
 	webhook := database.Webhook{
-		URL:     req.URL,
-		Secret:  req.Secret,
-		Events:  string(eventsJSON),
-		Headers: string(headersJSON),
-		Active:  true,
+		URL:             req.URL,
+		Secret:          req.Secret,
+		Events:          string(eventsJSON),
+		Headers:         string(headersJSON),
+		Active:          true,
+		BatchWindowMS:   req.BatchWindowMS,
+		BatchMaxSize:    req.BatchMaxSize,
+		PayloadTemplate: req.PayloadTemplate,
 	}
 
 	if err := s.db.Create(&webhook).Error; err != nil {
@@ -116,6 +147,10 @@ func (s *Server) handleCreateWebhook(c *gin.Context) {
 		Active:    webhook.Active,
 		CreatedAt: webhook.CreatedAt,
 		UpdatedAt: webhook.UpdatedAt,
+
+		BatchWindowMS:   webhook.BatchWindowMS,
+		BatchMaxSize:    webhook.BatchMaxSize,
+		PayloadTemplate: webhook.PayloadTemplate,
 	}
 
 	c.JSON(201, response)
@@ -143,6 +178,10 @@ func (s *Server) handleGetWebhooks(c *gin.Context) {
 			Active:    webhook.Active,
 			CreatedAt: webhook.CreatedAt,
 			UpdatedAt: webhook.UpdatedAt,
+
+			BatchWindowMS:   webhook.BatchWindowMS,
+			BatchMaxSize:    webhook.BatchMaxSize,
+			PayloadTemplate: webhook.PayloadTemplate,
 		}
 	}
 
@@ -176,6 +215,10 @@ func (s *Server) handleGetWebhook(c *gin.Context) {
 		Active:    webhook.Active,
 		CreatedAt: webhook.CreatedAt,
 		UpdatedAt: webhook.UpdatedAt,
+
+		BatchWindowMS:   webhook.BatchWindowMS,
+		BatchMaxSize:    webhook.BatchMaxSize,
+		PayloadTemplate: webhook.PayloadTemplate,
 	}
 
 	c.JSON(200, response)
@@ -196,24 +239,27 @@ func (s *Server) handleUpdateWebhook(c *gin.Context) {
 
 	var req WebhookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
 	// Validate events
-	validEvents := map[string]bool{
-		"message.received": true,
-		"message.sent":     true,
-		"broadcast.start":  true,
-		"broadcast.end":    true,
-		"connection":       true,
-	}
-
 	for _, event := range req.Events {
-		if !validEvents[event] {
+		if !validWebhookEvents[event] {
 			c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid event: %s", event)})
 			return
 		}
+		if event == "raw" && !middleware.IsAdmin(c) {
+			c.JSON(403, gin.H{"error": "Only admins can subscribe to raw events"})
+			return
+		}
+	}
+
+	if req.PayloadTemplate != "" {
+		if _, err := parsePayloadTemplate(req.PayloadTemplate); err != nil {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid payload template: %v", err)})
+			return
+		}
 	}
 
 	// Convert events to JSON
@@ -224,6 +270,9 @@ func (s *Server) handleUpdateWebhook(c *gin.Context) {
 	webhook.Secret = req.Secret
 	webhook.Events = string(eventsJSON)
 	webhook.Headers = string(headersJSON)
+	webhook.BatchWindowMS = req.BatchWindowMS
+	webhook.BatchMaxSize = req.BatchMaxSize
+	webhook.PayloadTemplate = req.PayloadTemplate
 
 	if err := s.db.Save(&webhook).Error; err != nil {
 		c.JSON(500, gin.H{"error": "Failed to update webhook"})
@@ -239,6 +288,10 @@ func (s *Server) handleUpdateWebhook(c *gin.Context) {
 		Active:    webhook.Active,
 		CreatedAt: webhook.CreatedAt,
 		UpdatedAt: webhook.UpdatedAt,
+
+		BatchWindowMS:   webhook.BatchWindowMS,
+		BatchMaxSize:    webhook.BatchMaxSize,
+		PayloadTemplate: webhook.PayloadTemplate,
 	}
 
 	c.JSON(200, response)
@@ -314,6 +367,87 @@ func (s *Server) handleGetWebhookLogs(c *gin.Context) {
 	var total int64
 	s.db.Model(&database.WebhookLog{}).Where("webhook_id = ?", uint(id)).Count(&total)
 
+	c.JSON(200, gin.H{
+		"logs":   toWebhookLogResponses(logs),
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// handleGetAllWebhookLogs lists recent deliveries across every webhook so
+// failures can be triaged in one place. Supports event, status_min,
+// status_max and has_error filters. The payloads span every user's messages
+// and broadcast callbacks, so it is admin only.
+func (s *Server) handleGetAllWebhookLogs(c *gin.Context) {
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	query := s.db.Model(&database.WebhookLog{})
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+	if v := c.Query("status_min"); v != "" {
+		statusMin, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid status_min"})
+			return
+		}
+		query = query.Where("status_code >= ?", statusMin)
+	}
+	if v := c.Query("status_max"); v != "" {
+		statusMax, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid status_max"})
+			return
+		}
+		query = query.Where("status_code <= ?", statusMax)
+	}
+	if v := c.Query("has_error"); v != "" {
+		hasError, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid has_error, use true or false"})
+			return
+		}
+		if hasError {
+			query = query.Where("error <> ''")
+		} else {
+			query = query.Where("error = '' OR error IS NULL")
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to count webhook logs"})
+		return
+	}
+
+	var logs []database.WebhookLog
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&logs).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to get webhook logs"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"logs":   toWebhookLogResponses(logs),
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func toWebhookLogResponses(logs []database.WebhookLog) []WebhookLogResponse {
 	response := make([]WebhookLogResponse, len(logs))
 	for i, log := range logs {
 		response[i] = WebhookLogResponse{
@@ -324,18 +458,96 @@ func (s *Server) handleGetWebhookLogs(c *gin.Context) {
 			StatusCode:   log.StatusCode,
 			ResponseBody: log.ResponseBody,
 			Error:        log.Error,
+			IsReplay:     log.IsReplay,
 			CreatedAt:    log.CreatedAt,
 		}
 	}
+	return response
+}
 
-	c.JSON(200, gin.H{
-		"logs":   response,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
+// webhookReplayInterval spaces out replayed deliveries so the receiver isn't flooded
+const webhookReplayInterval = 500 * time.Millisecond
+
+func (s *Server) handleReplayWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var req WebhookReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, req.From)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid from format. Use RFC3339 format"})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, req.To)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid to format. Use RFC3339 format"})
+		return
+	}
+	if !from.Before(to) {
+		c.JSON(400, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	var webhook database.Webhook
+	if err := s.db.First(&webhook, uint(id)).Error; err != nil {
+		c.JSON(404, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	// Only replay original deliveries, not earlier replays of them
+	var logs []database.WebhookLog
+	if err := s.db.Where("webhook_id = ? AND is_replay = ? AND created_at >= ? AND created_at < ?", webhook.ID, false, from, to).
+		Order("created_at ASC").Find(&logs).Error; err != nil {
+		c.JSON(500, gin.H{"error": "Failed to get webhook logs"})
+		return
+	}
+
+	go func() {
+		for i, log := range logs {
+			if i > 0 {
+				time.Sleep(webhookReplayInterval)
+			}
+			s.deliverWebhook(webhook, log.Payload, log.Event, true)
+		}
+	}()
+
+	c.JSON(202, gin.H{
+		"message":  "Webhook replay started",
+		"replayed": len(logs),
+		"from":     from,
+		"to":       to,
 	})
 }
 
+// handleGetWebhookQueueStats reports the webhook delivery queue depth
+func (s *Server) handleGetWebhookQueueStats(c *gin.Context) {
+	c.JSON(200, s.webhookQueue.stats())
+}
+
+// validWebhookEvents are the events a webhook can subscribe to
+var validWebhookEvents = map[string]bool{
+	"message.received":  true,
+	"message.sent":      true,
+	"message.delivered": true,
+	"message.read":      true,
+	"broadcast.start":   true,
+	"broadcast.end":     true,
+	"connection":        true,
+	"scheduled.created": true,
+	"scheduled.sent":    true,
+	"scheduled.failed":  true,
+	"scheduled.missed":  true,
+	"raw":               true, // Every whatsmeow event, needs WEBHOOK_RAW_EVENTS
+}
+
 // SendWebhook sends webhook event to all active webhooks
 func (s *Server) SendWebhook(event string, data interface{}) {
 	var webhooks []database.Webhook
@@ -373,25 +585,95 @@ func (s *Server) SendWebhook(event string, data interface{}) {
 			continue
 		}
 
-		// Send webhook in goroutine
-		go s.sendWebhookRequest(webhook, string(payload), event)
+		body := string(payload)
+		if webhook.PayloadTemplate != "" {
+			rendered, err := renderPayload(webhook.PayloadTemplate, body)
+			if err != nil {
+				s.logWebhookError(webhook.ID, event, body, 0, "", fmt.Sprintf("payload template: %v", err), false)
+				continue
+			}
+			body = rendered
+		}
+
+		if batchingEnabled(webhook) {
+			s.webhookBatcher.add(webhook, body)
+			continue
+		}
+
+		// Queue delivery for the webhook workers
+		s.webhookQueue.enqueue(webhookJob{
+			webhook: webhook,
+			payload: body,
+			event:   event,
+		})
+	}
+}
+
+// notifyBroadcast sends broadcast lifecycle events to the subscribed webhooks
+// and to the callback URL the broadcast was created with, if any. Progress
+// events are only requested per broadcast so they only go to the callback.
+func (s *Server) notifyBroadcast(event string, data interface{}) {
+	if event != "broadcast.progress" {
+		s.SendWebhook(event, data)
+	}
+
+	broadcastEvent, ok := data.(broadcast.Event)
+	if !ok || broadcastEvent.CallbackURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(WebhookEvent{
+		Event:     event,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return
+	}
+
+	// The callback goes through the same queue as a webhook without a row of
+	// its own, so its deliveries are logged with webhook_id 0
+	callback := database.Webhook{URL: broadcastEvent.CallbackURL}
+	if broadcastEvent.CallbackSecret != "" {
+		headers, _ := json.Marshal(map[string]string{
+			broadcast.CallbackSignatureHeader: broadcast.SignCallback(broadcastEvent.CallbackSecret, string(payload)),
+		})
+		callback.Headers = string(headers)
 	}
+	s.webhookQueue.enqueue(webhookJob{
+		webhook: callback,
+		payload: string(payload),
+		event:   event,
+	})
 }
 
 func (s *Server) sendWebhookRequest(webhook database.Webhook, payload, event string) {
+	s.deliverWebhook(webhook, payload, event, false)
+}
+
+// deliverWebhook posts the payload to the webhook and records the outcome
+func (s *Server) deliverWebhook(webhook database.Webhook, payload, event string, replay bool) {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
+	if webhook.ID == 0 {
+		// Broadcast callback URLs come from users rather than admins, keep
+		// them off the server's own network
+		client = netguard.Client(30 * time.Second)
+	}
 
 	req, err := http.NewRequest("POST", webhook.URL, bytes.NewBufferString(payload))
 	if err != nil {
-		s.logWebhookError(webhook.ID, event, payload, 0, "", err.Error())
+		s.logWebhookError(webhook.ID, event, payload, 0, "", err.Error(), replay)
 		return
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "GOWA-Broadcast-Webhook/1.0")
+	if replay {
+		req.Header.Set("X-Webhook-Replay", "true")
+	}
 
 	// Add custom headers
 	var headers map[string]string
@@ -410,7 +692,7 @@ func (s *Server) sendWebhookRequest(webhook database.Webhook, payload, event str
 
 	resp, err := client.Do(req)
 	if err != nil {
-		s.logWebhookError(webhook.ID, event, payload, 0, "", err.Error())
+		s.logWebhookError(webhook.ID, event, payload, 0, "", err.Error(), replay)
 		return
 	}
 	defer resp.Body.Close()
@@ -427,6 +709,7 @@ func (s *Server) sendWebhookRequest(webhook database.Webhook, payload, event str
 		Payload:      payload,
 		StatusCode:   resp.StatusCode,
 		ResponseBody: respBodyStr,
+		IsReplay:     replay,
 	}
 
 	if resp.StatusCode >= 400 {
@@ -436,7 +719,7 @@ func (s *Server) sendWebhookRequest(webhook database.Webhook, payload, event str
 	s.db.Create(&log)
 }
 
-func (s *Server) logWebhookError(webhookID uint, event, payload string, statusCode int, responseBody, errorMsg string) {
+func (s *Server) logWebhookError(webhookID uint, event, payload string, statusCode int, responseBody, errorMsg string, replay bool) {
 	log := database.WebhookLog{
 		WebhookID:    webhookID,
 		Event:        event,
@@ -444,6 +727,7 @@ func (s *Server) logWebhookError(webhookID uint, event, payload string, statusCo
 		StatusCode:   statusCode,
 		ResponseBody: responseBody,
 		Error:        errorMsg,
+		IsReplay:     replay,
 	}
 	s.db.Create(&log)
-}
\ No newline at end of file
+}
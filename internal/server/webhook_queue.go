@@ -0,0 +1,90 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+type webhookJob struct {
+	webhook database.Webhook
+	payload string
+	event   string
+}
+
+// webhookQueue delivers webhooks with a fixed number of workers so bursts of
+// events can't spawn an unbounded number of goroutines
+type webhookQueue struct {
+	jobs     chan webhookJob
+	drop     func(job webhookJob)
+	workers  int
+	inFlight int64
+	dropped  int64
+}
+
+// WebhookQueueStats describes the current load of the webhook queue
+type WebhookQueueStats struct {
+	Queued   int   `json:"queued"`
+	Capacity int   `json:"capacity"`
+	InFlight int64 `json:"in_flight"`
+	Workers  int   `json:"workers"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// newWebhookQueue starts workers that pass jobs to deliver. drop is called
+// for every job that didn't fit in the queue.
+func newWebhookQueue(size, workers int, deliver, drop func(job webhookJob)) *webhookQueue {
+	if size < 1 {
+		size = 1
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	q := &webhookQueue{
+		jobs:    make(chan webhookJob, size),
+		drop:    drop,
+		workers: workers,
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range q.jobs {
+				atomic.AddInt64(&q.inFlight, 1)
+				deliver(job)
+				atomic.AddInt64(&q.inFlight, -1)
+			}
+		}()
+	}
+
+	return q
+}
+
+// enqueue queues a delivery without blocking, the caller is often the
+// whatsmeow event handler. A full queue drops it, counts the drop and hands
+// it to drop.
+func (q *webhookQueue) enqueue(job webhookJob) bool {
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		atomic.AddInt64(&q.dropped, 1)
+		logrus.Warnf("Webhook queue full, dropping %s delivery to webhook %d", job.event, job.webhook.ID)
+		if q.drop != nil {
+			q.drop(job)
+		}
+		return false
+	}
+}
+
+func (q *webhookQueue) stats() WebhookQueueStats {
+	return WebhookQueueStats{
+		Queued:   len(q.jobs),
+		Capacity: cap(q.jobs),
+		InFlight: atomic.LoadInt64(&q.inFlight),
+		Workers:  q.workers,
+		Dropped:  atomic.LoadInt64(&q.dropped),
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+)
+
+// payloadTemplateFuncs are available in webhook payload templates
+var payloadTemplateFuncs = template.FuncMap{
+	// json encodes a value, e.g. {{json .data.content}} for a quoted string
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+// parsePayloadTemplate compiles a webhook payload template
+func parsePayloadTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(payloadTemplateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// renderPayload applies the webhook's template to an event payload. The
+// template sees the event as its JSON fields, e.g. {{.event}} and
+// {{.data.message_id}}.
+func renderPayload(text, payload string) (string, error) {
+	tmpl, err := parsePayloadTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, event); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}
@@ -0,0 +1,183 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+const (
+	// MinAlbumItems and MaxAlbumItems bound the number of items in an album
+	MinAlbumItems = 2
+	MaxAlbumItems = 30
+)
+
+// ErrPartialAlbum is returned when an album failed after some items were
+// already delivered. It is never transient so the album isn't resent twice.
+var ErrPartialAlbum = errors.New("album partially sent")
+
+// AlbumItem is one image or video of an album
+type AlbumItem struct {
+	Type     string `json:"type" binding:"required,oneof=image video"`
+	MediaURL string `json:"media_url" binding:"required"`
+	Caption  string `json:"caption,omitempty"`
+}
+
+type AlbumMessageRequest struct {
+	To          string      `json:"to" binding:"required"`
+	Items       []AlbumItem `json:"items" binding:"required,dive"`
+	CountryCode string      `json:"country_code,omitempty"`
+}
+
+// ValidateAlbum checks the item count and that every item is an image or video
+func ValidateAlbum(items []AlbumItem) error {
+	if len(items) < MinAlbumItems || len(items) > MaxAlbumItems {
+		return fmt.Errorf("album must have between %d and %d items", MinAlbumItems, MaxAlbumItems)
+	}
+	for i, item := range items {
+		switch strings.ToLower(item.Type) {
+		case "image", "video":
+		default:
+			return fmt.Errorf("album item %d: only image and video are allowed, got %q", i, item.Type)
+		}
+		if item.MediaURL == "" {
+			return fmt.Errorf("album item %d: media_url is required", i)
+		}
+	}
+	return nil
+}
+
+// UploadAlbum uploads every album item once so the album can be sent to any
+// number of recipients
+func (c *Client) UploadAlbum(items []AlbumItem) ([]*UploadedMedia, error) {
+	if err := ValidateAlbum(items); err != nil {
+		return nil, err
+	}
+
+	media := make([]*UploadedMedia, 0, len(items))
+	for i, item := range items {
+		uploaded, err := c.UploadMedia(item.MediaURL, item.Type)
+		if err != nil {
+			return nil, fmt.Errorf("album item %d: %w", i, err)
+		}
+		media = append(media, uploaded)
+	}
+	return media, nil
+}
+
+// SendAlbum uploads and sends an album to one recipient
+func (c *Client) SendAlbum(req *AlbumMessageRequest) (*MessageResponse, error) {
+	if !c.IsReady() {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "WhatsApp client not ready",
+			Timestamp: time.Now().Unix(),
+		}, ErrClientNotReady
+	}
+
+	jid, err := c.parseJID(req.To, "")
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Invalid JID: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	media, err := c.UploadAlbum(req.Items)
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now().Unix(),
+		}, err
+	}
+
+	captions := make([]string, len(req.Items))
+	for i, item := range req.Items {
+		captions[i] = item.Caption
+	}
+
+	return c.sendUploadedAlbum(jid, media, captions, c.defaultRetry())
+}
+
+// SendUploadedAlbum sends an album previously uploaded with UploadAlbum
+func (c *Client) SendUploadedAlbum(to string, media []*UploadedMedia, captions []string) (*MessageResponse, error) {
+	return c.SendUploadedAlbumWithRetry(to, media, captions, c.defaultRetry())
+}
+
+// SendUploadedAlbumWithRetry is SendUploadedAlbum retrying transient failures
+// of each item as retry says instead of WHATSAPP_SEND_RETRY_*
+func (c *Client) SendUploadedAlbumWithRetry(to string, media []*UploadedMedia, captions []string, retry RetryPolicy) (*MessageResponse, error) {
+	if !c.IsReady() {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "WhatsApp client not ready",
+			Timestamp: time.Now().Unix(),
+		}, ErrClientNotReady
+	}
+
+	jid, err := c.parseJID(to, "")
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Invalid JID: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	return c.sendUploadedAlbum(jid, media, captions, retry)
+}
+
+// sendUploadedAlbum sends the items back to back. WhatsApp has no album
+// message in this protocol version; clients group consecutive images and
+// videos from the same sender into an album on their own.
+func (c *Client) sendUploadedAlbum(jid types.JID, media []*UploadedMedia, captions []string, retry RetryPolicy) (*MessageResponse, error) {
+	if len(media) == 0 {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "Album has no items",
+			Timestamp: time.Now().Unix(),
+		}, errors.New("album has no items")
+	}
+
+	messageIDs := make([]string, 0, len(media))
+	var timestamp int64
+	attempts := 0
+
+	for i, item := range media {
+		caption := ""
+		if i < len(captions) {
+			caption = captions[i]
+		}
+
+		resp, err := c.sendUploadedMedia(jid, item, caption, "", retry)
+		attempts += resp.Attempts
+		if err != nil {
+			if len(messageIDs) > 0 {
+				err = fmt.Errorf("%w (%d of %d items): %v", ErrPartialAlbum, len(messageIDs), len(media), err)
+			}
+			return &MessageResponse{
+				Success:    false,
+				MessageIDs: messageIDs,
+				Error:      err.Error(),
+				Timestamp:  time.Now().Unix(),
+				Attempts:   attempts,
+			}, err
+		}
+
+		messageIDs = append(messageIDs, resp.MessageID)
+		timestamp = resp.Timestamp
+	}
+
+	return &MessageResponse{
+		Success:    true,
+		MessageID:  messageIDs[0],
+		MessageIDs: messageIDs,
+		Timestamp:  timestamp,
+		Attempts:   attempts,
+	}, nil
+}
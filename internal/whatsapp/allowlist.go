@@ -0,0 +1,54 @@
+package whatsapp
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ErrRecipientNotAllowed is returned when WHATSAPP_RECIPIENT_ALLOWLIST is set
+// and the recipient matches none of its patterns
+var ErrRecipientNotAllowed = errors.New("recipient not allowed")
+
+// RecipientAllowed checks a recipient against WHATSAPP_RECIPIENT_ALLOWLIST,
+// an empty allowlist allows everyone
+func (c *Client) RecipientAllowed(jid types.JID) bool {
+	return MatchRecipient(c.cfg.WhatsApp.ParseRecipientAllowlist(), jid)
+}
+
+// RecipientJIDAllowed is RecipientAllowed for a JID in string form, as
+// returned by NormalizeJID. JIDs that can't be parsed aren't allowed.
+func (c *Client) RecipientJIDAllowed(jid string) bool {
+	parsed, err := types.ParseJID(jid)
+	if err != nil {
+		return false
+	}
+	return c.RecipientAllowed(parsed)
+}
+
+// MatchRecipient reports whether jid matches one of the patterns, or true when
+// there are none. Patterns are globs: one containing "@" is matched against
+// the full JID ("*@g.us" allows every group), any other against the number
+// alone ("62812*" allows numbers by prefix). A "+" in front of a number is
+// ignored. Invalid patterns match nothing.
+func MatchRecipient(patterns []string, jid types.JID) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	full := jid.ToNonAD().String()
+	for _, pattern := range patterns {
+		subject := jid.User
+		if strings.Contains(pattern, "@") {
+			subject = full
+		} else {
+			pattern = strings.TrimPrefix(pattern, "+")
+		}
+		if ok, err := path.Match(pattern, subject); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestMatchRecipient(t *testing.T) {
+	user := types.NewJID("628123456789", types.DefaultUserServer)
+	device := types.JID{User: "628123456789", Device: 3, Server: types.DefaultUserServer}
+	group := types.NewJID("120363000000000001", types.GroupServer)
+
+	tests := []struct {
+		name     string
+		patterns []string
+		jid      types.JID
+		want     bool
+	}{
+		{name: "empty allowlist allows everyone", patterns: nil, jid: user, want: true},
+		{name: "exact number", patterns: []string{"628123456789"}, jid: user, want: true},
+		{name: "number with plus", patterns: []string{"+628123456789"}, jid: user, want: true},
+		{name: "number prefix", patterns: []string{"62812*"}, jid: user, want: true},
+		{name: "other prefix", patterns: []string{"62813*"}, jid: user, want: false},
+		{name: "full JID", patterns: []string{"628123456789@s.whatsapp.net"}, jid: user, want: true},
+		{name: "device is ignored", patterns: []string{"628123456789@s.whatsapp.net"}, jid: device, want: true},
+		{name: "all groups", patterns: []string{"*@g.us"}, jid: group, want: true},
+		{name: "groups pattern doesn't match users", patterns: []string{"*@g.us"}, jid: user, want: false},
+		{name: "number pattern doesn't match groups", patterns: []string{"62812*"}, jid: group, want: false},
+		{name: "status broadcast", patterns: []string{"status@broadcast"}, jid: types.StatusBroadcastJID, want: true},
+		{name: "any pattern matches", patterns: []string{"62813*", "*@g.us", "62812*"}, jid: user, want: true},
+		{name: "invalid pattern matches nothing", patterns: []string{"[62812"}, jid: user, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchRecipient(tt.patterns, tt.jid); got != tt.want {
+				t.Errorf("MatchRecipient(%q, %s) = %v, want %v", tt.patterns, tt.jid, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package whatsapp
+
+import (
+	"errors"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"gorm.io/gorm"
+)
+
+// DefaultAckWindow is how long after a broadcast started replies are
+// acknowledged when the broadcast doesn't set its own window
+const DefaultAckWindow = 24 * time.Hour
+
+// acknowledgeBroadcastReply answers the first reply of a broadcast recipient
+// with the broadcast's acknowledgment, a text, a reaction or both. The reply
+// is tied to the most recent broadcast that was sent to the sender and asks
+// for acknowledgments, and only counts while that broadcast's window since
+// StartedAt is open. Reactions, edits and other protocol messages aren't
+// replies. A sender is acknowledged at most once per window, even when they
+// got several broadcasts.
+func (c *Client) acknowledgeBroadcastReply(evt *events.Message) {
+	if evt.Info.IsGroup || evt.Info.Chat.Server == types.BroadcastServer || !hasContent(evt.Message) {
+		return
+	}
+
+	sender := evt.Info.Sender.ToNonAD()
+	senderJIDs := []string{sender.String(), sender.User, "+" + sender.User}
+	var match struct {
+		DeliveryID   uint
+		StartedAt    time.Time
+		AckMessage   string
+		AckReaction  string
+		AckWindowMin int
+	}
+	err := c.db.Model(&database.BroadcastDelivery{}).
+		Select("broadcast_deliveries.id AS delivery_id, broadcast_messages.started_at, "+
+			"broadcast_messages.ack_message, broadcast_messages.ack_reaction, broadcast_messages.ack_window_min").
+		Joins("JOIN broadcast_messages ON broadcast_messages.id = broadcast_deliveries.broadcast_message_id").
+		Where("broadcast_deliveries.jid IN ? AND broadcast_deliveries.status = ? AND broadcast_deliveries.acked_at IS NULL",
+			senderJIDs, "sent").
+		Where("(broadcast_messages.ack_message <> '' OR broadcast_messages.ack_reaction <> '') AND broadcast_messages.started_at <= ?",
+			evt.Info.Timestamp).
+		Order("broadcast_messages.started_at DESC").
+		Take(&match).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Errorf("Failed to look up broadcast of reply %s: %v", evt.Info.ID, err)
+		}
+		return
+	}
+
+	window := DefaultAckWindow
+	if match.AckWindowMin > 0 {
+		window = time.Duration(match.AckWindowMin) * time.Minute
+	}
+	if evt.Info.Timestamp.After(match.StartedAt.Add(window)) {
+		return
+	}
+
+	// Claim the delivery first so a redelivered or concurrent reply can't
+	// trigger a second acknowledgment, unless the sender was acknowledged for
+	// another broadcast within the window
+	now := time.Now()
+	acked := c.db.Model(&database.BroadcastDelivery{}).Select("1").
+		Where("jid IN ? AND acked_at > ?", senderJIDs, now.Add(-window))
+	result := c.db.Model(&database.BroadcastDelivery{}).
+		Where("id = ? AND acked_at IS NULL AND NOT EXISTS (?)", match.DeliveryID, acked).
+		Update("acked_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	if match.AckReaction != "" {
+		reaction := c.GetClient().BuildReaction(evt.Info.Chat, evt.Info.Sender, evt.Info.ID, match.AckReaction)
+		if _, err := c.sendWithRetry(evt.Info.Chat, reaction); err != nil {
+			logrus.Warnf("Failed to react to broadcast reply %s: %v", evt.Info.ID, err)
+		}
+	}
+	if match.AckMessage != "" {
+		if _, err := c.SendTextMessage(evt.Info.Chat.String(), match.AckMessage); err != nil {
+			logrus.Warnf("Failed to acknowledge broadcast reply %s: %v", evt.Info.ID, err)
+		}
+	}
+}
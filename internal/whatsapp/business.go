@@ -0,0 +1,101 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// BusinessProfile is what WhatsApp shares about a business account.
+// IsBusiness is false, with the other fields empty, for regular accounts.
+type BusinessProfile struct {
+	JID            string            `json:"jid"`
+	IsBusiness     bool              `json:"is_business"`
+	Categories     []string          `json:"categories,omitempty"`
+	Email          string            `json:"email,omitempty"`
+	Address        string            `json:"address,omitempty"`
+	HoursTimezone  string            `json:"hours_timezone,omitempty"`
+	ProfileOptions map[string]string `json:"profile_options,omitempty"`
+	FetchedAt      time.Time         `json:"fetched_at"`
+}
+
+// GetBusinessProfile returns a contact's business profile. Profiles are
+// cached for WHATSAPP_BUSINESS_CACHE_SEC and stored on matching contacts.
+func (c *Client) GetBusinessProfile(jid string) (*BusinessProfile, error) {
+	targetJID, err := c.parseJID(jid, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+	key := targetJID.String()
+
+	ttl := time.Duration(c.cfg.WhatsApp.BusinessCacheSec) * time.Second
+	c.businessMu.Lock()
+	cached, ok := c.businessProfiles[key]
+	c.businessMu.Unlock()
+	if ok && time.Since(cached.FetchedAt) < ttl {
+		copied := *cached
+		return &copied, nil
+	}
+
+	if !c.IsReady() {
+		return nil, ErrClientNotReady
+	}
+
+	info, err := c.GetClient().GetBusinessProfile(targetJID)
+	var missing *whatsmeow.ElementMissingError
+	if err != nil && !errors.As(err, &missing) {
+		return nil, fmt.Errorf("failed to get business profile: %v", err)
+	}
+
+	profile := &BusinessProfile{JID: key, FetchedAt: time.Now()}
+	if err == nil && info != nil {
+		fillBusinessProfile(profile, info)
+	}
+
+	c.businessMu.Lock()
+	if c.businessProfiles == nil {
+		c.businessProfiles = make(map[string]*BusinessProfile)
+	}
+	c.businessProfiles[key] = profile
+	c.businessMu.Unlock()
+
+	c.storeBusinessProfile(profile)
+
+	copied := *profile
+	return &copied, nil
+}
+
+func fillBusinessProfile(profile *BusinessProfile, info *types.BusinessProfile) {
+	profile.IsBusiness = true
+	profile.Email = info.Email
+	profile.Address = info.Address
+	profile.HoursTimezone = info.BusinessHoursTimeZone
+	profile.ProfileOptions = info.ProfileOptions
+	for _, category := range info.Categories {
+		profile.Categories = append(profile.Categories, category.Name)
+	}
+}
+
+// storeBusinessProfile saves the profile on every contact with its JID
+func (c *Client) storeBusinessProfile(profile *BusinessProfile) {
+	updates := map[string]interface{}{
+		"is_business":         profile.IsBusiness,
+		"business_profile":    "",
+		"business_checked_at": profile.FetchedAt,
+	}
+	if profile.IsBusiness {
+		encoded, _ := json.Marshal(profile)
+		updates["business_profile"] = string(encoded)
+	}
+
+	if err := c.db.Model(&database.Contact{}).Where("jid = ?", profile.JID).Updates(updates).Error; err != nil {
+		logrus.Errorf("Failed to store business profile of %s: %v", profile.JID, err)
+	}
+}
@@ -0,0 +1,154 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BusinessHours is a weekly opening schedule with holidays, evaluated in a
+// fixed timezone
+type BusinessHours struct {
+	location *time.Location
+	days     map[time.Weekday][]hoursRange
+	holidays map[string]bool // YYYY-MM-DD
+}
+
+// hoursRange is an opening window in minutes since midnight, end exclusive
+type hoursRange struct {
+	start int
+	end   int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseBusinessHours parses a schedule such as
+// "mon-fri=09:00-17:00;sat=09:00-12:00,13:00-15:00". Days without an entry are
+// closed. holidays is a list of YYYY-MM-DD dates that are closed all day.
+func ParseBusinessHours(schedule, timezone string, holidays []string) (*BusinessHours, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+	}
+
+	bh := &BusinessHours{
+		location: location,
+		days:     make(map[time.Weekday][]hoursRange),
+		holidays: make(map[string]bool),
+	}
+
+	for _, entry := range strings.Split(schedule, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid business hours entry %q", entry)
+		}
+
+		days, err := parseWeekdays(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, window := range strings.Split(parts[1], ",") {
+			r, err := parseHoursRange(strings.TrimSpace(window))
+			if err != nil {
+				return nil, err
+			}
+			for _, day := range days {
+				bh.days[day] = append(bh.days[day], r)
+			}
+		}
+	}
+
+	for _, holiday := range holidays {
+		if _, err := time.Parse("2006-01-02", holiday); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q, expected YYYY-MM-DD", holiday)
+		}
+		bh.holidays[holiday] = true
+	}
+
+	return bh, nil
+}
+
+// IsOpen reports whether t falls inside business hours
+func (bh *BusinessHours) IsOpen(t time.Time) bool {
+	local := t.In(bh.location)
+	if bh.holidays[local.Format("2006-01-02")] {
+		return false
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	for _, r := range bh.days[local.Weekday()] {
+		if minute >= r.start && minute < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWeekdays parses "mon", "mon-fri" or "fri-mon"
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	bounds := strings.SplitN(strings.ToLower(value), "-", 2)
+
+	first, ok := weekdays[bounds[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid weekday %q", bounds[0])
+	}
+	if len(bounds) == 1 {
+		return []time.Weekday{first}, nil
+	}
+
+	last, ok := weekdays[bounds[1]]
+	if !ok {
+		return nil, fmt.Errorf("invalid weekday %q", bounds[1])
+	}
+
+	days := []time.Weekday{first}
+	for day := first; day != last; {
+		day = (day + 1) % 7
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseHoursRange parses "09:00-17:00"
+func parseHoursRange(value string) (hoursRange, error) {
+	bounds := strings.SplitN(value, "-", 2)
+	if len(bounds) != 2 {
+		return hoursRange{}, fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", value)
+	}
+
+	start, err := time.Parse("15:04", strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return hoursRange{}, fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", value)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(bounds[1]))
+	if err != nil {
+		return hoursRange{}, fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", value)
+	}
+
+	r := hoursRange{
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+	}
+	// 24:00 can't be parsed, so 00:00 as the end means midnight
+	if r.end == 0 {
+		r.end = 24 * 60
+	}
+	if r.end <= r.start {
+		return hoursRange{}, fmt.Errorf("invalid hours %q, end must be after start", value)
+	}
+	return r, nil
+}
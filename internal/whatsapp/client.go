@@ -2,14 +2,17 @@ package whatsapp
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"gowa-broadcast/internal/config"
 	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/media"
 
 	"github.com/sirupsen/logrus"
 	"go.mau.fi/whatsmeow"
@@ -19,6 +22,7 @@ import (
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Client struct {
@@ -28,14 +32,66 @@ type Client struct {
 	store    *sqlstore.Container
 	device   *store.Device
 	logger   waLog.Logger
-	qrChan   chan string
 	isReady  bool
+
+	presenceMu    sync.Mutex
+	presenceHolds int
+
+	stateMu            sync.RWMutex
+	lastConnectedAt    time.Time
+	lastDisconnectedAt time.Time
+	lastEventAt        time.Time
+	qrPending          bool
+	connectAttempts    int           // Attempts of the connect in progress, 0 once connected
+	connectBackoff     time.Duration // Wait before the next connect attempt
+
+	keepAliveOnce sync.Once
+
+	// The latest QR code is kept rather than consumed so concurrent
+	// pollers all see it; qrUpdated is closed whenever a new code arrives
+	qrMu        sync.Mutex
+	qrCode      string
+	qrExpiresAt time.Time
+	qrUpdated   chan struct{}
+	pairingUser uint // User that requested the QR code, owner of the paired device
+
+	contactPresenceMu sync.RWMutex
+	contactPresence   map[string]*ContactPresence
+
+	businessMu       sync.Mutex
+	businessProfiles map[string]*BusinessProfile
+
+	// businessHours limits auto replies to outside opening hours when set
+	businessHours *BusinessHours
+
+	// mediaStore holds inbound attachments when WHATSAPP_STORE_MEDIA is set
+	mediaStore media.Store
+	mediaJobs  chan inboundMediaJob
+
+	// lids maps LIDs to phone numbers where WhatsApp revealed both
+	lids lidMap
+
+	// sessionMu guards client, store, device and sessionDB, which a session
+	// import swaps out
+	sessionMu   sync.RWMutex
+	sessionDB   *sql.DB
+	sessionPath string
+
+	// rawEvents gets every event when WEBHOOK_RAW_EVENTS is enabled
+	rawEvents RawEventHandler
+
+	// receipts gets delivery and read receipts of sent messages
+	receipts ReceiptHandler
+
+	// messages gets received messages
+	messages MessageHandler
 }
 
 type QRResponse struct {
-	QRCode    string `json:"qr_code"`
-	Timeout   int    `json:"timeout"`
-	Connected bool   `json:"connected"`
+	QRCode    string    `json:"qr_code"`
+	Timeout   int       `json:"timeout"` // Seconds until the code expires
+	Connected bool      `json:"connected"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 func NewClient(cfg *config.Config, db *gorm.DB) (*Client, error) {
@@ -47,15 +103,10 @@ func NewClient(cfg *config.Config, db *gorm.DB) (*Client, error) {
 
 	// Initialize store
 	dbLog := waLog.Stdout("Database", "INFO", true)
-	container, err := sqlstore.New("sqlite3", filepath.Join(storageDir, "whatsapp_session.db"), dbLog)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create store: %v", err)
-	}
-
-	// Get device store
-	deviceStore, err := container.GetFirstDevice()
+	sessionPath := sessionFilePath(storageDir)
+	sessionDB, container, deviceStore, err := openSessionStore(sessionPath, dbLog)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get device: %v", err)
+		return nil, err
 	}
 
 	// Create logger
@@ -64,35 +115,118 @@ func NewClient(cfg *config.Config, db *gorm.DB) (*Client, error) {
 	// Create WhatsApp client
 	client := whatsmeow.NewClient(deviceStore, clientLog)
 
-	return &Client{
-		cfg:    cfg,
-		db:     db,
-		client: client,
-		store:  container,
-		device: deviceStore,
-		logger: clientLog,
-		qrChan: make(chan string, 1),
-	}, nil
+	var businessHours *BusinessHours
+	if cfg.WhatsApp.BusinessHours != "" {
+		businessHours, err = ParseBusinessHours(cfg.WhatsApp.BusinessHours, cfg.WhatsApp.BusinessTimezone, cfg.WhatsApp.ParseBusinessHolidays())
+		if err != nil {
+			sessionDB.Close()
+			return nil, fmt.Errorf("invalid business hours: %v", err)
+		}
+	}
+
+	mediaStore, err := media.NewStore(cfg.Media)
+	if err != nil {
+		sessionDB.Close()
+		return nil, fmt.Errorf("invalid media storage: %v", err)
+	}
+
+	c := &Client{
+		cfg:           cfg,
+		db:            db,
+		client:        client,
+		store:         container,
+		device:        deviceStore,
+		logger:        clientLog,
+		qrUpdated:     make(chan struct{}),
+		sessionDB:     sessionDB,
+		sessionPath:   sessionPath,
+		businessHours: businessHours,
+		mediaStore:    mediaStore,
+	}
+	if cfg.WhatsApp.StoreMedia {
+		c.startInboundMediaWorkers()
+	}
+	return c, nil
 }
 
 func (c *Client) Start() error {
 	// Add event handlers
-	c.client.AddEventHandler(c.handleEvents)
+	c.GetClient().AddEventHandler(c.handleEvents)
+
+	c.keepAliveOnce.Do(func() {
+		if c.cfg.WhatsApp.KeepAliveSec > 0 {
+			go c.keepAlive()
+		}
+	})
 
 	// Connect to WhatsApp
-	if c.client.Store.ID == nil {
+	if c.GetClient().Store.ID == nil {
 		// Not logged in, need QR code
 		logrus.Info("Device not logged in, waiting for QR code scan...")
 		return c.connectWithQR()
 	} else {
 		// Already logged in, try to connect
 		logrus.Info("Device already logged in, connecting...")
-		return c.client.Connect()
+		return c.connect()
+	}
+}
+
+// connect opens the WhatsApp connection, retrying with exponential backoff so
+// a network that isn't up yet at boot doesn't leave the client offline
+func (c *Client) connect() error {
+	retries := c.cfg.WhatsApp.ConnectRetries
+	if retries < 0 {
+		retries = 0
+	}
+	backoff := time.Duration(c.cfg.WhatsApp.ConnectBackoffMS) * time.Millisecond
+	timeout := time.Duration(c.cfg.WhatsApp.ConnectTimeoutSec) * time.Second
+
+	var err error
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		logrus.Infof("Connecting to WhatsApp (attempt %d/%d)", attempt, retries+1)
+		c.setConnectAttempt(attempt, 0)
+
+		if err = c.connectOnce(timeout); err == nil {
+			c.setConnectAttempt(0, 0)
+			return nil
+		}
+
+		logrus.Warnf("WhatsApp connect attempt %d failed: %v", attempt, err)
+		if attempt <= retries {
+			wait := backoff * time.Duration(1<<(attempt-1))
+			c.setConnectAttempt(attempt, wait)
+			time.Sleep(wait)
+		}
+	}
+
+	return fmt.Errorf("failed to connect after %d attempt(s): %v", retries+1, err)
+}
+
+// connectOnce makes a single connect attempt bounded by timeout (0 disables it)
+func (c *Client) connectOnce(timeout time.Duration) error {
+	if timeout <= 0 {
+		return c.GetClient().Connect()
+	}
+
+	wa := c.GetClient()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- wa.Connect()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(timeout):
+		// Disconnect waits for the socket lock the stuck Connect holds, so
+		// tear it down in the background instead of hanging the retry loop
+		go wa.Disconnect()
+		return fmt.Errorf("timed out after %s", timeout)
 	}
 }
 
 func (c *Client) connectWithQR() error {
-	qrChan, err := c.client.GetQRChannel(context.Background())
+	qrChan, err := c.GetClient().GetQRChannel(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to get QR channel: %v", err)
 	}
@@ -101,8 +235,9 @@ func (c *Client) connectWithQR() error {
 		for evt := range qrChan {
 			if evt.Event == "code" {
 				logrus.Info("QR code received")
-				c.qrChan <- evt.Code
-				
+				c.publishQR(evt.Code, evt.Timeout)
+				c.setQRPending(true)
+
 				// Save QR code to database
 				device := &database.Device{
 					JID:       "pending",
@@ -115,14 +250,22 @@ func (c *Client) connectWithQR() error {
 				c.db.Create(device)
 			} else {
 				logrus.Infof("QR channel event: %s", evt.Event)
+				c.publishQR("", 0)
+				c.setQRPending(false)
 				if evt.Event == "success" {
 					c.isReady = true
+					c.markConnected()
 					logrus.Info("Successfully connected to WhatsApp")
-					
+
 					// Update device in database
-					if c.client.Store.ID != nil {
+					if c.GetClient().Store.ID != nil {
+						c.qrMu.Lock()
+						owner := c.pairingUser
+						c.qrMu.Unlock()
+
 						device := &database.Device{
-							JID:       c.client.Store.ID.String(),
+							UserID:    owner,
+							JID:       c.GetClient().Store.ID.String(),
 							Name:      c.cfg.App.OS,
 							Platform:  "web",
 							Connected: true,
@@ -136,38 +279,53 @@ func (c *Client) connectWithQR() error {
 		}
 	}()
 
-	return c.client.Connect()
+	return c.connect()
 }
 
 func (c *Client) handleEvents(evt interface{}) {
+	c.stateMu.Lock()
+	c.lastEventAt = time.Now()
+	rawEvents := c.rawEvents
+	c.stateMu.Unlock()
+
+	if rawEvents != nil && forwardsRaw(evt) {
+		rawEvents(RawEvent{Type: rawEventType(evt), Event: evt})
+	}
+
 	switch v := evt.(type) {
 	case *events.Message:
 		c.handleMessage(v)
 	case *events.Receipt:
 		c.handleReceipt(v)
+	case *events.Presence:
+		c.handlePresence(v)
+	case *events.PushName:
+		c.updatePushName(v.JID, v.NewPushName)
 	case *events.Connected:
 		logrus.Info("Connected to WhatsApp")
 		c.isReady = true
-		
+		c.markConnected()
+
 		// Update device status
-		if c.client.Store.ID != nil {
-			c.db.Model(&database.Device{}).Where("jid = ?", c.client.Store.ID.String()).Update("connected", true)
+		if c.GetClient().Store.ID != nil {
+			c.db.Model(&database.Device{}).Where("jid = ?", c.GetClient().Store.ID.String()).Update("connected", true)
 		}
 	case *events.Disconnected:
 		logrus.Warn("Disconnected from WhatsApp")
 		c.isReady = false
-		
+		c.markDisconnected()
+
 		// Update device status
-		if c.client.Store.ID != nil {
-			c.db.Model(&database.Device{}).Where("jid = ?", c.client.Store.ID.String()).Update("connected", false)
+		if c.GetClient().Store.ID != nil {
+			c.db.Model(&database.Device{}).Where("jid = ?", c.GetClient().Store.ID.String()).Update("connected", false)
 		}
 	case *events.LoggedOut:
 		logrus.Warn("Logged out from WhatsApp")
 		c.isReady = false
-		
+
 		// Remove device from database
-		if c.client.Store.ID != nil {
-			c.db.Where("jid = ?", c.client.Store.ID.String()).Delete(&database.Device{})
+		if c.GetClient().Store.ID != nil {
+			c.db.Where("jid = ?", c.GetClient().Store.ID.String()).Delete(&database.Device{})
 		}
 	}
 }
@@ -177,35 +335,175 @@ func (c *Client) handleMessage(evt *events.Message) {
 		return // Skip own messages
 	}
 
+	if evt.Info.PushName != "" {
+		c.updatePushName(evt.Info.Sender, evt.Info.PushName)
+	}
+
 	// Save message to database if chat storage is enabled
-	if c.cfg.WhatsApp.ChatStorage {
+	if c.cfg.WhatsApp.ChatStorage && c.shouldStoreMessage(evt) {
 		msg := &database.Message{
+			UserID:    c.chatOwner(evt.Info.Chat),
 			MessageID: evt.Info.ID,
 			FromJID:   evt.Info.Sender.String(),
 			ToJID:     evt.Info.Chat.String(),
 			Type:      "text",
-			Content:   evt.Message.GetConversation(),
+			Content:   messageText(evt.Message),
 			Timestamp: evt.Info.Timestamp,
 			IsFromMe:  evt.Info.IsFromMe,
 			IsRead:    false,
 		}
-		c.db.Create(msg)
+		attachment, mediaType, _, caption := inboundMedia(evt.Message)
+		if attachment != nil {
+			msg.Type = mediaType
+			msg.Content = caption
+		}
+		msg.Content, msg.Truncated = truncateContent(msg.Content, c.cfg.WhatsApp.StorageMaxContent)
+
+		// WhatsApp may redeliver the same event after a reconnect, so a
+		// conflicting insert means this message was already handled
+		result := c.db.Clauses(clause.OnConflict{DoNothing: true}).Create(msg)
+		if result.Error == nil && result.RowsAffected == 0 {
+			logrus.Debugf("Skipping redelivered message %s", evt.Info.ID)
+			return
+		}
+
+		if attachment != nil && result.Error == nil && c.cfg.WhatsApp.StoreMedia {
+			c.queueInboundMedia(evt, msg.ID)
+		}
 	}
 
 	// Auto mark as read if enabled
-	if c.cfg.WhatsApp.AutoMarkRead {
-		c.client.MarkRead([]types.MessageID{evt.Info.ID}, evt.Info.Timestamp, evt.Info.Chat, evt.Info.Sender)
+	if c.cfg.WhatsApp.AutoMarkRead && c.shouldAutoMarkRead(evt.Info.Chat) {
+		c.autoMarkRead(evt)
 	}
 
+	// Acknowledge replies to broadcasts that ask for it
+	go c.acknowledgeBroadcastReply(evt)
+
 	// Auto reply if configured
-	if c.cfg.WhatsApp.AutoReply != "" {
-		c.SendTextMessage(evt.Info.Chat.String(), c.cfg.WhatsApp.AutoReply)
+	if reply := c.autoReplyText(evt.Info.Timestamp); reply != "" {
+		c.SendTextMessage(evt.Info.Chat.String(), reply)
+	}
+
+	c.forwardMessage(evt)
+}
+
+// autoReplyText returns the auto reply for a message received at t. With
+// business hours configured, replies are only sent while closed.
+func (c *Client) autoReplyText(t time.Time) string {
+	if c.businessHours == nil {
+		return c.cfg.WhatsApp.AutoReply
+	}
+	if c.businessHours.IsOpen(t) {
+		return ""
+	}
+	if c.cfg.WhatsApp.AfterHoursReply != "" {
+		return c.cfg.WhatsApp.AfterHoursReply
+	}
+	return c.cfg.WhatsApp.AutoReply
+}
+
+// shouldStoreMessage applies the storage filters, everything is stored when
+// none are configured
+func (c *Client) shouldStoreMessage(evt *events.Message) bool {
+	chat := evt.Info.Chat
+
+	isDM := chat.Server == types.DefaultUserServer || chat.Server == types.HiddenUserServer
+	if c.cfg.WhatsApp.StorageDMOnly && !isDM {
+		return false
+	}
+	if c.cfg.WhatsApp.StorageSkipGroups && chat.Server == types.GroupServer {
+		return false
+	}
+
+	if jids := c.cfg.WhatsApp.ParseStorageJIDs(); len(jids) > 0 {
+		matched := false
+		for _, jid := range jids {
+			if jid == chat.String() || jid == chat.User {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
 	}
 
-	// Send webhook if configured
-	if c.cfg.WhatsApp.Webhook != "" {
-		go c.sendWebhook(evt)
+	if allowed := c.cfg.WhatsApp.ParseStorageTypes(); len(allowed) > 0 {
+		msgType := "text"
+		if attachment, mediaType, _, _ := inboundMedia(evt.Message); attachment != nil {
+			msgType = mediaType
+		}
+		matched := false
+		for _, t := range allowed {
+			if strings.EqualFold(t, msgType) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
 	}
+
+	if keywords := c.cfg.WhatsApp.ParseStorageKeywords(); len(keywords) > 0 {
+		content := strings.ToLower(messageText(evt.Message))
+		for _, keyword := range keywords {
+			if strings.Contains(content, strings.ToLower(keyword)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// truncateContent cuts content to at most max characters, reporting whether
+// anything was removed. A max of zero or less keeps the content as is.
+func truncateContent(content string, max int) (string, bool) {
+	if max <= 0 || utf8.RuneCountInString(content) <= max {
+		return content, false
+	}
+	return string([]rune(content)[:max]), true
+}
+
+// shouldAutoMarkRead checks the chat against the auto mark read allow/deny lists
+func (c *Client) shouldAutoMarkRead(chat types.JID) bool {
+	for _, denied := range c.cfg.WhatsApp.ParseAutoMarkReadDeny() {
+		if denied == chat.String() || denied == chat.User {
+			return false
+		}
+	}
+
+	allowed := c.cfg.WhatsApp.ParseAutoMarkReadAllow()
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == chat.String() || a == chat.User {
+			return true
+		}
+	}
+	return false
+}
+
+// autoMarkRead sends the read receipt, optionally after the configured delay
+func (c *Client) autoMarkRead(evt *events.Message) {
+	markRead := func(readAt time.Time) {
+		if err := c.GetClient().MarkRead([]types.MessageID{evt.Info.ID}, readAt, evt.Info.Chat, evt.Info.Sender); err != nil {
+			logrus.Warnf("Failed to mark message %s as read: %v", evt.Info.ID, err)
+		}
+	}
+
+	if c.cfg.WhatsApp.AutoMarkReadDelayMS <= 0 {
+		markRead(evt.Info.Timestamp)
+		return
+	}
+
+	time.AfterFunc(time.Duration(c.cfg.WhatsApp.AutoMarkReadDelayMS)*time.Millisecond, func() {
+		markRead(time.Now())
+	})
 }
 
 func (c *Client) handleReceipt(evt *events.Receipt) {
@@ -215,67 +513,282 @@ func (c *Client) handleReceipt(evt *events.Receipt) {
 			c.db.Model(&database.Message{}).Where("message_id = ?", msgID).Update("is_read", true)
 		}
 	}
+
+	c.recordBroadcastReceipt(evt)
+	c.forwardReceipt(evt)
+}
+
+// recordBroadcastReceipt stamps broadcast deliveries with when the recipient
+// received and read them. A read receipt implies delivery, which WhatsApp
+// doesn't always report separately.
+func (c *Client) recordBroadcastReceipt(evt *events.Receipt) {
+	if len(evt.MessageIDs) == 0 {
+		return
+	}
+
+	var columns []string
+	switch evt.Type {
+	case events.ReceiptTypeDelivered:
+		columns = []string{"delivered_at"}
+	case events.ReceiptTypeRead, events.ReceiptTypePlayed:
+		columns = []string{"delivered_at", "read_at"}
+	default:
+		return
+	}
+
+	for _, column := range columns {
+		err := c.db.Model(&database.BroadcastDelivery{}).
+			Where("message_id IN ? AND "+column+" IS NULL", evt.MessageIDs).
+			Update(column, evt.Timestamp).Error
+		if err != nil {
+			logrus.Errorf("Failed to record %s of broadcast deliveries: %v", column, err)
+		}
+	}
 }
 
-func (c *Client) sendWebhook(evt *events.Message) {
-	// Create webhook payload
-	payload := map[string]interface{}{
-		"event": "message",
-		"data": map[string]interface{}{
-			"message_id": evt.Info.ID,
-			"from":       evt.Info.Sender.String(),
-			"to":         evt.Info.Chat.String(),
-			"content":    evt.Message.GetConversation(),
-			"timestamp":  evt.Info.Timestamp.Unix(),
-			"is_group":   evt.Info.IsGroup,
-		},
+// MediaStore returns the store holding inbound attachments
+func (c *Client) MediaStore() media.Store {
+	return c.mediaStore
+}
+
+// GetQRCode returns the current QR code for login, waiting for one when none
+// is valid. Reading the code doesn't consume it, every caller gets the same one.
+func (c *Client) GetQRCode() (*QRResponse, error) {
+	if c.GetClient().Store.ID != nil {
+		return nil, fmt.Errorf("already logged in")
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-	
-	// Send to all configured webhooks
-	webhooks := c.cfg.WhatsApp.ParseWebhooks()
-	for _, webhookURL := range webhooks {
-		go func(url string) {
-			// TODO: Implement webhook delivery with retry logic
-			logrus.Debugf("Sending webhook to %s: %s", url, string(payloadBytes))
-		}(webhookURL)
+	wait := time.Duration(c.cfg.WhatsApp.QRWaitSec) * time.Second
+	if wait <= 0 {
+		wait = 30 * time.Second
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	for {
+		c.qrMu.Lock()
+		code, expiresAt, updated := c.qrCode, c.qrExpiresAt, c.qrUpdated
+		c.qrMu.Unlock()
+
+		if code != "" && time.Now().Before(expiresAt) {
+			return &QRResponse{
+				QRCode:    code,
+				Timeout:   int(time.Until(expiresAt).Seconds()),
+				ExpiresAt: expiresAt,
+			}, nil
+		}
+
+		select {
+		case <-updated:
+		case <-deadline.C:
+			return nil, fmt.Errorf("timeout waiting for QR code")
+		}
 	}
 }
 
-// GetQRCode returns the current QR code for login
-func (c *Client) GetQRCode() (string, error) {
-	if c.client.Store.ID != nil {
-		return "", fmt.Errorf("already logged in")
+// SetPairingUser records the user scanning the QR code, the device paired
+// with it is theirs
+func (c *Client) SetPairingUser(userID uint) {
+	c.qrMu.Lock()
+	defer c.qrMu.Unlock()
+	c.pairingUser = userID
+}
+
+// RefreshQRCode restarts the QR pairing cycle and returns the first new code
+func (c *Client) RefreshQRCode() (*QRResponse, error) {
+	if c.GetClient().Store.ID != nil {
+		return nil, fmt.Errorf("already logged in")
 	}
 
-	select {
-	case qr := <-c.qrChan:
-		return qr, nil
-	case <-time.After(30 * time.Second):
-		return "", fmt.Errorf("timeout waiting for QR code")
+	// Tear down the current pairing attempt so a new QR channel can be opened
+	c.GetClient().Disconnect()
+	c.isReady = false
+
+	// Drop the stale code left over from the previous cycle
+	c.publishQR("", 0)
+
+	if err := c.connectWithQR(); err != nil {
+		return nil, err
+	}
+
+	return c.GetQRCode()
+}
+
+// publishQR stores the latest QR code, valid for timeout, and wakes up
+// waiting readers. An empty code clears it.
+func (c *Client) publishQR(code string, timeout time.Duration) {
+	c.qrMu.Lock()
+	defer c.qrMu.Unlock()
+
+	c.qrCode = code
+	c.qrExpiresAt = time.Now().Add(timeout)
+	if code != "" {
+		close(c.qrUpdated)
+		c.qrUpdated = make(chan struct{})
 	}
 }
 
 // IsReady returns true if the client is connected and ready
 func (c *Client) IsReady() bool {
-	return c.isReady && c.client.IsConnected()
+	return c.isReady && c.GetClient().IsConnected()
 }
 
-// GetClient returns the underlying whatsmeow client
+// ConnectionState describes the connection for API responses: connected,
+// qr_pending, logged_out or disconnected
+func (c *Client) ConnectionState() string {
+	switch {
+	case c.IsReady():
+		return "connected"
+	case c.IsQRPending():
+		return "qr_pending"
+	case c.GetClient().Store.ID == nil:
+		return "logged_out"
+	default:
+		return "disconnected"
+	}
+}
+
+// LastConnectedAt returns when the client last connected, or nil if it never has
+func (c *Client) LastConnectedAt() *time.Time {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+
+	if c.lastConnectedAt.IsZero() {
+		return nil
+	}
+	t := c.lastConnectedAt
+	return &t
+}
+
+// IsQRPending returns true while a QR code is waiting to be scanned
+func (c *Client) IsQRPending() bool {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.qrPending
+}
+
+// keepAlive periodically sends a presence update to keep an idle session
+// healthy, and reconnects when nothing has been received for too long
+func (c *Client) keepAlive() {
+	interval := time.Duration(c.cfg.WhatsApp.KeepAliveSec) * time.Second
+	idleTimeout := time.Duration(c.cfg.WhatsApp.IdleReconnectSec) * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Read under sessionMu once per tick, an import may swap the client
+		wa := c.GetClient()
+		if wa.Store.ID == nil || !c.IsReady() {
+			continue
+		}
+
+		c.stateMu.RLock()
+		lastEventAt := c.lastEventAt
+		c.stateMu.RUnlock()
+
+		if idleTimeout > 0 && time.Since(lastEventAt) > idleTimeout {
+			logrus.Warnf("No WhatsApp events for %s, reconnecting", time.Since(lastEventAt).Round(time.Second))
+			wa.Disconnect()
+			c.isReady = false
+			if err := c.connect(); err != nil {
+				logrus.Errorf("Failed to reconnect idle session: %v", err)
+			}
+			continue
+		}
+
+		// Respect online presence held by broadcasts
+		c.presenceMu.Lock()
+		presence := types.PresenceUnavailable
+		if c.presenceHolds > 0 {
+			presence = types.PresenceAvailable
+		}
+		err := wa.SendPresence(presence)
+		c.presenceMu.Unlock()
+
+		if err != nil {
+			logrus.Warnf("Keep-alive presence failed: %v", err)
+		} else {
+			logrus.Debug("Keep-alive presence sent")
+		}
+	}
+}
+
+func (c *Client) markConnected() {
+	c.stateMu.Lock()
+	c.lastConnectedAt = time.Now()
+	c.qrPending = false
+	c.stateMu.Unlock()
+}
+
+func (c *Client) markDisconnected() {
+	c.stateMu.Lock()
+	c.lastDisconnectedAt = time.Now()
+	c.stateMu.Unlock()
+}
+
+func (c *Client) setConnectAttempt(attempt int, backoff time.Duration) {
+	c.stateMu.Lock()
+	c.connectAttempts = attempt
+	c.connectBackoff = backoff
+	c.stateMu.Unlock()
+}
+
+func (c *Client) setQRPending(pending bool) {
+	c.stateMu.Lock()
+	c.qrPending = pending
+	c.stateMu.Unlock()
+}
+
+// HoldOnlinePresence marks the account as available until every hold has
+// been released. Holds are counted so overlapping callers don't undo each other.
+func (c *Client) HoldOnlinePresence() {
+	c.presenceMu.Lock()
+	defer c.presenceMu.Unlock()
+
+	c.presenceHolds++
+	if c.presenceHolds == 1 {
+		if err := c.GetClient().SendPresence(types.PresenceAvailable); err != nil {
+			logrus.Warnf("Failed to set presence to available: %v", err)
+		}
+	}
+}
+
+// ReleaseOnlinePresence releases a hold taken by HoldOnlinePresence and marks
+// the account unavailable once the last hold is gone
+func (c *Client) ReleaseOnlinePresence() {
+	c.presenceMu.Lock()
+	defer c.presenceMu.Unlock()
+
+	if c.presenceHolds == 0 {
+		return
+	}
+
+	c.presenceHolds--
+	if c.presenceHolds == 0 {
+		if err := c.GetClient().SendPresence(types.PresenceUnavailable); err != nil {
+			logrus.Warnf("Failed to set presence to unavailable: %v", err)
+		}
+	}
+}
+
+// GetClient returns the underlying whatsmeow client. It can change when a
+// session is imported, so don't hold on to it.
 func (c *Client) GetClient() *whatsmeow.Client {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
 	return c.client
 }
 
 // Disconnect disconnects the client
 func (c *Client) Disconnect() {
-	c.client.Disconnect()
+	c.GetClient().Disconnect()
 	c.isReady = false
 }
 
 // Logout logs out the client
 func (c *Client) Logout() error {
-	err := c.client.Logout()
+	err := c.GetClient().Logout()
 	c.isReady = false
 	return err
-}
\ No newline at end of file
+}
@@ -0,0 +1,129 @@
+package whatsapp
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gowa-broadcast/internal/config"
+	"gowa-broadcast/internal/database"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHandleMessageIgnoresRedelivery(t *testing.T) {
+	db, err := database.Initialize("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.WhatsApp.ChatStorage = true
+	c := &Client{cfg: cfg, db: db}
+
+	forwarded := 0
+	c.SetMessageHandler(func(IncomingMessage) { forwarded++ })
+
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	evt := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:    group,
+				Sender:  types.NewJID("6281234567890", types.DefaultUserServer),
+				IsGroup: true,
+			},
+			ID:        "3EB0DEADBEEF",
+			Timestamp: time.Now(),
+		},
+		Message: &waProto.Message{Conversation: proto.String("hello")},
+	}
+
+	// Delivered twice, as after a reconnect
+	c.handleMessage(evt)
+	c.handleMessage(evt)
+
+	var stored int64
+	db.Model(&database.Message{}).Where("message_id = ?", evt.Info.ID).Count(&stored)
+	if stored != 1 {
+		t.Errorf("stored %d copies, want 1", stored)
+	}
+	if forwarded != 1 {
+		t.Errorf("forwarded %d times, want 1", forwarded)
+	}
+}
+
+func TestHandleMessageStoresExtendedText(t *testing.T) {
+	db, err := database.Initialize("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.WhatsApp.ChatStorage = true
+	cfg.WhatsApp.StorageKeywords = "promo"
+	cfg.WhatsApp.StorageMaxContent = 12
+	c := &Client{cfg: cfg, db: db}
+
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	message := func(id, text string) *events.Message {
+		// Replies and messages with links arrive as ExtendedTextMessage,
+		// with Conversation left empty
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{
+					Chat:    group,
+					Sender:  types.NewJID("6281234567890", types.DefaultUserServer),
+					IsGroup: true,
+				},
+				ID:        id,
+				Timestamp: time.Now(),
+			},
+			Message: &waProto.Message{ExtendedTextMessage: &waProto.ExtendedTextMessage{Text: proto.String(text)}},
+		}
+	}
+
+	c.handleMessage(message("3EB0MATCH", "Is the PROMO still on? https://example.com"))
+	c.handleMessage(message("3EB0OTHER", "Unrelated reply"))
+
+	var stored []database.Message
+	db.Find(&stored)
+	if len(stored) != 1 {
+		t.Fatalf("stored %d messages, want only the keyword match", len(stored))
+	}
+	if stored[0].Content != "Is the PROMO" || !stored[0].Truncated {
+		t.Errorf("stored content %q (truncated %v), want the truncated reply text", stored[0].Content, stored[0].Truncated)
+	}
+}
+
+func TestTruncateContent(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		max           int
+		want          string
+		wantTruncated bool
+	}{
+		{"under limit", "hello", 10, "hello", false},
+		{"exact limit", "hello", 5, "hello", false},
+		{"over limit", "hello world", 5, "hello", true},
+		{"multibyte kept whole", "héllo wörld", 7, "héllo w", true},
+		{"emoji at the cut", "ok 👍👍", 4, "ok 👍", true},
+		{"multibyte exact limit", "日本語", 3, "日本語", false},
+		{"zero disables", "hello", 0, "hello", false},
+		{"negative disables", "hello", -1, "hello", false},
+		{"empty", "", 5, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncateContent(tt.content, tt.max)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("truncateContent(%q, %d) = %q, %v, want %q, %v",
+					tt.content, tt.max, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package whatsapp
+
+import (
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// updatePushName stores the name a contact set for themselves on every saved
+// contact with that JID. WhatsApp often provides no other name.
+func (c *Client) updatePushName(jid types.JID, pushName string) {
+	if pushName == "" {
+		return
+	}
+
+	target := jid.ToNonAD().String()
+	err := c.db.Model(&database.Contact{}).
+		Where("jid = ? AND push_name <> ?", target, pushName).
+		Update("push_name", pushName).Error
+	if err != nil {
+		logrus.Errorf("Failed to store push name of %s: %v", target, err)
+	}
+}
@@ -0,0 +1,55 @@
+package whatsapp
+
+import (
+	"time"
+)
+
+// Diagnostics is a snapshot of the connection and session state for support
+type Diagnostics struct {
+	State               string     `json:"state"`
+	HasStoreID          bool       `json:"has_store_id"` // False until a QR code has been scanned
+	JID                 string     `json:"jid,omitempty"`
+	PushName            string     `json:"push_name,omitempty"`
+	Platform            string     `json:"platform,omitempty"`
+	QRPending           bool       `json:"qr_pending"`
+	LastConnectedAt     *time.Time `json:"last_connected_at,omitempty"`
+	LastDisconnectedAt  *time.Time `json:"last_disconnected_at,omitempty"`
+	LastEventAt         *time.Time `json:"last_event_at,omitempty"`
+	ReconnectAttempts   int        `json:"reconnect_attempts"`
+	ReconnectBackoffMS  int64      `json:"reconnect_backoff_ms"`
+	AutoReconnectErrors int        `json:"auto_reconnect_errors"` // Consecutive failures of whatsmeow's own reconnect loop
+}
+
+// Diagnostics reports the state tracked by the client alongside what the
+// session store knows about the linked device
+func (c *Client) Diagnostics() *Diagnostics {
+	d := &Diagnostics{
+		State:               c.ConnectionState(),
+		QRPending:           c.IsQRPending(),
+		AutoReconnectErrors: c.GetClient().AutoReconnectErrors,
+	}
+
+	if c.GetClient().Store.ID != nil {
+		d.HasStoreID = true
+		d.JID = c.GetClient().Store.ID.String()
+	}
+	d.PushName = c.GetClient().Store.PushName
+	d.Platform = c.GetClient().Store.Platform
+
+	c.stateMu.RLock()
+	d.LastConnectedAt = timeOrNil(c.lastConnectedAt)
+	d.LastDisconnectedAt = timeOrNil(c.lastDisconnectedAt)
+	d.LastEventAt = timeOrNil(c.lastEventAt)
+	d.ReconnectAttempts = c.connectAttempts
+	d.ReconnectBackoffMS = c.connectBackoff.Milliseconds()
+	c.stateMu.RUnlock()
+
+	return d
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,195 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Group errors handlers map to status codes
+var (
+	ErrNotInGroup    = whatsmeow.ErrNotInGroup
+	ErrGroupNotFound = whatsmeow.ErrGroupNotFound
+)
+
+type JoinGroupRequest struct {
+	Link string `json:"link" binding:"required"`
+}
+
+// GroupParticipant is a member of a group
+type GroupParticipant struct {
+	JID          string `json:"jid"`
+	LID          string `json:"lid,omitempty"`
+	PhoneNumber  string `json:"phone_number,omitempty"`
+	DisplayName  string `json:"display_name,omitempty"`
+	IsAdmin      bool   `json:"is_admin"`
+	IsSuperAdmin bool   `json:"is_super_admin"`
+}
+
+// GroupMetadata is the live state of a group as reported by WhatsApp
+type GroupMetadata struct {
+	JID              string             `json:"jid"`
+	Name             string             `json:"name"`
+	Description      string             `json:"description"`
+	OwnerJID         string             `json:"owner_jid,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	IsAnnounce       bool               `json:"is_announce"` // Only admins can send messages
+	IsLocked         bool               `json:"is_locked"`   // Only admins can edit group info
+	ParticipantCount int                `json:"participant_count"`
+	Participants     []GroupParticipant `json:"participants"`
+}
+
+// GetGroupMetadata fetches a group's metadata and participants from WhatsApp
+func (c *Client) GetGroupMetadata(group string) (*GroupMetadata, error) {
+	if !c.IsReady() {
+		return nil, ErrClientNotReady
+	}
+
+	jid, err := parseGroupJID(group)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetClient().GetGroupInfo(jid)
+	if err != nil {
+		return nil, describeGroupError(err)
+	}
+
+	metadata := &GroupMetadata{
+		JID:              info.JID.String(),
+		Name:             info.Name,
+		Description:      info.Topic,
+		CreatedAt:        info.GroupCreated,
+		IsAnnounce:       info.IsAnnounce,
+		IsLocked:         info.IsLocked,
+		ParticipantCount: len(info.Participants),
+		Participants:     make([]GroupParticipant, 0, len(info.Participants)),
+	}
+	if !info.OwnerJID.IsEmpty() {
+		metadata.OwnerJID = info.OwnerJID.String()
+	}
+	c.rememberParticipantLIDs(info.Participants)
+
+	for _, participant := range info.Participants {
+		member := GroupParticipant{
+			JID:          participant.JID.String(),
+			DisplayName:  participant.DisplayName,
+			IsAdmin:      participant.IsAdmin || participant.IsSuperAdmin,
+			IsSuperAdmin: participant.IsSuperAdmin,
+		}
+		if participant.JID.Server == types.DefaultUserServer {
+			member.PhoneNumber = participant.JID.User
+		}
+		if !participant.LID.IsEmpty() {
+			member.LID = participant.LID.String()
+		}
+		metadata.Participants = append(metadata.Participants, member)
+	}
+
+	return metadata, nil
+}
+
+// GroupMemberJIDs returns the JIDs of a group's members, leaving out this
+// account
+func (c *Client) GroupMemberJIDs(group string) ([]string, error) {
+	metadata, err := c.GetGroupMetadata(group)
+	if err != nil {
+		return nil, err
+	}
+
+	var own string
+	if c.GetClient().Store.ID != nil {
+		own = c.GetClient().Store.ID.ToNonAD().String()
+	}
+
+	jids := make([]string, 0, len(metadata.Participants))
+	for _, participant := range metadata.Participants {
+		if participant.JID != own {
+			jids = append(jids, participant.JID)
+		}
+	}
+	return jids, nil
+}
+
+// GetGroupInviteLink returns the invite link of a group
+func (c *Client) GetGroupInviteLink(group string) (string, error) {
+	if !c.IsReady() {
+		return "", ErrClientNotReady
+	}
+
+	jid, err := parseGroupJID(group)
+	if err != nil {
+		return "", err
+	}
+
+	link, err := c.GetClient().GetGroupInviteLink(jid, false)
+	if err != nil {
+		return "", describeGroupError(err)
+	}
+
+	return link, nil
+}
+
+// JoinGroupWithLink joins a group using an invite link and returns its info
+func (c *Client) JoinGroupWithLink(link string) (*types.GroupInfo, error) {
+	if !c.IsReady() {
+		return nil, ErrClientNotReady
+	}
+
+	code := strings.TrimPrefix(strings.TrimSpace(link), whatsmeow.InviteLinkPrefix)
+	if code == "" {
+		return nil, fmt.Errorf("invite link is empty")
+	}
+
+	jid, err := c.GetClient().JoinGroupWithLink(code)
+	if err != nil {
+		return nil, describeGroupError(err)
+	}
+
+	// The join already succeeded, so fall back to the bare JID if the
+	// metadata can't be fetched right away
+	info, err := c.GetClient().GetGroupInfo(jid)
+	if err != nil {
+		return &types.GroupInfo{JID: jid}, nil
+	}
+
+	return info, nil
+}
+
+// parseGroupJID parses a group JID, accepting the bare group ID as well
+func parseGroupJID(group string) (types.JID, error) {
+	if !strings.Contains(group, "@") {
+		group += "@" + types.GroupServer
+	}
+
+	jid, err := types.ParseJID(group)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+	if jid.Server != types.GroupServer {
+		return types.JID{}, fmt.Errorf("%w: %s is not a group", ErrInvalidJID, group)
+	}
+
+	return jid, nil
+}
+
+// describeGroupError turns whatsmeow group errors into user facing messages
+func describeGroupError(err error) error {
+	switch {
+	case errors.Is(err, whatsmeow.ErrInviteLinkInvalid):
+		return fmt.Errorf("invite link is invalid or has expired: %w", err)
+	case errors.Is(err, whatsmeow.ErrInviteLinkRevoked):
+		return fmt.Errorf("invite link has been revoked: %w", err)
+	case errors.Is(err, whatsmeow.ErrGroupInviteLinkUnauthorized):
+		return fmt.Errorf("only group admins can get the invite link: %w", err)
+	case errors.Is(err, whatsmeow.ErrGroupNotFound):
+		return fmt.Errorf("group not found: %w", err)
+	case errors.Is(err, whatsmeow.ErrNotInGroup):
+		return fmt.Errorf("this account is not a member of the group: %w", err)
+	}
+	return err
+}
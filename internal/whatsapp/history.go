@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types"
+	"gorm.io/gorm/clause"
+)
+
+// RecordSentMessage stores a message sent through the API in the chat
+// history of the user who sent it, so it can be listed, starred and edited
+// later. Nothing is stored when chat storage is disabled.
+func (c *Client) RecordSentMessage(userID uint, to, msgType, content, mediaURL string, resp *MessageResponse) {
+	if !c.cfg.WhatsApp.ChatStorage || resp == nil || resp.MessageID == "" {
+		return
+	}
+
+	jid, err := c.parseJID(to, "")
+	if err != nil {
+		return
+	}
+
+	from := ""
+	if ownID := c.GetClient().Store.ID; ownID != nil {
+		from = ownID.ToNonAD().String()
+	}
+	timestamp := time.Now()
+	if resp.Timestamp > 0 {
+		timestamp = time.Unix(resp.Timestamp, 0)
+	}
+
+	msg := &database.Message{
+		UserID:    userID,
+		MessageID: resp.MessageID,
+		FromJID:   from,
+		ToJID:     jid.String(),
+		Type:      msgType,
+		MediaURL:  mediaURL,
+		Timestamp: timestamp,
+		IsFromMe:  true, // IsRead is set by the recipient's read receipt
+	}
+	msg.Content, msg.Truncated = truncateContent(content, c.cfg.WhatsApp.StorageMaxContent)
+
+	if err := c.db.Clauses(clause.OnConflict{DoNothing: true}).Create(msg).Error; err != nil {
+		logrus.Errorf("Failed to store sent message %s: %v", resp.MessageID, err)
+	}
+}
+
+// chatOwner returns the user that last sent to chat through the API, whose
+// history received messages in that chat are stored in. It is 0 for chats
+// no user has written to.
+func (c *Client) chatOwner(chat types.JID) uint {
+	var owner struct{ UserID uint }
+	c.db.Model(&database.Message{}).
+		Select("user_id").
+		Where("to_jid = ? AND is_from_me = ?", chat.ToNonAD().String(), true).
+		Order("timestamp DESC").
+		Limit(1).
+		Scan(&owner)
+	return owner.UserID
+}
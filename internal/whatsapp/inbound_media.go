@@ -0,0 +1,104 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"gowa-broadcast/internal/database"
+	"gowa-broadcast/internal/media"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	// inboundMediaTimeout bounds downloading and storing one inbound attachment
+	inboundMediaTimeout = 2 * time.Minute
+
+	// A burst of media messages waits in a queue for a fixed number of
+	// downloaders instead of starting a download each
+	inboundMediaWorkers = 4
+	inboundMediaQueue   = 256
+)
+
+// inboundMediaJob is a received attachment waiting to be stored
+type inboundMediaJob struct {
+	evt   *events.Message
+	rowID uint
+}
+
+// inboundMedia returns the attachment of a message with its type, mime type
+// and caption, or a nil attachment for messages without media
+func inboundMedia(msg *waProto.Message) (whatsmeow.DownloadableMessage, string, string, string) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		m := msg.GetImageMessage()
+		return m, "image", m.GetMimetype(), m.GetCaption()
+	case msg.GetVideoMessage() != nil:
+		m := msg.GetVideoMessage()
+		return m, "video", m.GetMimetype(), m.GetCaption()
+	case msg.GetAudioMessage() != nil:
+		m := msg.GetAudioMessage()
+		return m, "audio", m.GetMimetype(), ""
+	case msg.GetDocumentMessage() != nil:
+		m := msg.GetDocumentMessage()
+		return m, "document", m.GetMimetype(), m.GetCaption()
+	case msg.GetStickerMessage() != nil:
+		m := msg.GetStickerMessage()
+		return m, "sticker", m.GetMimetype(), ""
+	}
+	return nil, "", "", ""
+}
+
+func (c *Client) startInboundMediaWorkers() {
+	c.mediaJobs = make(chan inboundMediaJob, inboundMediaQueue)
+	for i := 0; i < inboundMediaWorkers; i++ {
+		go func() {
+			for job := range c.mediaJobs {
+				c.storeInboundMedia(job.evt, job.rowID)
+			}
+		}()
+	}
+}
+
+// queueInboundMedia hands an attachment to the downloaders. It runs on the
+// event loop, so when the queue is full the attachment is skipped rather than
+// waited on; the message itself is already stored.
+func (c *Client) queueInboundMedia(evt *events.Message, messageRowID uint) {
+	select {
+	case c.mediaJobs <- inboundMediaJob{evt: evt, rowID: messageRowID}:
+	default:
+		logrus.Warnf("Media queue full, not storing media of message %s", evt.Info.ID)
+	}
+}
+
+// storeInboundMedia downloads a received attachment into the media store and
+// saves its key on the stored message
+func (c *Client) storeInboundMedia(evt *events.Message, messageRowID uint) {
+	attachment, _, mimeType, _ := inboundMedia(evt.Message)
+	if attachment == nil || c.mediaStore == nil {
+		return
+	}
+
+	data, err := c.GetClient().Download(attachment)
+	if err != nil {
+		logrus.Errorf("Failed to download media of message %s: %v", evt.Info.ID, err)
+		return
+	}
+
+	key := media.InboundKey(evt.Info.Chat.User, evt.Info.ID, mimeType)
+
+	ctx, cancel := context.WithTimeout(context.Background(), inboundMediaTimeout)
+	defer cancel()
+
+	if err := c.mediaStore.Put(ctx, key, data, mimeType); err != nil {
+		logrus.Errorf("Failed to store media of message %s: %v", evt.Info.ID, err)
+		return
+	}
+
+	if err := c.db.Model(&database.Message{}).Where("id = ?", messageRowID).Update("media_url", key).Error; err != nil {
+		logrus.Errorf("Failed to save media key of message %s: %v", evt.Info.ID, err)
+	}
+}
@@ -0,0 +1,76 @@
+package whatsapp
+
+import (
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// MessageHandler receives messages sent to this account
+type MessageHandler func(msg IncomingMessage)
+
+// IncomingMessage is a received message, as the message.received webhook event
+type IncomingMessage struct {
+	MessageID string
+	From      string // Sender
+	Chat      string // Chat the message arrived in, the group for group messages
+	Type      string // text, image, video, audio, document, sticker, location or contact
+	Content   string // Text, or the caption of an attachment
+	IsGroup   bool
+	Timestamp time.Time
+
+	// Set when the message is a reply
+	QuotedMessageID string
+	QuotedContent   string
+}
+
+// SetMessageHandler forwards received messages to h. h runs on the event
+// loop, so it must not block.
+func (c *Client) SetMessageHandler(h MessageHandler) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.messages = h
+}
+
+// forwardMessage hands a received message to the message handler
+func (c *Client) forwardMessage(evt *events.Message) {
+	c.stateMu.RLock()
+	handler := c.messages
+	c.stateMu.RUnlock()
+	if handler == nil || !hasContent(evt.Message) {
+		return
+	}
+
+	msg := IncomingMessage{
+		MessageID: evt.Info.ID,
+		From:      evt.Info.Sender.ToNonAD().String(),
+		Chat:      evt.Info.Chat.String(),
+		Type:      "text",
+		Content:   messageText(evt.Message),
+		IsGroup:   evt.Info.IsGroup,
+		Timestamp: evt.Info.Timestamp,
+	}
+	if attachment, mediaType, _, _ := inboundMedia(evt.Message); attachment != nil {
+		msg.Type = mediaType
+	} else if evt.Message.GetLocationMessage() != nil {
+		msg.Type = "location"
+	} else if evt.Message.GetContactMessage() != nil || evt.Message.GetContactsArrayMessage() != nil {
+		msg.Type = "contact"
+	}
+	msg.QuotedMessageID, msg.QuotedContent = quotedContext(evt.Message)
+
+	handler(msg)
+}
+
+// hasContent reports whether msg carries something a person sent, as opposed
+// to reactions, edits, revokes and other protocol messages
+func hasContent(msg *waProto.Message) bool {
+	if attachment, _, _, _ := inboundMedia(msg); attachment != nil {
+		return true
+	}
+	return messageText(msg) != "" ||
+		msg.GetLocationMessage() != nil ||
+		msg.GetContactMessage() != nil ||
+		msg.GetContactsArrayMessage() != nil
+}
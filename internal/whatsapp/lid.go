@@ -0,0 +1,46 @@
+package whatsapp
+
+import (
+	"sync"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// lidMap remembers the phone number JID behind a LID. The pinned whatsmeow
+// has no LID store of its own, so pairs are learned from group participant
+// lists, which carry both for each member.
+type lidMap struct {
+	mu    sync.RWMutex
+	phone map[string]types.JID // LID user -> phone number JID
+}
+
+// rememberLID records that lid and phone are the same account
+func (c *Client) rememberLID(lid, phone types.JID) {
+	if lid.Server != types.HiddenUserServer || phone.Server != types.DefaultUserServer {
+		return
+	}
+
+	c.lids.mu.Lock()
+	defer c.lids.mu.Unlock()
+	if c.lids.phone == nil {
+		c.lids.phone = make(map[string]types.JID)
+	}
+	c.lids.phone[lid.User] = phone.ToNonAD()
+}
+
+// rememberParticipantLIDs records the LIDs of a group's members
+func (c *Client) rememberParticipantLIDs(participants []types.GroupParticipant) {
+	for _, participant := range participants {
+		if !participant.LID.IsEmpty() {
+			c.rememberLID(participant.LID, participant.JID)
+		}
+	}
+}
+
+// phoneForLID returns the phone number JID of a LID if it is known
+func (c *Client) phoneForLID(lid types.JID) (types.JID, bool) {
+	c.lids.mu.RLock()
+	defer c.lids.mu.RUnlock()
+	phone, ok := c.lids.phone[lid.User]
+	return phone, ok
+}
@@ -0,0 +1,247 @@
+package whatsapp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif" // Decoders for preview images
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"gowa-broadcast/internal/netguard"
+
+	"github.com/sirupsen/logrus"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	linkPreviewTimeout      = 10 * time.Second // Fetching the page and its image together
+	maxPreviewPageBytes     = 512 << 10        // The metadata is in the head, the rest isn't read
+	maxPreviewImageBytes    = 5 << 20
+	maxPreviewImagePixels   = 25_000_000 // Checked from the header before decoding
+	previewThumbnailSize    = 160        // Longest side in pixels
+	previewThumbnailQuality = 75
+)
+
+var (
+	linkPattern    = regexp.MustCompile(`https?://[^\s<>"']+`)
+	metaTagPattern = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern    = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	titlePattern   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// LinkPreview is the page metadata shown in a link preview
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	Thumbnail   []byte // JPEG
+}
+
+// textMessage builds a text message. Without linkPreview it's a plain text
+// message as before, false explicitly disables the preview and true attaches
+// the preview of the first link in text. A preview that can't be fetched is
+// left out rather than failing the send.
+func (c *Client) textMessage(text string, linkPreview *bool) *waProto.Message {
+	if linkPreview == nil {
+		return &waProto.Message{Conversation: proto.String(text)}
+	}
+
+	ext := &waProto.ExtendedTextMessage{
+		Text:        proto.String(text),
+		PreviewType: waProto.ExtendedTextMessage_NONE.Enum(),
+	}
+	if !*linkPreview {
+		return &waProto.Message{ExtendedTextMessage: ext}
+	}
+
+	link := strings.TrimRight(linkPattern.FindString(text), ".,;:!?)]}")
+	if link == "" {
+		return &waProto.Message{ExtendedTextMessage: ext}
+	}
+	preview, err := c.fetchLinkPreview(link)
+	if err != nil {
+		logrus.Warnf("Failed to fetch link preview of %s, sending without it: %v", link, err)
+		return &waProto.Message{ExtendedTextMessage: ext}
+	}
+
+	ext.MatchedText = proto.String(link)
+	ext.CanonicalUrl = proto.String(preview.URL)
+	ext.Title = proto.String(preview.Title)
+	ext.Description = proto.String(preview.Description)
+	if len(preview.Thumbnail) > 0 {
+		ext.JpegThumbnail = preview.Thumbnail
+	}
+	return &waProto.Message{ExtendedTextMessage: ext}
+}
+
+// fetchLinkPreview reads the title, description and image of a page from its
+// Open Graph tags, falling back to the <title> and description meta tags
+func (c *Client) fetchLinkPreview(link string) (*LinkPreview, error) {
+	client := c.httpClient(linkPreviewTimeout)
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.WhatsApp.MediaUserAgent != "" {
+		req.Header.Set("User-Agent", c.cfg.WhatsApp.MediaUserAgent)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPreviewPageBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	preview := parseLinkPreview(string(page))
+	if preview.Title == "" {
+		return nil, errors.New("page has no title")
+	}
+	if preview.URL == "" {
+		// Where the redirects ended up
+		preview.URL = resp.Request.URL.String()
+	}
+
+	if preview.ImageURL != "" {
+		if imageURL, err := resp.Request.URL.Parse(preview.ImageURL); err == nil {
+			preview.ImageURL = imageURL.String()
+			if preview.Thumbnail, err = fetchThumbnail(client, preview.ImageURL); err != nil {
+				logrus.Debugf("Failed to fetch preview image %s: %v", preview.ImageURL, err)
+			}
+		}
+	}
+
+	return preview, nil
+}
+
+// parseLinkPreview extracts the preview metadata from a page's HTML
+func parseLinkPreview(page string) *LinkPreview {
+	meta := make(map[string]string)
+	for _, tag := range metaTagPattern.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(attr[1])] = attr[2] + attr[3]
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if key != "" && meta[key] == "" {
+			meta[key] = strings.TrimSpace(html.UnescapeString(attrs["content"]))
+		}
+	}
+
+	preview := &LinkPreview{
+		URL:         meta["og:url"],
+		Title:       firstNonEmpty(meta["og:title"], meta["twitter:title"]),
+		Description: firstNonEmpty(meta["og:description"], meta["twitter:description"], meta["description"]),
+		ImageURL:    firstNonEmpty(meta["og:image"], meta["twitter:image"]),
+	}
+	if preview.Title == "" {
+		if match := titlePattern.FindStringSubmatch(page); match != nil {
+			preview.Title = strings.TrimSpace(html.UnescapeString(match[1]))
+		}
+	}
+	return preview
+}
+
+// fetchThumbnail downloads an image and scales it down to a small JPEG
+func fetchThumbnail(client *http.Client, imageURL string) ([]byte, error) {
+	resp, err := client.Get(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPreviewImageBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	// A small file can still declare huge dimensions, check them before
+	// decoding allocates the pixels
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if header.Width <= 0 || header.Height <= 0 ||
+		int64(header.Width)*int64(header.Height) > maxPreviewImagePixels {
+		return nil, fmt.Errorf("image too large (%dx%d)", header.Width, header.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, errors.New("empty image")
+	}
+	thumbWidth, thumbHeight := width, height
+	if width >= height && width > previewThumbnailSize {
+		thumbWidth, thumbHeight = previewThumbnailSize, height*previewThumbnailSize/width
+	} else if height > width && height > previewThumbnailSize {
+		thumbWidth, thumbHeight = width*previewThumbnailSize/height, previewThumbnailSize
+	}
+	if thumbWidth < 1 {
+		thumbWidth = 1
+	}
+	if thumbHeight < 1 {
+		thumbHeight = 1
+	}
+
+	// Nearest neighbour is plenty for a thumbnail this small
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		for x := 0; x < thumbWidth; x++ {
+			thumb.Set(x, y, src.At(bounds.Min.X+x*width/thumbWidth, bounds.Min.Y+y*height/thumbHeight))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: previewThumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// httpClient returns the client for fetching user supplied URLs, which only
+// reaches public addresses unless WHATSAPP_ALLOW_PRIVATE_URLS is set
+func (c *Client) httpClient(timeout time.Duration) *http.Client {
+	if c.cfg.WhatsApp.AllowPrivateURLs {
+		return &http.Client{Timeout: timeout}
+	}
+	return netguard.Client(timeout)
+}
+
+// firstNonEmpty returns the first value that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -2,8 +2,11 @@ package whatsapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"mime"
+	"net"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -12,79 +15,137 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
-	waProto "go.mau.fi/whatsmeow/binary/proto"
+)
+
+var (
+	// ErrClientNotReady is returned when sending while WhatsApp is not connected
+	ErrClientNotReady = errors.New("client not ready")
+	// ErrInvalidJID is returned when a recipient can't be parsed into a JID
+	ErrInvalidJID = errors.New("invalid JID")
 )
 
 type MessageRequest struct {
-	To      string `json:"to" binding:"required"`
-	Message string `json:"message" binding:"required"`
-	Type    string `json:"type,omitempty"` // text, image, document, audio, video
+	To          string `json:"to" form:"to" binding:"required"`
+	Message     string `json:"message" form:"message" binding:"required"`
+	Type        string `json:"type,omitempty" form:"type"`                 // text, image, document, audio, video
+	CountryCode string `json:"country_code,omitempty" form:"country_code"` // Overrides DEFAULT_COUNTRY_CODE for local numbers
+	SendAt      string `json:"send_at,omitempty" form:"send_at"`           // RFC3339, schedules the message instead of sending it now
+	LinkPreview *bool  `json:"link_preview,omitempty" form:"link_preview"` // false suppresses the preview, true attaches one for the first link
 }
 
 type MediaMessageRequest struct {
-	To       string `json:"to" binding:"required"`
-	Message  string `json:"message,omitempty"`
-	MediaURL string `json:"media_url" binding:"required"`
-	Type     string `json:"type" binding:"required"` // image, document, audio, video
-	FileName string `json:"file_name,omitempty"`
-	Caption  string `json:"caption,omitempty"`
+	To           string            `json:"to" form:"to" binding:"required"`
+	Message      string            `json:"message,omitempty" form:"message"`
+	MediaURL     string            `json:"media_url" form:"media_url" binding:"required"`
+	Type         string            `json:"type" form:"type" binding:"required"` // image, document, audio, video
+	FileName     string            `json:"file_name,omitempty" form:"file_name"`
+	Caption      string            `json:"caption,omitempty" form:"caption"`
+	CountryCode  string            `json:"country_code,omitempty" form:"country_code"`
+	MediaHeaders map[string]string `json:"media_headers,omitempty" form:"-"` // Extra headers for fetching media_url, e.g. Authorization
+	SendAt       string            `json:"send_at,omitempty" form:"send_at"` // RFC3339, schedules the message instead of sending it now
 }
 
 type LocationMessageRequest struct {
-	To        string  `json:"to" binding:"required"`
-	Latitude  float64 `json:"latitude" binding:"required"`
-	Longitude float64 `json:"longitude" binding:"required"`
-	Name      string  `json:"name,omitempty"`
-	Address   string  `json:"address,omitempty"`
+	To          string  `json:"to" form:"to" binding:"required"`
+	Latitude    float64 `json:"latitude" form:"latitude" binding:"required"`
+	Longitude   float64 `json:"longitude" form:"longitude" binding:"required"`
+	Name        string  `json:"name,omitempty" form:"name"`
+	Address     string  `json:"address,omitempty" form:"address"`
+	CountryCode string  `json:"country_code,omitempty" form:"country_code"`
 }
 
 type ContactMessageRequest struct {
-	To          string `json:"to" binding:"required"`
-	DisplayName string `json:"display_name" binding:"required"`
-	VCard       string `json:"vcard" binding:"required"`
+	To          string       `json:"to" form:"to" binding:"required"`
+	DisplayName string       `json:"display_name" form:"display_name" binding:"required_without=Contact"` // Defaults to contact.name
+	VCard       string       `json:"vcard" form:"vcard" binding:"required_without=Contact"`
+	Contact     *VCardFields `json:"contact,omitempty" form:"-" binding:"omitempty"` // Builds the vCard instead of sending a raw one
+	CountryCode string       `json:"country_code,omitempty" form:"country_code"`
+}
+
+// ContactCard is one contact of a contacts array message
+type ContactCard struct {
+	DisplayName string       `json:"display_name" binding:"required_without=Contact"`
+	VCard       string       `json:"vcard" binding:"required_without=Contact"`
+	Contact     *VCardFields `json:"contact,omitempty" binding:"omitempty"`
+}
+
+// VCardRequest is a card to check with POST /messages/contact/validate
+type VCardRequest struct {
+	VCard       string       `json:"vcard" binding:"required_without=Contact"`
+	Contact     *VCardFields `json:"contact,omitempty" binding:"omitempty"`
+	CountryCode string       `json:"country_code,omitempty"`
+}
+
+type ContactsArrayMessageRequest struct {
+	To          string        `json:"to" binding:"required"`
+	DisplayName string        `json:"display_name,omitempty"`                        // Shown above the cards, defaults to "N contacts"
+	Contacts    []ContactCard `json:"contacts" binding:"required,min=1,max=50,dive"` // Up to 50 cards
+	CountryCode string        `json:"country_code,omitempty"`
+}
+
+type EditMessageRequest struct {
+	Message string `json:"message" binding:"required"`
 }
 
 type MessageResponse struct {
-	Success   bool   `json:"success"`
-	MessageID string `json:"message_id,omitempty"`
-	Error     string `json:"error,omitempty"`
-	Timestamp int64  `json:"timestamp"`
+	Success    bool     `json:"success"`
+	MessageID  string   `json:"message_id,omitempty"`
+	MessageIDs []string `json:"message_ids,omitempty"` // Every message sent, for albums
+	Error      string   `json:"error,omitempty"`
+	Timestamp  int64    `json:"timestamp"`
+	Attempts   int      `json:"attempts,omitempty"` // Send attempts made, more than 1 after retries
 }
 
 // SendTextMessage sends a text message
 func (c *Client) SendTextMessage(to, message string) (*MessageResponse, error) {
+	return c.SendTextMessageWithPreview(to, message, nil)
+}
+
+// SendTextMessageWithPreview sends a text message with the link preview
+// turned off or on, nil leaves it as SendTextMessage does
+func (c *Client) SendTextMessageWithPreview(to, message string, linkPreview *bool) (*MessageResponse, error) {
+	return c.sendText(to, message, linkPreview, c.defaultRetry())
+}
+
+// SendTextMessageWithRetry is SendTextMessage retrying transient failures
+// as retry says instead of WHATSAPP_SEND_RETRY_*
+func (c *Client) SendTextMessageWithRetry(to, message string, retry RetryPolicy) (*MessageResponse, error) {
+	return c.sendText(to, message, nil, retry)
+}
+
+func (c *Client) sendText(to, message string, linkPreview *bool, retry RetryPolicy) (*MessageResponse, error) {
 	if !c.IsReady() {
 		return &MessageResponse{
 			Success:   false,
 			Error:     "WhatsApp client not ready",
 			Timestamp: time.Now().Unix(),
-		}, fmt.Errorf("client not ready")
+		}, ErrClientNotReady
 	}
 
 	// Parse JID
-	jid, err := c.parseJID(to)
+	jid, err := c.parseJID(to, "")
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
 			Error:     fmt.Sprintf("Invalid JID: %v", err),
 			Timestamp: time.Now().Unix(),
-		}, err
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
 	}
 
 	// Create message
-	msg := &waProto.Message{
-		Conversation: proto.String(message),
-	}
+	msg := c.textMessage(message, linkPreview)
 
 	// Send message
-	resp, err := c.client.SendMessage(context.Background(), jid, msg)
+	resp, attempts, err := c.sendWithPolicy(jid, msg, retry)
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
 			Error:     fmt.Sprintf("Failed to send message: %v", err),
 			Timestamp: time.Now().Unix(),
+			Attempts:  attempts,
 		}, err
 	}
 
@@ -92,9 +153,26 @@ func (c *Client) SendTextMessage(to, message string) (*MessageResponse, error) {
 		Success:   true,
 		MessageID: resp.ID,
 		Timestamp: resp.Timestamp.Unix(),
+		Attempts:  attempts,
 	}, nil
 }
 
+// UploadedMedia is media already uploaded to WhatsApp. It can be sent to any
+// number of recipients without downloading or uploading it again.
+type UploadedMedia struct {
+	Type   string
+	Upload whatsmeow.UploadResponse
+	Size   int
+}
+
+// mediaTypes maps request media types to whatsmeow upload types
+var mediaTypes = map[string]whatsmeow.MediaType{
+	"image":    whatsmeow.MediaImage,
+	"document": whatsmeow.MediaDocument,
+	"audio":    whatsmeow.MediaAudio,
+	"video":    whatsmeow.MediaVideo,
+}
+
 // SendMediaMessage sends a media message
 func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, error) {
 	if !c.IsReady() {
@@ -102,42 +180,107 @@ func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, e
 			Success:   false,
 			Error:     "WhatsApp client not ready",
 			Timestamp: time.Now().Unix(),
-		}, fmt.Errorf("client not ready")
+		}, ErrClientNotReady
 	}
 
 	// Parse JID
-	jid, err := c.parseJID(req.To)
+	jid, err := c.parseJID(req.To, "")
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
 			Error:     fmt.Sprintf("Invalid JID: %v", err),
 			Timestamp: time.Now().Unix(),
-		}, err
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
 	}
 
-	// Download media
-	mediaData, err := c.downloadMedia(req.MediaURL)
+	media, err := c.UploadMediaWithHeaders(req.MediaURL, req.Type, req.MediaHeaders)
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
-			Error:     fmt.Sprintf("Failed to download media: %v", err),
+			Error:     err.Error(),
 			Timestamp: time.Now().Unix(),
 		}, err
 	}
 
+	return c.sendUploadedMedia(jid, media, req.Caption, req.FileName, c.defaultRetry())
+}
+
+// UploadMedia downloads media from a URL and uploads it to WhatsApp once
+func (c *Client) UploadMedia(mediaURL, mediaType string) (*UploadedMedia, error) {
+	return c.UploadMediaWithHeaders(mediaURL, mediaType, nil)
+}
+
+// UploadMediaWithHeaders is UploadMedia with extra headers for the download,
+// on top of the configured defaults
+func (c *Client) UploadMediaWithHeaders(mediaURL, mediaType string, headers map[string]string) (*UploadedMedia, error) {
+	mediaType = strings.ToLower(mediaType)
+	waMediaType, ok := mediaTypes[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported media type: %s", mediaType)
+	}
+
+	// Download media
+	var mediaData []byte
+	err := c.withMediaRetry("download media", func() (err error) {
+		mediaData, err = c.downloadMedia(mediaURL, headers)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %w", err)
+	}
+
 	// Upload media
-	uploaded, err := c.client.Upload(context.Background(), mediaData, whatsmeow.MediaType(req.Type))
+	var uploaded whatsmeow.UploadResponse
+	err = c.withMediaRetry("upload media", func() (err error) {
+		uploaded, err = c.GetClient().Upload(context.Background(), mediaData, waMediaType)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload media: %w", err)
+	}
+
+	return &UploadedMedia{
+		Type:   mediaType,
+		Upload: uploaded,
+		Size:   len(mediaData),
+	}, nil
+}
+
+// SendUploadedMedia sends media previously uploaded with UploadMedia
+func (c *Client) SendUploadedMedia(to string, media *UploadedMedia, caption, fileName string) (*MessageResponse, error) {
+	return c.SendUploadedMediaWithRetry(to, media, caption, fileName, c.defaultRetry())
+}
+
+// SendUploadedMediaWithRetry is SendUploadedMedia retrying transient failures
+// as retry says instead of WHATSAPP_SEND_RETRY_*
+func (c *Client) SendUploadedMediaWithRetry(to string, media *UploadedMedia, caption, fileName string, retry RetryPolicy) (*MessageResponse, error) {
+	if !c.IsReady() {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "WhatsApp client not ready",
+			Timestamp: time.Now().Unix(),
+		}, ErrClientNotReady
+	}
+
+	// Parse JID
+	jid, err := c.parseJID(to, "")
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
-			Error:     fmt.Sprintf("Failed to upload media: %v", err),
+			Error:     fmt.Sprintf("Invalid JID: %v", err),
 			Timestamp: time.Now().Unix(),
-		}, err
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
 	}
 
+	return c.sendUploadedMedia(jid, media, caption, fileName, retry)
+}
+
+func (c *Client) sendUploadedMedia(jid types.JID, media *UploadedMedia, caption, fileName string, retry RetryPolicy) (*MessageResponse, error) {
+	uploaded := media.Upload
+
 	// Create message based on type
 	var msg *waProto.Message
-	switch strings.ToLower(req.Type) {
+	switch media.Type {
 	case "image":
 		msg = &waProto.Message{
 			ImageMessage: &waProto.ImageMessage{
@@ -146,12 +289,11 @@ func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, e
 				MediaKey:      uploaded.MediaKey,
 				FileEncSha256: uploaded.FileEncSHA256,
 				FileSha256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(uint64(len(mediaData))),
-				Caption:       proto.String(req.Caption),
+				FileLength:    proto.Uint64(uint64(media.Size)),
+				Caption:       proto.String(caption),
 			},
 		}
 	case "document":
-		fileName := req.FileName
 		if fileName == "" {
 			fileName = "document"
 		}
@@ -166,10 +308,10 @@ func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, e
 				MediaKey:      uploaded.MediaKey,
 				FileEncSha256: uploaded.FileEncSHA256,
 				FileSha256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(uint64(len(mediaData))),
+				FileLength:    proto.Uint64(uint64(media.Size)),
 				FileName:      proto.String(fileName),
 				Mimetype:      proto.String(mimeType),
-				Caption:       proto.String(req.Caption),
+				Caption:       proto.String(caption),
 			},
 		}
 	case "audio":
@@ -180,7 +322,7 @@ func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, e
 				MediaKey:      uploaded.MediaKey,
 				FileEncSha256: uploaded.FileEncSHA256,
 				FileSha256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(uint64(len(mediaData))),
+				FileLength:    proto.Uint64(uint64(media.Size)),
 				Mimetype:      proto.String("audio/ogg; codecs=opus"),
 			},
 		}
@@ -192,8 +334,8 @@ func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, e
 				MediaKey:      uploaded.MediaKey,
 				FileEncSha256: uploaded.FileEncSHA256,
 				FileSha256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(uint64(len(mediaData))),
-				Caption:       proto.String(req.Caption),
+				FileLength:    proto.Uint64(uint64(media.Size)),
+				Caption:       proto.String(caption),
 			},
 		}
 	default:
@@ -201,16 +343,17 @@ func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, e
 			Success:   false,
 			Error:     "Unsupported media type",
 			Timestamp: time.Now().Unix(),
-		}, fmt.Errorf("unsupported media type: %s", req.Type)
+		}, fmt.Errorf("unsupported media type: %s", media.Type)
 	}
 
 	// Send message
-	resp, err := c.client.SendMessage(context.Background(), jid, msg)
+	resp, attempts, err := c.sendWithPolicy(jid, msg, retry)
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
 			Error:     fmt.Sprintf("Failed to send message: %v", err),
 			Timestamp: time.Now().Unix(),
+			Attempts:  attempts,
 		}, err
 	}
 
@@ -218,6 +361,7 @@ func (c *Client) SendMediaMessage(req *MediaMessageRequest) (*MessageResponse, e
 		Success:   true,
 		MessageID: resp.ID,
 		Timestamp: resp.Timestamp.Unix(),
+		Attempts:  attempts,
 	}, nil
 }
 
@@ -228,17 +372,17 @@ func (c *Client) SendLocationMessage(req *LocationMessageRequest) (*MessageRespo
 			Success:   false,
 			Error:     "WhatsApp client not ready",
 			Timestamp: time.Now().Unix(),
-		}, fmt.Errorf("client not ready")
+		}, ErrClientNotReady
 	}
 
 	// Parse JID
-	jid, err := c.parseJID(req.To)
+	jid, err := c.parseJID(req.To, "")
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
 			Error:     fmt.Sprintf("Invalid JID: %v", err),
 			Timestamp: time.Now().Unix(),
-		}, err
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
 	}
 
 	// Create location message
@@ -252,7 +396,7 @@ func (c *Client) SendLocationMessage(req *LocationMessageRequest) (*MessageRespo
 	}
 
 	// Send message
-	resp, err := c.client.SendMessage(context.Background(), jid, msg)
+	resp, err := c.sendWithRetry(jid, msg)
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
@@ -275,29 +419,103 @@ func (c *Client) SendContactMessage(req *ContactMessageRequest) (*MessageRespons
 			Success:   false,
 			Error:     "WhatsApp client not ready",
 			Timestamp: time.Now().Unix(),
-		}, fmt.Errorf("client not ready")
+		}, ErrClientNotReady
 	}
 
 	// Parse JID
-	jid, err := c.parseJID(req.To)
+	jid, err := c.parseJID(req.To, "")
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
 			Error:     fmt.Sprintf("Invalid JID: %v", err),
 			Timestamp: time.Now().Unix(),
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	vcard, err := ResolveVCard(req.VCard, req.Contact, c.countryCode(req.CountryCode))
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now().Unix(),
 		}, err
 	}
 
 	// Create contact message
 	msg := &waProto.Message{
 		ContactMessage: &waProto.ContactMessage{
-			DisplayName: proto.String(req.DisplayName),
-			Vcard:       proto.String(req.VCard),
+			DisplayName: proto.String(contactDisplayName(req.DisplayName, req.Contact)),
+			Vcard:       proto.String(vcard),
+		},
+	}
+
+	// Send message
+	resp, err := c.sendWithRetry(jid, msg)
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Failed to send message: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, err
+	}
+
+	return &MessageResponse{
+		Success:   true,
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp.Unix(),
+	}, nil
+}
+
+// SendContactsArray sends several contact cards as one message
+func (c *Client) SendContactsArray(req *ContactsArrayMessageRequest) (*MessageResponse, error) {
+	if !c.IsReady() {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "WhatsApp client not ready",
+			Timestamp: time.Now().Unix(),
+		}, ErrClientNotReady
+	}
+
+	// Parse JID
+	jid, err := c.parseJID(req.To, "")
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Invalid JID: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	contacts := make([]*waProto.ContactMessage, 0, len(req.Contacts))
+	for i, contact := range req.Contacts {
+		vcard, err := ResolveVCard(contact.VCard, contact.Contact, c.countryCode(req.CountryCode))
+		if err != nil {
+			return &MessageResponse{
+				Success:   false,
+				Error:     fmt.Sprintf("Contact %d: %v", i+1, err),
+				Timestamp: time.Now().Unix(),
+			}, fmt.Errorf("contact %d: %w", i+1, err)
+		}
+		contacts = append(contacts, &waProto.ContactMessage{
+			DisplayName: proto.String(contactDisplayName(contact.DisplayName, contact.Contact)),
+			Vcard:       proto.String(vcard),
+		})
+	}
+
+	displayName := req.DisplayName
+	if displayName == "" {
+		displayName = fmt.Sprintf("%d contacts", len(contacts))
+	}
+
+	msg := &waProto.Message{
+		ContactsArrayMessage: &waProto.ContactsArrayMessage{
+			DisplayName: proto.String(displayName),
+			Contacts:    contacts,
 		},
 	}
 
 	// Send message
-	resp, err := c.client.SendMessage(context.Background(), jid, msg)
+	resp, err := c.sendWithRetry(jid, msg)
 	if err != nil {
 		return &MessageResponse{
 			Success:   false,
@@ -313,40 +531,322 @@ func (c *Client) SendContactMessage(req *ContactMessageRequest) (*MessageRespons
 	}, nil
 }
 
-// parseJID parses a phone number or JID string into a types.JID
-func (c *Client) parseJID(to string) (types.JID, error) {
+// EditWindow is how long after sending WhatsApp still accepts edits
+const EditWindow = 15 * time.Minute
+
+// EditMessage replaces the text of a previously sent message
+func (c *Client) EditMessage(chat, messageID, newText string) (*MessageResponse, error) {
+	if !c.IsReady() {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "WhatsApp client not ready",
+			Timestamp: time.Now().Unix(),
+		}, ErrClientNotReady
+	}
+
+	// Parse JID
+	jid, err := c.parseJID(chat, "")
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Invalid JID: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	// Create edit message
+	msg := c.GetClient().BuildEdit(jid, types.MessageID(messageID), &waProto.Message{
+		Conversation: proto.String(newText),
+	})
+
+	// Send message
+	resp, err := c.sendWithRetry(jid, msg)
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Failed to edit message: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, err
+	}
+
+	return &MessageResponse{
+		Success:   true,
+		MessageID: messageID,
+		Timestamp: resp.Timestamp.Unix(),
+	}, nil
+}
+
+// MarkRead sends read receipts for messages a sender sent in a chat
+func (c *Client) MarkRead(chat, sender string, messageIDs []string) error {
+	if !c.IsReady() {
+		return ErrClientNotReady
+	}
+
+	chatJID, err := types.ParseJID(chat)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+	senderJID, err := types.ParseJID(sender)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	ids := make([]types.MessageID, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = types.MessageID(id)
+	}
+
+	return c.GetClient().MarkRead(ids, time.Now(), chatJID, senderJID)
+}
+
+// IsTransientError reports whether a failed send is worth retrying. Network
+// problems and timeouts are transient; invalid recipients and unsupported
+// payloads are permanent.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, ErrInvalidJID) {
+		return false
+	}
+
+	if errors.Is(err, ErrClientNotReady) ||
+		errors.Is(err, whatsmeow.ErrNotConnected) ||
+		errors.Is(err, whatsmeow.ErrIQTimedOut) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// parseJID parses a phone number or JID string into a types.JID. Phone numbers,
+// user JIDs (@s.whatsapp.net), LIDs (@lid), groups (@g.us), broadcast lists and
+// newsletters are accepted. Device suffixes are stripped from user addresses so
+// messages go to the account rather than a single linked device. Local phone
+// numbers get countryCode, or DEFAULT_COUNTRY_CODE when it is empty.
+func (c *Client) parseJID(to, countryCode string) (types.JID, error) {
+	to = strings.TrimSpace(to)
+
 	if strings.Contains(to, "@") {
 		// Already a JID
-		return types.ParseJID(to)
+		jid, err := types.ParseJID(to)
+		if err != nil {
+			return jid, err
+		}
+
+		switch jid.Server {
+		case types.DefaultUserServer, types.HiddenUserServer:
+			if jid.User == "" {
+				return jid, fmt.Errorf("missing user in %q", to)
+			}
+			// Phone number addressing is what this whatsmeow version sends
+			// most reliably, use it when the LID's number is known
+			if jid.Server == types.HiddenUserServer {
+				if phone, ok := c.phoneForLID(jid); ok {
+					return phone, nil
+				}
+			}
+			return jid.ToNonAD(), nil
+		case types.GroupServer, types.BroadcastServer, types.NewsletterServer:
+			return jid, nil
+		default:
+			return jid, fmt.Errorf("unsupported JID server %q", jid.Server)
+		}
 	}
 
 	// Phone number, convert to JID
-	phoneNumber := strings.ReplaceAll(to, "+", "")
+	phoneNumber := ApplyCountryCode(to, c.countryCode(countryCode))
+	phoneNumber = strings.ReplaceAll(phoneNumber, "+", "")
 	phoneNumber = strings.ReplaceAll(phoneNumber, " ", "")
 	phoneNumber = strings.ReplaceAll(phoneNumber, "-", "")
 
-	if !strings.HasSuffix(phoneNumber, "@s.whatsapp.net") {
-		phoneNumber += "@s.whatsapp.net"
+	if phoneNumber == "" || strings.Trim(phoneNumber, "0123456789") != "" {
+		return types.JID{}, fmt.Errorf("invalid phone number %q", to)
+	}
+
+	return types.NewJID(phoneNumber, types.DefaultUserServer), nil
+}
+
+// countryCode returns the request's country code, or DEFAULT_COUNTRY_CODE
+func (c *Client) countryCode(override string) string {
+	if override != "" {
+		return override
 	}
+	return c.cfg.WhatsApp.DefaultCountryCode
+}
 
-	return types.ParseJID(phoneNumber)
+// WithCountryCode returns the JID of a phone number read with countryCode in
+// place of DEFAULT_COUNTRY_CODE, so sending to it doesn't apply the default on
+// top. Input that doesn't parse is returned as is for the send to report.
+func (c *Client) WithCountryCode(to, countryCode string) string {
+	jid, err := c.parseJID(to, countryCode)
+	if err != nil {
+		return to
+	}
+	return jid.String()
 }
 
-// downloadMedia downloads media from URL
-func (c *Client) downloadMedia(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+// NormalizeJID returns the JID a send to the given phone number or JID goes
+// to, so differently written addresses of the same chat compare equal
+func (c *Client) NormalizeJID(to string) (string, error) {
+	jid, err := c.parseJID(to, "")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+	return jid.String(), nil
+}
+
+// minInternationalLength is the shortest number assumed to already carry a country code
+const minInternationalLength = 10
+
+// ApplyCountryCode prefixes a local phone number with countryCode. A leading
+// trunk "0" is replaced, and numbers too short to include a country code get
+// it prepended. JIDs and numbers written with a leading "+" are left as is.
+func ApplyCountryCode(phone, countryCode string) string {
+	countryCode = strings.TrimPrefix(strings.TrimSpace(countryCode), "+")
+	phone = strings.TrimSpace(phone)
+	if countryCode == "" || phone == "" || strings.Contains(phone, "@") || strings.HasPrefix(phone, "+") {
+		return phone
+	}
+
+	digits := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+
+	if strings.HasPrefix(digits, "0") {
+		return countryCode + strings.TrimLeft(digits, "0")
+	}
+	if len(digits) < minInternationalLength {
+		return countryCode + digits
+	}
+	return digits
+}
+
+// mediaStatusError is returned when the media URL answers with a non-200 status
+type mediaStatusError struct {
+	StatusCode int
+}
+
+func (e *mediaStatusError) Error() string {
+	return fmt.Sprintf("failed to download media: status %d", e.StatusCode)
+}
+
+// isTransientMediaError reports whether a media download or upload is worth
+// retrying. Server errors and throttling are, missing or forbidden media isn't.
+func isTransientMediaError(err error) bool {
+	var statusErr *mediaStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return IsTransientError(err)
+}
+
+// RetryPolicy is how a send retries transient failures: up to Attempts more
+// tries after the first, waiting Backoff times the attempt number in between
+type RetryPolicy struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// defaultRetry is the policy from WHATSAPP_SEND_RETRY_*
+func (c *Client) defaultRetry() RetryPolicy {
+	return RetryPolicy{
+		Attempts: c.cfg.WhatsApp.SendRetryAttempts,
+		Backoff:  time.Duration(c.cfg.WhatsApp.SendRetryBackoffMS) * time.Millisecond,
+	}
+}
+
+// messageSender is the part of whatsmeow.Client sends go through, so the
+// retry loop can run against a stub
+type messageSender interface {
+	SendMessage(ctx context.Context, to types.JID, message *waProto.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error)
+}
+
+// sendWithRetry sends msg with the WHATSAPP_SEND_RETRY_* policy
+func (c *Client) sendWithRetry(jid types.JID, msg *waProto.Message) (whatsmeow.SendResponse, error) {
+	resp, _, err := c.sendWithPolicy(jid, msg, c.defaultRetry())
+	return resp, err
+}
+
+// sendWithPolicy sends msg, retrying transient failures as retry says, and
+// returns the number of attempts made. It is the only place sends are
+// retried, callers must not retry on top of it.
+func (c *Client) sendWithPolicy(jid types.JID, msg *waProto.Message, retry RetryPolicy) (whatsmeow.SendResponse, int, error) {
+	// Every send goes through here, so the allowlist covers direct,
+	// broadcast and scheduled messages alike
+	if !c.RecipientAllowed(jid) {
+		return whatsmeow.SendResponse{}, 0, fmt.Errorf("%w: %s", ErrRecipientNotAllowed, jid)
+	}
+
+	return sendAttempts(c.GetClient(), jid, msg, c.GetClient().GenerateMessageID(), retry)
+}
+
+// sendAttempts sends msg under the message ID id until it succeeds, fails
+// permanently or runs out of retries. The ID stays the same across attempts,
+// so if a failed attempt actually reached WhatsApp the retry is deduplicated
+// instead of delivered twice.
+func sendAttempts(sender messageSender, jid types.JID, msg *waProto.Message, id types.MessageID, retry RetryPolicy) (whatsmeow.SendResponse, int, error) {
+	extra := whatsmeow.SendRequestExtra{ID: id}
+
+	attempts := 0
+	for {
+		attempts++
+		resp, err := sender.SendMessage(context.Background(), jid, msg, extra)
+		if err == nil || attempts > retry.Attempts || !IsTransientError(err) {
+			return resp, attempts, err
+		}
+
+		logrus.Warnf("Failed to send message to %s (attempt %d), retrying: %v", jid, attempts, err)
+		time.Sleep(retry.Backoff * time.Duration(attempts))
+	}
+}
+
+// withMediaRetry runs fn, retrying transient failures with a linear backoff
+func (c *Client) withMediaRetry(op string, fn func() error) error {
+	backoff := time.Duration(c.cfg.WhatsApp.MediaRetryBackoffMS) * time.Millisecond
+
+	attempts := 0
+	for {
+		attempts++
+		err := fn()
+		if err == nil || attempts > c.cfg.WhatsApp.MediaRetryAttempts || !isTransientMediaError(err) {
+			return err
+		}
+
+		logrus.Warnf("Failed to %s (attempt %d), retrying: %v", op, attempts, err)
+		time.Sleep(backoff * time.Duration(attempts))
+	}
+}
+
+// downloadMedia downloads media from URL. Request headers override the
+// configured defaults.
+func (c *Client) downloadMedia(url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cfg.WhatsApp.MediaUserAgent != "" {
+		req.Header.Set("User-Agent", c.cfg.WhatsApp.MediaUserAgent)
+	}
+	for name, value := range c.cfg.WhatsApp.ParseMediaHeaders() {
+		req.Header.Set(name, value)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.httpClient(0).Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download media: status %d", resp.StatusCode)
+		return nil, &mediaStatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Read response body
-	data := make([]byte, resp.ContentLength)
-	_, err = resp.Body.Read(data)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -354,7 +854,55 @@ func (c *Client) downloadMedia(url string) ([]byte, error) {
 	return data, nil
 }
 
+// quotedContext returns the id and text of the message a reply quotes, or
+// empty strings when msg isn't a reply
+func quotedContext(msg *waProto.Message) (string, string) {
+	var info *waProto.ContextInfo
+	switch {
+	case msg.GetExtendedTextMessage() != nil:
+		info = msg.GetExtendedTextMessage().GetContextInfo()
+	case msg.GetImageMessage() != nil:
+		info = msg.GetImageMessage().GetContextInfo()
+	case msg.GetVideoMessage() != nil:
+		info = msg.GetVideoMessage().GetContextInfo()
+	case msg.GetAudioMessage() != nil:
+		info = msg.GetAudioMessage().GetContextInfo()
+	case msg.GetDocumentMessage() != nil:
+		info = msg.GetDocumentMessage().GetContextInfo()
+	case msg.GetStickerMessage() != nil:
+		info = msg.GetStickerMessage().GetContextInfo()
+	case msg.GetLocationMessage() != nil:
+		info = msg.GetLocationMessage().GetContextInfo()
+	case msg.GetContactMessage() != nil:
+		info = msg.GetContactMessage().GetContextInfo()
+	}
+	if info.GetStanzaId() == "" {
+		return "", ""
+	}
+
+	return info.GetStanzaId(), messageText(info.GetQuotedMessage())
+}
+
+// messageText returns the text or caption of a message
+func messageText(msg *waProto.Message) string {
+	switch {
+	case msg.GetConversation() != "":
+		return msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		return msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetCaption()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetCaption()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage().GetCaption()
+	case msg.GetContactMessage() != nil:
+		return msg.GetContactMessage().GetDisplayName()
+	}
+	return ""
+}
+
 // GenerateMessageID generates a unique message ID
 func (c *Client) GenerateMessageID() string {
 	return uuid.New().String()
-}
\ No newline at end of file
+}
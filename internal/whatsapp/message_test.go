@@ -0,0 +1,216 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"gowa-broadcast/internal/config"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// stubSender fails the first len(errs) sends with those errors, then succeeds
+type stubSender struct {
+	errs []error
+	ids  []types.MessageID
+}
+
+func (s *stubSender) SendMessage(ctx context.Context, to types.JID, message *waProto.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	id := extra[0].ID
+	s.ids = append(s.ids, id)
+	if len(s.ids) <= len(s.errs) {
+		return whatsmeow.SendResponse{}, s.errs[len(s.ids)-1]
+	}
+	return whatsmeow.SendResponse{ID: id}, nil
+}
+
+func TestSendAttemptsRetriesTransientErrors(t *testing.T) {
+	sender := &stubSender{errs: []error{io.EOF, io.ErrUnexpectedEOF}}
+	jid := types.NewJID("6281234567890", types.DefaultUserServer)
+
+	resp, attempts, err := sendAttempts(sender, jid, &waProto.Message{}, "ID1", RetryPolicy{Attempts: 2})
+	if err != nil {
+		t.Fatalf("sendAttempts: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+	if resp.ID != "ID1" {
+		t.Errorf("resp.ID = %q, want ID1", resp.ID)
+	}
+	for i, id := range sender.ids {
+		if id != "ID1" {
+			t.Errorf("attempt %d sent as %q, want the same ID every time", i+1, id)
+		}
+	}
+}
+
+func TestSendAttemptsGivesUp(t *testing.T) {
+	jid := types.NewJID("6281234567890", types.DefaultUserServer)
+	permanent := errors.New("not on WhatsApp")
+
+	tests := []struct {
+		name         string
+		errs         []error
+		retries      int
+		wantErr      error
+		wantAttempts int
+	}{
+		{"permanent error", []error{permanent}, 2, permanent, 1},
+		{"out of retries", []error{io.EOF, io.EOF, io.EOF}, 2, io.EOF, 3},
+		{"no retries", []error{io.EOF}, 0, io.EOF, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &stubSender{errs: tt.errs}
+			_, attempts, err := sendAttempts(sender, jid, &waProto.Message{}, "ID1", RetryPolicy{Attempts: tt.retries})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestParseJIDCountryCode(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WhatsApp.DefaultCountryCode = "62"
+	c := &Client{cfg: cfg}
+
+	tests := []struct {
+		to          string
+		countryCode string
+		want        string
+	}{
+		{"081234567890", "", "6281234567890@s.whatsapp.net"},
+		{"081234567890", "65", "6581234567890@s.whatsapp.net"},
+		{"91234567", "65", "6591234567@s.whatsapp.net"},
+		{"+6591234567", "", "6591234567@s.whatsapp.net"},
+		{"6281234567890", "65", "6281234567890@s.whatsapp.net"},
+		{"6281234567890@s.whatsapp.net", "65", "6281234567890@s.whatsapp.net"},
+	}
+
+	for _, tt := range tests {
+		jid, err := c.parseJID(tt.to, tt.countryCode)
+		if err != nil {
+			t.Errorf("parseJID(%q, %q): %v", tt.to, tt.countryCode, err)
+			continue
+		}
+		if jid.String() != tt.want {
+			t.Errorf("parseJID(%q, %q) = %s, want %s", tt.to, tt.countryCode, jid, tt.want)
+		}
+	}
+}
+
+// An override applied by the handler must not get the default on top when
+// the send parses the recipient again
+func TestWithCountryCodeAppliesOnce(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WhatsApp.DefaultCountryCode = "62"
+	c := &Client{cfg: cfg}
+
+	to := c.WithCountryCode("91234567", "65")
+	jid, err := c.parseJID(to, "")
+	if err != nil {
+		t.Fatalf("parseJID(%q): %v", to, err)
+	}
+	if jid.String() != "6591234567@s.whatsapp.net" {
+		t.Errorf("sent to %s, want 6591234567@s.whatsapp.net", jid)
+	}
+
+	if got := c.WithCountryCode("not a number", "65"); got != "not a number" {
+		t.Errorf("WithCountryCode kept %q, want the input unchanged", got)
+	}
+}
+
+func TestApplyCountryCode(t *testing.T) {
+	tests := []struct {
+		phone, countryCode, want string
+	}{
+		{"08123456789", "62", "628123456789"},
+		{"0812-3456-789", "+62", "628123456789"},
+		{"91234567", "65", "6591234567"},
+		{"6281234567890", "62", "6281234567890"},
+		{"+6591234567", "62", "+6591234567"},
+		{"120363000000000000@g.us", "62", "120363000000000000@g.us"},
+		{"08123456789", "", "08123456789"},
+	}
+
+	for _, tt := range tests {
+		if got := ApplyCountryCode(tt.phone, tt.countryCode); got != tt.want {
+			t.Errorf("ApplyCountryCode(%q, %q) = %q, want %q", tt.phone, tt.countryCode, got, tt.want)
+		}
+	}
+}
+
+func TestParseJIDAddressing(t *testing.T) {
+	c := &Client{cfg: &config.Config{}}
+
+	tests := []struct {
+		to      string
+		want    string
+		wantErr bool
+	}{
+		{"6281234567890@s.whatsapp.net", "6281234567890@s.whatsapp.net", false},
+		{"6281234567890:12@s.whatsapp.net", "6281234567890@s.whatsapp.net", false},
+		{"123456789012345@lid", "123456789012345@lid", false},
+		{"123456789012345:3@lid", "123456789012345@lid", false},
+		{"120363000000000000@g.us", "120363000000000000@g.us", false},
+		{"status@broadcast", "status@broadcast", false},
+		{"@s.whatsapp.net", "", true},
+		{"@lid", "", true},
+		{"6281234567890@example.com", "", true},
+		{"not a number", "", true},
+	}
+
+	for _, tt := range tests {
+		jid, err := c.parseJID(tt.to, "")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseJID(%q) = %s, want an error", tt.to, jid)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseJID(%q): %v", tt.to, err)
+			continue
+		}
+		if jid.String() != tt.want {
+			t.Errorf("parseJID(%q) = %s, want %s", tt.to, jid, tt.want)
+		}
+	}
+}
+
+func TestParseJIDMapsKnownLIDs(t *testing.T) {
+	c := &Client{cfg: &config.Config{}}
+	lid := types.NewJID("123456789012345", types.HiddenUserServer)
+	phone := types.NewJID("6281234567890", types.DefaultUserServer)
+
+	c.rememberParticipantLIDs([]types.GroupParticipant{
+		{JID: phone, LID: lid},
+		{JID: types.NewJID("6289876543210", types.DefaultUserServer)}, // No LID reported
+	})
+
+	jid, err := c.parseJID("123456789012345@lid", "")
+	if err != nil {
+		t.Fatalf("parseJID: %v", err)
+	}
+	if jid != phone {
+		t.Errorf("parseJID(known LID) = %s, want %s", jid, phone)
+	}
+
+	jid, err = c.parseJID("999999999999999@lid", "")
+	if err != nil {
+		t.Fatalf("parseJID: %v", err)
+	}
+	if jid.Server != types.HiddenUserServer {
+		t.Errorf("parseJID(unknown LID) = %s, want it sent to the LID", jid)
+	}
+}
@@ -0,0 +1,95 @@
+package whatsapp
+
+import (
+	"fmt"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ContactPresence is the last known presence of a contact
+type ContactPresence struct {
+	JID       string     `json:"jid"`
+	Online    bool       `json:"online"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+	Hidden    bool       `json:"last_seen_hidden"` // Offline with no last seen, the contact's privacy settings hide it
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// SubscribePresence asks WhatsApp to send presence updates for a contact.
+// Updates only arrive while we're marked available, and only when the
+// contact's privacy settings allow it.
+func (c *Client) SubscribePresence(jid string) error {
+	if !c.IsReady() {
+		return ErrClientNotReady
+	}
+
+	targetJID, err := c.parseJID(jid, "")
+	if err != nil {
+		return err
+	}
+
+	if err := c.GetClient().SubscribePresence(targetJID); err != nil {
+		return fmt.Errorf("failed to subscribe to presence: %v", err)
+	}
+	return nil
+}
+
+// GetPresence returns the last presence received for a contact, or nil if
+// none has arrived yet
+func (c *Client) GetPresence(jid string) (*ContactPresence, error) {
+	targetJID, err := c.parseJID(jid, "")
+	if err != nil {
+		return nil, err
+	}
+
+	c.contactPresenceMu.RLock()
+	defer c.contactPresenceMu.RUnlock()
+
+	presence, ok := c.contactPresence[targetJID.ToNonAD().String()]
+	if !ok {
+		return nil, nil
+	}
+	copied := *presence
+	return &copied, nil
+}
+
+// handlePresence caches a presence update and stores it on matching contacts
+func (c *Client) handlePresence(evt *events.Presence) {
+	jid := evt.From.ToNonAD().String()
+	presence := &ContactPresence{
+		JID:       jid,
+		Online:    !evt.Unavailable,
+		UpdatedAt: time.Now(),
+	}
+	if !evt.LastSeen.IsZero() {
+		lastSeen := evt.LastSeen
+		presence.LastSeen = &lastSeen
+	} else if evt.Unavailable {
+		presence.Hidden = true
+	}
+
+	c.contactPresenceMu.Lock()
+	if c.contactPresence == nil {
+		c.contactPresence = make(map[string]*ContactPresence)
+	}
+	c.contactPresence[jid] = presence
+	c.contactPresenceMu.Unlock()
+
+	updates := map[string]interface{}{
+		"is_online":           presence.Online,
+		"presence_updated_at": presence.UpdatedAt,
+	}
+	if presence.LastSeen != nil {
+		updates["last_seen"] = *presence.LastSeen
+	} else if presence.Online {
+		updates["last_seen"] = presence.UpdatedAt
+	}
+
+	if err := c.db.Model(&database.Contact{}).Where("jid = ?", jid).Updates(updates).Error; err != nil {
+		logrus.Errorf("Failed to store presence of %s: %v", jid, err)
+	}
+}
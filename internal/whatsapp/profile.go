@@ -0,0 +1,101 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/types"
+)
+
+type ProfileInfo struct {
+	JID        string `json:"jid"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	PictureID  string `json:"picture_id,omitempty"`
+	PictureURL string `json:"picture_url,omitempty"`
+}
+
+// GetProfile returns the connected account's name, status and picture
+func (c *Client) GetProfile() (*ProfileInfo, error) {
+	if !c.IsReady() {
+		return nil, ErrClientNotReady
+	}
+	if c.GetClient().Store.ID == nil {
+		return nil, fmt.Errorf("not logged in")
+	}
+
+	own := c.GetClient().Store.ID.ToNonAD()
+	profile := &ProfileInfo{
+		JID:  own.String(),
+		Name: c.GetClient().Store.PushName,
+	}
+
+	info, err := c.GetClient().GetUserInfo([]types.JID{own})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %v", err)
+	}
+	if user, ok := info[own]; ok {
+		profile.Status = user.Status
+		profile.PictureID = user.PictureID
+	}
+
+	// A missing picture is not an error
+	picture, err := c.GetClient().GetProfilePictureInfo(own, &whatsmeow.GetProfilePictureParams{})
+	if err == nil && picture != nil {
+		profile.PictureID = picture.ID
+		profile.PictureURL = picture.URL
+	} else if err != nil && !errors.Is(err, whatsmeow.ErrProfilePictureNotSet) {
+		return nil, fmt.Errorf("failed to get profile picture: %v", err)
+	}
+
+	return profile, nil
+}
+
+// SetProfileName changes the push name shown to other users
+func (c *Client) SetProfileName(name string) error {
+	if !c.IsReady() {
+		return ErrClientNotReady
+	}
+
+	if err := c.GetClient().SendAppState(appstate.BuildSettingPushName(name)); err != nil {
+		return fmt.Errorf("failed to set profile name: %v", err)
+	}
+
+	c.GetClient().Store.PushName = name
+	if err := c.GetClient().Store.Save(); err != nil {
+		return fmt.Errorf("failed to save profile name: %v", err)
+	}
+	return nil
+}
+
+// SetProfileStatus changes the "about" text of the account
+func (c *Client) SetProfileStatus(status string) error {
+	if !c.IsReady() {
+		return ErrClientNotReady
+	}
+
+	if err := c.GetClient().SetStatusMessage(status); err != nil {
+		return fmt.Errorf("failed to set profile status: %v", err)
+	}
+	return nil
+}
+
+// SetProfilePicture changes the account picture and returns the new picture
+// ID. The image should be a JPEG, nil removes the picture.
+func (c *Client) SetProfilePicture(image []byte) (string, error) {
+	if !c.IsReady() {
+		return "", ErrClientNotReady
+	}
+
+	// Without a target JID the picture query applies to our own account
+	pictureID, err := c.GetClient().SetGroupPhoto(types.EmptyJID, image)
+	if err != nil {
+		if errors.Is(err, whatsmeow.ErrInvalidImageFormat) {
+			return "", fmt.Errorf("profile picture must be a JPEG image")
+		}
+		return "", fmt.Errorf("failed to set profile picture: %v", err)
+	}
+	return pictureID, nil
+}
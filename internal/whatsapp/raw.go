@@ -0,0 +1,92 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrInvalidRawMessage is returned when a raw message can't be decoded or
+// isn't allowed
+var ErrInvalidRawMessage = errors.New("invalid raw message")
+
+type RawMessageRequest struct {
+	To          string          `json:"to" binding:"required"`
+	Message     json.RawMessage `json:"message" binding:"required"` // waProto.Message in protobuf JSON form
+	CountryCode string          `json:"country_code,omitempty"`
+}
+
+// ParseRawMessage decodes a waProto.Message from its protobuf JSON form.
+// Protocol level messages are rejected, they can revoke messages, change
+// disappearing timers or break encryption sessions.
+func ParseRawMessage(raw []byte) (*waProto.Message, error) {
+	msg := &waProto.Message{}
+	if err := protojson.Unmarshal(raw, msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRawMessage, err)
+	}
+
+	if proto.Size(msg) == 0 {
+		return nil, fmt.Errorf("%w: message is empty", ErrInvalidRawMessage)
+	}
+
+	switch {
+	case msg.GetProtocolMessage() != nil:
+		return nil, fmt.Errorf("%w: protocolMessage is not allowed", ErrInvalidRawMessage)
+	case msg.GetSenderKeyDistributionMessage() != nil:
+		return nil, fmt.Errorf("%w: senderKeyDistributionMessage is not allowed", ErrInvalidRawMessage)
+	case msg.GetDeviceSentMessage() != nil:
+		return nil, fmt.Errorf("%w: deviceSentMessage is not allowed", ErrInvalidRawMessage)
+	}
+
+	return msg, nil
+}
+
+// SendRawMessage sends a message given as protobuf JSON, for message types
+// without a typed endpoint
+func (c *Client) SendRawMessage(to string, raw []byte) (*MessageResponse, error) {
+	msg, err := ParseRawMessage(raw)
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     err.Error(),
+			Timestamp: time.Now().Unix(),
+		}, err
+	}
+
+	if !c.IsReady() {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "WhatsApp client not ready",
+			Timestamp: time.Now().Unix(),
+		}, ErrClientNotReady
+	}
+
+	jid, err := c.parseJID(to, "")
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Invalid JID: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	resp, err := c.sendWithRetry(jid, msg)
+	if err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Failed to send message: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, err
+	}
+
+	return &MessageResponse{
+		Success:   true,
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp.Unix(),
+	}, nil
+}
@@ -0,0 +1,62 @@
+package whatsapp
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// RawEventHandler receives every whatsmeow event before it is handled
+type RawEventHandler func(event RawEvent)
+
+// RawEvent is a whatsmeow event as forwarded to "raw" webhook subscribers
+type RawEvent struct {
+	Type  string      `json:"type"` // Event type in snake case, e.g. receipt, chat_presence, group_info
+	Event interface{} `json:"event"`
+}
+
+// SetRawEventHandler forwards every whatsmeow event to h. h runs on the
+// event loop, so it must not block.
+func (c *Client) SetRawEventHandler(h RawEventHandler) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.rawEvents = h
+}
+
+// forwardsRaw reports whether an event may be forwarded to raw subscribers.
+// Pairing events carry the QR codes that link a device to the account, and
+// app state and identity events expose the account's synced state and keys.
+func forwardsRaw(evt interface{}) bool {
+	switch evt.(type) {
+	case *events.QR, *events.PairSuccess, *events.PairError, *events.QRScannedWithoutMultidevice,
+		*events.AppState, *events.AppStateSyncComplete, *events.IdentityChange:
+		return false
+	}
+	return true
+}
+
+// rawEventType names an event after its whatsmeow type, events.ChatPresence
+// becomes chat_presence
+func rawEventType(evt interface{}) string {
+	t := reflect.TypeOf(evt)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var b strings.Builder
+	runes := []rune(t.Name())
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at an upper case letter following a lower
+			// case one, or ending an acronym (QRScanned → qr_scanned)
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
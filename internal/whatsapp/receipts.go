@@ -0,0 +1,80 @@
+package whatsapp
+
+import (
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ReceiptHandler receives delivery and read receipts of sent messages
+type ReceiptHandler func(receipt MessageReceipt)
+
+// MessageReceipt reports that one sent message reached or was read by its
+// recipient, as the message.delivered and message.read webhook events
+type MessageReceipt struct {
+	Event       string    `json:"-"` // message.delivered or message.read
+	MessageID   string    `json:"message_id"`
+	Recipient   string    `json:"recipient"`             // Chat the message was sent to
+	Participant string    `json:"participant,omitempty"` // Group member the receipt is from
+	BroadcastID uint      `json:"broadcast_id,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// SetReceiptHandler forwards receipts of sent messages to h. h runs on the
+// event loop, so it must not block.
+func (c *Client) SetReceiptHandler(h ReceiptHandler) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.receipts = h
+}
+
+// forwardReceipt hands a delivered, read or played receipt to the receipt
+// handler, one MessageReceipt per message it covers. Played voice notes
+// count as read. Receipts only arrive for messages this account sent.
+func (c *Client) forwardReceipt(evt *events.Receipt) {
+	c.stateMu.RLock()
+	handler := c.receipts
+	c.stateMu.RUnlock()
+	if handler == nil || len(evt.MessageIDs) == 0 {
+		return
+	}
+
+	var event string
+	switch evt.Type {
+	case events.ReceiptTypeDelivered:
+		event = "message.delivered"
+	case events.ReceiptTypeRead, events.ReceiptTypePlayed:
+		event = "message.read"
+	default:
+		return
+	}
+
+	// Tie receipts of broadcast messages back to their broadcast
+	var deliveries []database.BroadcastDelivery
+	if err := c.db.Select("message_id", "broadcast_message_id").
+		Where("message_id IN ?", evt.MessageIDs).
+		Find(&deliveries).Error; err != nil {
+		logrus.Errorf("Failed to look up broadcasts of receipt: %v", err)
+	}
+	broadcasts := make(map[string]uint, len(deliveries))
+	for _, delivery := range deliveries {
+		broadcasts[delivery.MessageID] = delivery.BroadcastMessageID
+	}
+
+	for _, id := range evt.MessageIDs {
+		receipt := MessageReceipt{
+			Event:       event,
+			MessageID:   id,
+			Recipient:   evt.Chat.String(),
+			BroadcastID: broadcasts[id],
+			Timestamp:   evt.Timestamp,
+		}
+		if evt.IsGroup {
+			receipt.Participant = evt.Sender.ToNonAD().String()
+		}
+		handler(receipt)
+	}
+}
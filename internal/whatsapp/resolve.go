@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ResolvedJID shows how an input would be addressed by the send endpoints
+type ResolvedJID struct {
+	Input       string `json:"input"`
+	JID         string `json:"jid"`
+	Type        string `json:"type"`                   // user, lid, group, broadcast, newsletter
+	Registered  *bool  `json:"registered,omitempty"`   // Only checked for user JIDs while connected
+	WhatsAppJID string `json:"whatsapp_jid,omitempty"` // JID reported by WhatsApp, can differ for some numbering plans
+}
+
+// ResolveJID normalizes input the same way sending does and, for phone
+// numbers, asks WhatsApp whether the number has an account. countryCode
+// overrides DEFAULT_COUNTRY_CODE like it does on the send requests.
+func (c *Client) ResolveJID(input, countryCode string) (*ResolvedJID, error) {
+	jid, err := c.parseJID(input, countryCode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	resolved := &ResolvedJID{
+		Input: input,
+		JID:   jid.String(),
+	}
+	switch jid.Server {
+	case types.DefaultUserServer:
+		resolved.Type = "user"
+	case types.HiddenUserServer:
+		resolved.Type = "lid"
+	case types.GroupServer:
+		resolved.Type = "group"
+	case types.BroadcastServer:
+		resolved.Type = "broadcast"
+	case types.NewsletterServer:
+		resolved.Type = "newsletter"
+	}
+
+	if resolved.Type != "user" || !c.IsReady() {
+		return resolved, nil
+	}
+
+	results, err := c.GetClient().IsOnWhatsApp([]string{"+" + jid.User})
+	if err != nil {
+		return resolved, fmt.Errorf("failed to check registration: %w", err)
+	}
+
+	registered := false
+	for _, result := range results {
+		if result.IsIn {
+			registered = true
+			resolved.WhatsAppJID = result.JID.String()
+			break
+		}
+	}
+	resolved.Registered = &registered
+
+	return resolved, nil
+}
@@ -0,0 +1,40 @@
+package whatsapp
+
+import (
+	"fmt"
+	"time"
+)
+
+// SelfTestResult reports a test message sent to the connected account itself
+type SelfTestResult struct {
+	JID       string    `json:"jid"`
+	MessageID string    `json:"message_id"`
+	LatencyMS int64     `json:"latency_ms"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// SelfTest sends a marker text to the account's own chat ("Message
+// yourself") through the regular send path, proving the connection and
+// whatsmeow can deliver without messaging anyone else
+func (c *Client) SelfTest() (*SelfTestResult, error) {
+	if !c.IsReady() {
+		return nil, ErrClientNotReady
+	}
+	if c.GetClient().Store.ID == nil {
+		return nil, fmt.Errorf("%w: not logged in", ErrClientNotReady)
+	}
+
+	own := c.GetClient().Store.ID.ToNonAD().String()
+	start := time.Now()
+	resp, err := c.SendTextMessage(own, fmt.Sprintf("GOWA self-test %s", start.Format(time.RFC3339)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &SelfTestResult{
+		JID:       own,
+		MessageID: resp.MessageID,
+		LatencyMS: time.Since(start).Milliseconds(),
+		SentAt:    start,
+	}, nil
+}
@@ -0,0 +1,278 @@
+package whatsapp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	sessionDBFile = "whatsapp_session.db"
+
+	// MinSessionPassphraseLength is the shortest passphrase accepted for session backups
+	MinSessionPassphraseLength = 12
+
+	sessionSaltSize = 16
+	sessionKeySize  = 32
+)
+
+var (
+	sessionMagic        = []byte("GOWASESS1")
+	sqliteHeader        = []byte("SQLite format 3\x00")
+	ErrWeakPassphrase   = fmt.Errorf("passphrase must be at least %d characters", MinSessionPassphraseLength)
+	ErrInvalidSession   = errors.New("invalid session backup")
+	ErrSessionDecrypt   = errors.New("failed to decrypt session backup, wrong passphrase or corrupted file")
+	ErrSessionNotLinked = errors.New("no logged in session to export")
+)
+
+// openSessionStore opens the whatsmeow session database and its first device
+func openSessionStore(path string, log waLog.Logger) (*sql.DB, *sqlstore.Container, *store.Device, error) {
+	sessionDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open session database: %v", err)
+	}
+
+	container := sqlstore.NewWithDB(sessionDB, "sqlite3", log)
+	if err := container.Upgrade(); err != nil {
+		sessionDB.Close()
+		return nil, nil, nil, fmt.Errorf("failed to create store: %v", err)
+	}
+
+	deviceStore, err := container.GetFirstDevice()
+	if err != nil {
+		sessionDB.Close()
+		return nil, nil, nil, fmt.Errorf("failed to get device: %v", err)
+	}
+
+	return sessionDB, container, deviceStore, nil
+}
+
+// ExportSession returns a consistent snapshot of the session store encrypted
+// with the given passphrase
+func (c *Client) ExportSession(passphrase string) ([]byte, error) {
+	if len(passphrase) < MinSessionPassphraseLength {
+		return nil, ErrWeakPassphrase
+	}
+
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+
+	if c.client.Store.ID == nil {
+		return nil, ErrSessionNotLinked
+	}
+
+	// VACUUM INTO gives a consistent copy even while the client is writing
+	snapshotPath := fmt.Sprintf("%s.export-%d", c.sessionPath, time.Now().UnixNano())
+	defer os.Remove(snapshotPath)
+
+	if _, err := c.sessionDB.Exec("VACUUM INTO ?", snapshotPath); err != nil {
+		return nil, fmt.Errorf("failed to snapshot session: %v", err)
+	}
+
+	data, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session snapshot: %v", err)
+	}
+
+	return encryptSession(data, passphrase)
+}
+
+// ImportSession replaces the session store with a backup made by ExportSession
+// and reconnects using the restored session
+func (c *Client) ImportSession(backup []byte, passphrase string) error {
+	if len(passphrase) < MinSessionPassphraseLength {
+		return ErrWeakPassphrase
+	}
+
+	data, err := decryptSession(backup, passphrase)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, sqliteHeader) {
+		return ErrInvalidSession
+	}
+
+	// Write the restored store next to the current one and make sure it
+	// opens before the running session is touched
+	tmpPath := c.sessionPath + ".import"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session: %v", err)
+	}
+	defer os.Remove(tmpPath)
+
+	dbLog := waLog.Stdout("Database", "INFO", true)
+	checkDB, _, deviceStore, err := openSessionStore(tmpPath, dbLog)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	loggedIn := deviceStore.ID != nil
+	checkDB.Close()
+
+	if err := c.swapSession(tmpPath, dbLog); err != nil {
+		return err
+	}
+	if !loggedIn {
+		logrus.Warn("Imported session is not logged in, a QR scan will be required")
+	}
+
+	return c.Start()
+}
+
+// swapSession replaces the session store with the one at path and builds a
+// client for it. The current store is kept aside until the new one is open
+// and put back if anything fails, so the client always has a session.
+func (c *Client) swapSession(path string, dbLog waLog.Logger) error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	c.client.Disconnect()
+	c.isReady = false
+	c.sessionDB.Close()
+
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(c.sessionPath + suffix)
+	}
+	previousPath := c.sessionPath + ".previous"
+	if err := os.Rename(c.sessionPath, previousPath); err != nil {
+		c.reopenSession(dbLog)
+		return fmt.Errorf("failed to replace session: %v", err)
+	}
+
+	err := os.Rename(path, c.sessionPath)
+	if err == nil {
+		err = c.openSession(dbLog)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to load imported session, restoring the previous one: %v", err)
+		os.Remove(c.sessionPath)
+		if restoreErr := os.Rename(previousPath, c.sessionPath); restoreErr != nil {
+			logrus.Errorf("Failed to restore previous session: %v", restoreErr)
+		}
+		c.reopenSession(dbLog)
+		return fmt.Errorf("failed to replace session: %v", err)
+	}
+
+	os.Remove(previousPath)
+	return nil
+}
+
+// openSession opens the store at sessionPath and builds a client for it. The
+// caller holds sessionMu.
+func (c *Client) openSession(dbLog waLog.Logger) error {
+	sessionDB, container, deviceStore, err := openSessionStore(c.sessionPath, dbLog)
+	if err != nil {
+		return err
+	}
+
+	c.sessionDB = sessionDB
+	c.store = container
+	c.device = deviceStore
+	c.client = whatsmeow.NewClient(deviceStore, c.logger)
+	return nil
+}
+
+// reopenSession brings the previous session back after a failed swap
+func (c *Client) reopenSession(dbLog waLog.Logger) {
+	if err := c.openSession(dbLog); err != nil {
+		logrus.Errorf("Failed to reopen session: %v", err)
+		return
+	}
+	go func() {
+		if err := c.Start(); err != nil {
+			logrus.Errorf("Failed to reconnect previous session: %v", err)
+		}
+	}()
+}
+
+func deriveSessionKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, sessionKeySize)
+}
+
+// encryptSession seals data as magic | salt | nonce | AES-GCM ciphertext
+func encryptSession(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, sessionSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	key, err := deriveSessionKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(sessionMagic)+len(salt)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, sessionMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, sessionMagic), nil
+}
+
+func decryptSession(backup []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(backup, sessionMagic) {
+		return nil, ErrInvalidSession
+	}
+	rest := backup[len(sessionMagic):]
+	if len(rest) < sessionSaltSize {
+		return nil, ErrInvalidSession
+	}
+	salt, rest := rest[:sessionSaltSize], rest[sessionSaltSize:]
+
+	key, err := deriveSessionKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrInvalidSession
+	}
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	data, err := gcm.Open(nil, nonce, ciphertext, sessionMagic)
+	if err != nil {
+		return nil, ErrSessionDecrypt
+	}
+	return data, nil
+}
+
+// sessionFilePath returns where the session store lives inside the storage directory
+func sessionFilePath(storageDir string) string {
+	return filepath.Join(storageDir, sessionDBFile)
+}
@@ -0,0 +1,116 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+type StatusRequest struct {
+	Type            string `json:"type" binding:"required,oneof=text image video"`
+	Content         string `json:"content,omitempty"`          // Text, or caption for image/video
+	MediaURL        string `json:"media_url,omitempty"`        // Required for image/video
+	BackgroundColor string `json:"background_color,omitempty"` // Hex RGB for text statuses, e.g. #1E88E5
+}
+
+// defaultStatusBackground is used for text statuses without a background color
+const defaultStatusBackground = 0xFF1E88E5
+
+// SendStatus posts a status update (story). WhatsApp delivers it to the
+// audience chosen in the account's status privacy settings.
+func (c *Client) SendStatus(req *StatusRequest) (*MessageResponse, error) {
+	if !c.IsReady() {
+		return &MessageResponse{
+			Success:   false,
+			Error:     "WhatsApp client not ready",
+			Timestamp: time.Now().Unix(),
+		}, ErrClientNotReady
+	}
+
+	// The status audience comes from the privacy settings, fail early if
+	// they can't be read since the send would fail the same way
+	if _, err := c.GetClient().GetStatusPrivacy(); err != nil {
+		return &MessageResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("Failed to get status privacy: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, err
+	}
+
+	switch req.Type {
+	case "text":
+		if strings.TrimSpace(req.Content) == "" {
+			return nil, fmt.Errorf("content is required for text status")
+		}
+
+		background, err := parseStatusColor(req.BackgroundColor)
+		if err != nil {
+			return nil, err
+		}
+
+		msg := &waProto.Message{
+			ExtendedTextMessage: &waProto.ExtendedTextMessage{
+				Text:           proto.String(req.Content),
+				BackgroundArgb: proto.Uint32(background),
+				TextArgb:       proto.Uint32(0xFFFFFFFF),
+				Font:           waProto.ExtendedTextMessage_SYSTEM.Enum(),
+			},
+		}
+
+		resp, err := c.sendWithRetry(types.StatusBroadcastJID, msg)
+		if err != nil {
+			return &MessageResponse{
+				Success:   false,
+				Error:     fmt.Sprintf("Failed to send status: %v", err),
+				Timestamp: time.Now().Unix(),
+			}, err
+		}
+
+		return &MessageResponse{
+			Success:   true,
+			MessageID: resp.ID,
+			Timestamp: resp.Timestamp.Unix(),
+		}, nil
+	case "image", "video":
+		if req.MediaURL == "" {
+			return nil, fmt.Errorf("media_url is required for %s status", req.Type)
+		}
+
+		media, err := c.UploadMedia(req.MediaURL, req.Type)
+		if err != nil {
+			return &MessageResponse{
+				Success:   false,
+				Error:     err.Error(),
+				Timestamp: time.Now().Unix(),
+			}, err
+		}
+
+		return c.sendUploadedMedia(types.StatusBroadcastJID, media, req.Content, "", c.defaultRetry())
+	default:
+		return nil, fmt.Errorf("unsupported status type: %s", req.Type)
+	}
+}
+
+// parseStatusColor parses a #RRGGBB color into an opaque ARGB value
+func parseStatusColor(color string) (uint32, error) {
+	if color == "" {
+		return defaultStatusBackground, nil
+	}
+
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 {
+		return 0, fmt.Errorf("background_color must be in #RRGGBB format")
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("background_color must be in #RRGGBB format")
+	}
+
+	return 0xFF000000 | uint32(rgb), nil
+}
@@ -0,0 +1,169 @@
+package whatsapp
+
+import (
+	"sync"
+	"time"
+
+	"gowa-broadcast/internal/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncResult reports how a contact or group sync went
+type SyncResult struct {
+	Total      int   `json:"total"`
+	Batches    int   `json:"batches"`
+	BatchSize  int   `json:"batch_size"`
+	Workers    int   `json:"workers"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// SyncContacts copies the address book WhatsApp keeps for this account into
+// the user's contacts, updating the names of contacts already stored
+func (c *Client) SyncContacts(userID uint) (*SyncResult, error) {
+	if !c.IsReady() {
+		return nil, ErrClientNotReady
+	}
+
+	all, err := c.GetClient().Store.Contacts.GetAllContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]database.Contact, 0, len(all))
+	for jid, info := range all {
+		name := info.FullName
+		if name == "" {
+			name = info.FirstName
+		}
+		rows = append(rows, database.Contact{
+			UserID:      userID,
+			JID:         jid.ToNonAD().String(),
+			Name:        name,
+			PushName:    info.PushName,
+			PhoneNumber: jid.User,
+		})
+	}
+
+	batch := func(from, to int) interface{} {
+		b := rows[from:to]
+		return &b
+	}
+	// The address book often lacks a name for a contact the user named
+	// themselves, keep the stored one rather than blanking it
+	updates := append(clause.Set{
+		{Column: clause.Column{Name: "name"}, Value: gorm.Expr("COALESCE(NULLIF(excluded.name, ''), contacts.name)")},
+		{Column: clause.Column{Name: "push_name"}, Value: gorm.Expr("COALESCE(NULLIF(excluded.push_name, ''), contacts.push_name)")},
+	}, clause.AssignmentColumns([]string{"phone_number", "updated_at"})...)
+	return c.upsertInBatches(len(rows), batch, updates)
+}
+
+// SyncGroups stores the groups this account is a member of for the user,
+// updating the name, description and owner of groups already stored
+func (c *Client) SyncGroups(userID uint) (*SyncResult, error) {
+	if !c.IsReady() {
+		return nil, ErrClientNotReady
+	}
+
+	groups, err := c.GetClient().GetJoinedGroups()
+	if err != nil {
+		return nil, describeGroupError(err)
+	}
+
+	rows := make([]database.Group, 0, len(groups))
+	for _, info := range groups {
+		c.rememberParticipantLIDs(info.Participants)
+		rows = append(rows, database.Group{
+			UserID:      userID,
+			JID:         info.JID.String(),
+			Name:        info.Name,
+			Description: info.Topic,
+			OwnerJID:    info.OwnerJID.String(),
+		})
+	}
+
+	batch := func(from, to int) interface{} {
+		b := rows[from:to]
+		return &b
+	}
+	return c.upsertInBatches(len(rows), batch, clause.AssignmentColumns([]string{"name", "description", "owner_jid", "updated_at"}))
+}
+
+// upsertInBatches writes total rows, handed out by batch as pointers to
+// slices of models, with one INSERT ... ON CONFLICT (user_id, jid) per batch.
+// Every batch is its own short transaction so a large sync never holds the
+// write lock long enough to stall message storage, which matters most on
+// SQLite. With more than one worker batches are written concurrently, only
+// useful on databases that allow parallel writers.
+func (c *Client) upsertInBatches(total int, batch func(from, to int) interface{}, updates clause.Set) (*SyncResult, error) {
+	batchSize := c.cfg.WhatsApp.SyncBatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	workers := c.cfg.WhatsApp.SyncWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	result := &SyncResult{
+		Total:     total,
+		Batches:   (total + batchSize - 1) / batchSize,
+		BatchSize: batchSize,
+		Workers:   workers,
+	}
+	if total == 0 {
+		return result, nil
+	}
+
+	upsert := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "jid"}},
+		DoUpdates: updates,
+	}
+
+	start := time.Now()
+	batches := make(chan [2]int)
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for bounds := range batches {
+				rows := batch(bounds[0], bounds[1])
+				err := c.db.Transaction(func(tx *gorm.DB) error {
+					return tx.Clauses(upsert).Create(rows).Error
+				})
+				if err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for from := 0; from < total; from += batchSize {
+		to := from + batchSize
+		if to > total {
+			to = total
+		}
+		batches <- [2]int{from, to}
+	}
+	close(batches)
+	wg.Wait()
+
+	result.DurationMS = time.Since(start).Milliseconds()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	logrus.Infof("Synced %d rows in %d batches in %dms", result.Total, result.Batches, result.DurationMS)
+	return result, nil
+}
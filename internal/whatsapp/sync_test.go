@@ -0,0 +1,77 @@
+package whatsapp
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"gowa-broadcast/internal/config"
+	"gowa-broadcast/internal/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+func TestUpsertInBatchesLargeSync(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&database.Contact{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.WhatsApp.SyncBatchSize = 200
+	cfg.WhatsApp.SyncWorkers = 1
+	c := &Client{cfg: cfg, db: db}
+
+	const total = 1050
+	rows := make([]database.Contact, total)
+	for i := range rows {
+		rows[i] = database.Contact{
+			UserID: 1,
+			JID:    fmt.Sprintf("62812%05d@s.whatsapp.net", i),
+			Name:   fmt.Sprintf("Contact %d", i),
+		}
+	}
+	batch := func(from, to int) interface{} {
+		b := rows[from:to]
+		return &b
+	}
+	updates := append(clause.Set{
+		{Column: clause.Column{Name: "name"}, Value: gorm.Expr("COALESCE(NULLIF(excluded.name, ''), contacts.name)")},
+	}, clause.AssignmentColumns([]string{"updated_at"})...)
+
+	result, err := c.upsertInBatches(total, batch, updates)
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if result.Batches != 6 {
+		t.Errorf("batches = %d, want 6", result.Batches)
+	}
+
+	// A second sync without names must neither duplicate nor blank them
+	for i := range rows {
+		rows[i].ID = 0
+		rows[i].Name = ""
+	}
+	if _, err := c.upsertInBatches(total, batch, updates); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+
+	var count int64
+	db.Model(&database.Contact{}).Count(&count)
+	if count != total {
+		t.Errorf("stored %d contacts, want %d", count, total)
+	}
+	var blank int64
+	db.Model(&database.Contact{}).Where("name = ''").Count(&blank)
+	if blank != 0 {
+		t.Errorf("%d names were blanked by the second sync", blank)
+	}
+}
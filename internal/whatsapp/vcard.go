@@ -0,0 +1,139 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidVCard is returned for contact cards WhatsApp can't display
+var ErrInvalidVCard = errors.New("invalid vcard")
+
+// supportedVCardVersions are the VERSION values WhatsApp renders
+var supportedVCardVersions = map[string]bool{"2.1": true, "3.0": true, "4.0": true}
+
+// VCardFields describes a contact so clients don't have to write vCard syntax
+type VCardFields struct {
+	Name         string `json:"name" binding:"required,max=200"`
+	Phone        string `json:"phone" binding:"required,max=30"`
+	Organization string `json:"organization,omitempty" binding:"max=200"`
+	Email        string `json:"email,omitempty" binding:"omitempty,email"`
+}
+
+// ValidateVCard checks that vcard is a single BEGIN:VCARD ... END:VCARD block
+// with a supported VERSION, a formatted name (FN) and at least one phone
+// number (TEL), without which recipients see an empty card
+func ValidateVCard(vcard string) error {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(vcard), "\r\n", "\n"), "\n")
+	if len(lines) < 2 {
+		return fmt.Errorf("%w: card is empty", ErrInvalidVCard)
+	}
+	if !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCARD") {
+		return fmt.Errorf("%w: must start with BEGIN:VCARD", ErrInvalidVCard)
+	}
+	if !strings.EqualFold(strings.TrimSpace(lines[len(lines)-1]), "END:VCARD") {
+		return fmt.Errorf("%w: must end with END:VCARD", ErrInvalidVCard)
+	}
+
+	var version, name string
+	hasPhone := false
+	for i, line := range lines[1 : len(lines)-1] {
+		// Folded lines continue the previous property
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		property, value, found := strings.Cut(line, ":")
+		if !found {
+			return fmt.Errorf("%w: line %d has no value", ErrInvalidVCard, i+2)
+		}
+
+		// Drop parameters (TEL;type=CELL) and group prefixes (item1.TEL)
+		property, _, _ = strings.Cut(property, ";")
+		if dot := strings.LastIndex(property, "."); dot >= 0 {
+			property = property[dot+1:]
+		}
+
+		switch strings.ToUpper(property) {
+		case "BEGIN", "END":
+			return fmt.Errorf("%w: only a single card is supported", ErrInvalidVCard)
+		case "VERSION":
+			version = strings.TrimSpace(value)
+		case "FN":
+			name = strings.TrimSpace(value)
+		case "TEL":
+			hasPhone = hasPhone || strings.TrimSpace(value) != ""
+		}
+	}
+
+	switch {
+	case version == "":
+		return fmt.Errorf("%w: VERSION is missing", ErrInvalidVCard)
+	case !supportedVCardVersions[version]:
+		return fmt.Errorf("%w: VERSION %s is not supported, use 2.1, 3.0 or 4.0", ErrInvalidVCard, version)
+	case name == "":
+		return fmt.Errorf("%w: FN (formatted name) is missing", ErrInvalidVCard)
+	case !hasPhone:
+		return fmt.Errorf("%w: TEL (phone number) is missing", ErrInvalidVCard)
+	}
+	return nil
+}
+
+// BuildVCard writes a version 3.0 vCard for the contact. The waid parameter
+// lets WhatsApp link the card to the number's account, so a local phone
+// number gets countryCode like a send recipient does.
+func BuildVCard(fields VCardFields, countryCode string) (string, error) {
+	name := strings.TrimSpace(fields.Name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidVCard)
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, ApplyCountryCode(fields.Phone, countryCode))
+	if len(digits) < 5 {
+		return "", fmt.Errorf("%w: phone %q is not a valid number", ErrInvalidVCard, fields.Phone)
+	}
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\n")
+	b.WriteString("VERSION:3.0\n")
+	fmt.Fprintf(&b, "FN:%s\n", escapeVCardValue(name))
+	fmt.Fprintf(&b, "N:%s;;;;\n", escapeVCardValue(name))
+	if org := strings.TrimSpace(fields.Organization); org != "" {
+		fmt.Fprintf(&b, "ORG:%s\n", escapeVCardValue(org))
+	}
+	fmt.Fprintf(&b, "TEL;type=CELL;type=VOICE;waid=%s:+%s\n", digits, digits)
+	if email := strings.TrimSpace(fields.Email); email != "" {
+		fmt.Fprintf(&b, "EMAIL:%s\n", escapeVCardValue(email))
+	}
+	b.WriteString("END:VCARD")
+	return b.String(), nil
+}
+
+// ResolveVCard returns the card to send: built from fields when given,
+// otherwise vcard after validation
+func ResolveVCard(vcard string, fields *VCardFields, countryCode string) (string, error) {
+	if fields != nil {
+		return BuildVCard(*fields, countryCode)
+	}
+	if err := ValidateVCard(vcard); err != nil {
+		return "", err
+	}
+	return vcard, nil
+}
+
+// contactDisplayName falls back to the structured contact's name when no
+// display name was given
+func contactDisplayName(displayName string, fields *VCardFields) string {
+	if displayName == "" && fields != nil {
+		return fields.Name
+	}
+	return displayName
+}
+
+// escapeVCardValue escapes the characters that have a meaning in vCard values
+func escapeVCardValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
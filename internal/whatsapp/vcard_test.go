@@ -0,0 +1,157 @@
+package whatsapp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateVCard(t *testing.T) {
+	tests := []struct {
+		name    string
+		vcard   string
+		wantErr string
+	}{
+		{
+			name:  "valid 3.0",
+			vcard: "BEGIN:VCARD\nVERSION:3.0\nFN:Budi\nTEL;type=CELL:+62812345678\nEND:VCARD",
+		},
+		{
+			name:  "CRLF line endings",
+			vcard: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Budi\r\nTEL:+62812345678\r\nEND:VCARD\r\n",
+		},
+		{
+			name:  "grouped TEL and folded line",
+			vcard: "BEGIN:VCARD\nVERSION:3.0\nFN:Budi\nNOTE:first part\n second part\nitem1.TEL;waid=62812345678:+62812345678\nEND:VCARD",
+		},
+		{
+			name:    "empty",
+			vcard:   "",
+			wantErr: "card is empty",
+		},
+		{
+			name:    "missing BEGIN",
+			vcard:   "VERSION:3.0\nFN:Budi\nTEL:+62812345678\nEND:VCARD",
+			wantErr: "must start with BEGIN:VCARD",
+		},
+		{
+			name:    "missing END",
+			vcard:   "BEGIN:VCARD\nVERSION:3.0\nFN:Budi\nTEL:+62812345678",
+			wantErr: "must end with END:VCARD",
+		},
+		{
+			name:    "missing VERSION",
+			vcard:   "BEGIN:VCARD\nFN:Budi\nTEL:+62812345678\nEND:VCARD",
+			wantErr: "VERSION is missing",
+		},
+		{
+			name:    "unsupported VERSION",
+			vcard:   "BEGIN:VCARD\nVERSION:5.0\nFN:Budi\nTEL:+62812345678\nEND:VCARD",
+			wantErr: "VERSION 5.0 is not supported",
+		},
+		{
+			name:    "missing FN",
+			vcard:   "BEGIN:VCARD\nVERSION:3.0\nN:Budi;;;;\nTEL:+62812345678\nEND:VCARD",
+			wantErr: "FN (formatted name) is missing",
+		},
+		{
+			name:    "empty TEL",
+			vcard:   "BEGIN:VCARD\nVERSION:3.0\nFN:Budi\nTEL: \nEND:VCARD",
+			wantErr: "TEL (phone number) is missing",
+		},
+		{
+			name:    "line without value",
+			vcard:   "BEGIN:VCARD\nVERSION:3.0\nFN:Budi\nTEL\nEND:VCARD",
+			wantErr: "line 4 has no value",
+		},
+		{
+			name:    "multiple cards",
+			vcard:   "BEGIN:VCARD\nVERSION:3.0\nFN:Budi\nTEL:+62812345678\nEND:VCARD\nBEGIN:VCARD\nVERSION:3.0\nFN:Ani\nTEL:+62812345679\nEND:VCARD",
+			wantErr: "only a single card is supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVCard(tt.vcard)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateVCard() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidVCard) {
+				t.Fatalf("ValidateVCard() error = %v, want ErrInvalidVCard", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateVCard() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildVCard(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      VCardFields
+		countryCode string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:        "local number gets the country code",
+			fields:      VCardFields{Name: "Budi", Phone: "0812-3456-789"},
+			countryCode: "62",
+			want:        []string{"TEL;type=CELL;type=VOICE;waid=628123456789:+628123456789"},
+		},
+		{
+			name:        "international number is kept",
+			fields:      VCardFields{Name: "Budi", Phone: "+1 (415) 555-0100"},
+			countryCode: "62",
+			want:        []string{"waid=14155550100:+14155550100"},
+		},
+		{
+			name:   "special characters are escaped",
+			fields: VCardFields{Name: "Budi; Jr, III", Phone: "+628123456789", Organization: "PT A, B", Email: "budi@example.com"},
+			want: []string{
+				`FN:Budi\; Jr\, III`,
+				`N:Budi\; Jr\, III;;;;`,
+				`ORG:PT A\, B`,
+				"EMAIL:budi@example.com",
+			},
+		},
+		{
+			name:    "empty name",
+			fields:  VCardFields{Name: "  ", Phone: "+628123456789"},
+			wantErr: true,
+		},
+		{
+			name:    "short phone",
+			fields:  VCardFields{Name: "Budi", Phone: "12"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildVCard(tt.fields, tt.countryCode)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidVCard) {
+					t.Fatalf("BuildVCard() error = %v, want ErrInvalidVCard", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildVCard() error = %v", err)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(got, line+"\n") {
+					t.Errorf("BuildVCard() = %q, want line %q", got, line)
+				}
+			}
+			if err := ValidateVCard(got); err != nil {
+				t.Errorf("BuildVCard() produced a card ValidateVCard rejects: %v", err)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 func main() {
@@ -43,45 +45,47 @@ func main() {
 	// Override config with command line flags if provided
 	if *port != "" {
 		cfg.App.Port = *port
+		cfg.MarkFlag("APP_PORT")
 	}
 	if *debug {
 		cfg.App.Debug = *debug
+		cfg.MarkFlag("APP_DEBUG")
 	}
 	if *osName != "" {
 		cfg.App.OS = *osName
+		cfg.MarkFlag("APP_OS")
 	}
 	if *basicAuth != "" {
 		cfg.App.BasicAuth = *basicAuth
+		cfg.MarkFlag("APP_BASIC_AUTH")
 	}
 	if *basePath != "" {
 		cfg.App.BasePath = *basePath
+		cfg.MarkFlag("APP_BASE_PATH")
 	}
 	if *autoReply != "" {
 		cfg.WhatsApp.AutoReply = *autoReply
+		cfg.MarkFlag("WHATSAPP_AUTO_REPLY")
 	}
 	if *autoMarkRead {
 		cfg.WhatsApp.AutoMarkRead = *autoMarkRead
+		cfg.MarkFlag("WHATSAPP_AUTO_MARK_READ")
 	}
 	if *webhook != "" {
 		cfg.WhatsApp.Webhook = *webhook
+		cfg.MarkFlag("WHATSAPP_WEBHOOK")
 	}
 	if *webhookSecret != "" {
 		cfg.WhatsApp.WebhookSecret = *webhookSecret
+		cfg.MarkFlag("WHATSAPP_WEBHOOK_SECRET")
 	}
 	if *dbURI != "" {
 		cfg.Database.URI = *dbURI
+		cfg.MarkFlag("DB_URI")
 	}
 
 	// Setup logging
-	if cfg.App.Debug {
-		logrus.SetLevel(logrus.DebugLevel)
-		logrus.SetFormatter(&logrus.TextFormatter{
-			FullTimestamp: true,
-		})
-	} else {
-		logrus.SetLevel(logrus.InfoLevel)
-		logrus.SetFormatter(&logrus.JSONFormatter{})
-	}
+	setupLogging(cfg)
 
 	// Check command
 	args := flag.Args()
@@ -106,6 +110,54 @@ func main() {
 	}
 }
 
+func setupLogging(cfg *config.Config) {
+	// Level
+	level := logrus.InfoLevel
+	if cfg.App.Debug {
+		level = logrus.DebugLevel
+	}
+	if cfg.Log.Level != "" {
+		parsed, err := logrus.ParseLevel(cfg.Log.Level)
+		if err != nil {
+			logrus.Warnf("Invalid LOG_LEVEL %q, using %s", cfg.Log.Level, level)
+		} else {
+			level = parsed
+		}
+	}
+	logrus.SetLevel(level)
+
+	// Format
+	format := cfg.Log.Format
+	if format == "" {
+		format = "json"
+		if cfg.App.Debug {
+			format = "text"
+		}
+	}
+	switch strings.ToLower(format) {
+	case "text":
+		logrus.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+		})
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+		logrus.Warnf("Invalid LOG_FORMAT %q, using json", cfg.Log.Format)
+	}
+
+	// Output, rotated when writing to a file
+	if cfg.Log.File != "" {
+		logrus.SetOutput(io.MultiWriter(os.Stdout, &lumberjack.Logger{
+			Filename:   cfg.Log.File,
+			MaxSize:    cfg.Log.MaxSizeMB,
+			MaxBackups: cfg.Log.MaxBackups,
+			MaxAge:     cfg.Log.MaxAgeDays,
+			Compress:   true,
+		}))
+	}
+}
+
 func startRESTServer(cfg *config.Config) {
 	logrus.Info("Starting GOWA Broadcast REST API Server...")
 
@@ -127,7 +179,10 @@ func startRESTServer(cfg *config.Config) {
 	}
 
 	// Initialize and start HTTP server
-	server := server.NewServer(cfg, db, waClient)
+	server, err := server.NewServer(cfg, db, waClient)
+	if err != nil {
+		log.Fatal("Failed to initialize server:", err)
+	}
 	if err := server.Start(); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
@@ -163,4 +218,6 @@ func showHelp() {
 	fmt.Println("  DB_URI, WHATSAPP_AUTO_REPLY, WHATSAPP_AUTO_MARK_READ")
 	fmt.Println("  WHATSAPP_WEBHOOK, WHATSAPP_WEBHOOK_SECRET")
 	fmt.Println("  BROADCAST_RATE_LIMIT, BROADCAST_DELAY_MS, BROADCAST_MAX_RECIPIENTS")
-}
\ No newline at end of file
+	fmt.Println("  JWT_ALGORITHM, JWT_SECRET, JWT_PRIVATE_KEY_FILE, JWT_PUBLIC_KEY_FILE, JWT_ISSUER, JWT_AUDIENCE")
+	fmt.Println("  LOG_LEVEL, LOG_FORMAT, LOG_FILE, LOG_MAX_SIZE_MB, LOG_MAX_BACKUPS, LOG_MAX_AGE_DAYS")
+}